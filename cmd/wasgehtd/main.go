@@ -20,6 +20,7 @@ func main() {
 	hostFile := flag.String("host-file", "sample-hosts.json", "Path to the host configuration file")
 	dataDir := flag.String("data-dir", "./data", "Path to the data directory containing 'rrds' and 'graphs' folders")
 	listenPort := flag.String("port", "1982", "Port to listen on")
+	maxChecks := flag.Int("max-concurrent-checks", 32, "Maximum number of checks running at the same time")
 	flag.Parse()
 
 	// Configure logrus to log to stdout with appropriate log level
@@ -58,7 +59,7 @@ func main() {
 	}
 
 	// Load the server with hosts and configuration
-	srv, err := server.NewServer(*hostFile, rrdDir, graphDir, *listenPort, logger)
+	srv, err := server.NewServer(*hostFile, rrdDir, graphDir, *listenPort, *maxChecks, logger)
 	if err != nil {
 		logger.Fatalf("Failed to start server: %v", err)
 	}
@@ -3,14 +3,16 @@ module github.com/kylerisse/wasgeht
 go 1.25
 
 require (
+	github.com/gosnmp/gosnmp v1.38.0
 	github.com/miekg/dns v1.1.72
 	github.com/sirupsen/logrus v1.9.4
+	golang.org/x/crypto v0.46.0
+	golang.org/x/net v0.48.0
 	golang.org/x/time v0.14.0
 )
 
 require (
 	golang.org/x/mod v0.31.0 // indirect
-	golang.org/x/net v0.48.0 // indirect
 	golang.org/x/sync v0.19.0 // indirect
 	golang.org/x/sys v0.41.0 // indirect
 	golang.org/x/tools v0.40.0 // indirect
@@ -1,5 +1,43 @@
 package check
 
+import (
+	"slices"
+	"time"
+)
+
+// DSType is the RRD data source type a metric is stored as.
+type DSType string
+
+const (
+	// DSGauge stores values as they are (e.g. a latency or a temperature).
+	DSGauge DSType = "GAUGE"
+
+	// DSCounter stores the per-second rate of a counter that only
+	// increases, accounting for 32 and 64 bit wraps (e.g. interface
+	// octets).
+	DSCounter DSType = "COUNTER"
+
+	// DSDerive stores the per-second rate of change. Unlike DSCounter it
+	// does not assume wraps, so a counter reset yields one unknown value
+	// rather than a spike, given the default minimum of 0.
+	DSDerive DSType = "DERIVE"
+
+	// DSAbsolute stores the per-second rate of a counter that is reset
+	// each time it is read (e.g. events since the previous poll).
+	DSAbsolute DSType = "ABSOLUTE"
+)
+
+// IsRate reports whether the data source type stores a per-second rate
+// rather than the value itself.
+func (t DSType) IsRate() bool {
+	return t == DSCounter || t == DSDerive || t == DSAbsolute
+}
+
+// Bound returns a pointer to v, for setting MetricDef.Min and Max.
+func Bound(v float64) *float64 {
+	return &v
+}
+
 // MetricDef describes a single metric produced by a check type.
 type MetricDef struct {
 	// ResultKey is the key used in Result.Metrics (e.g. "latency_us").
@@ -17,8 +55,46 @@ type MetricDef struct {
 	// Scale is the divisor applied to convert the raw stored value to
 	// the display unit. For example, ping stores microseconds but
 	// displays milliseconds, so Scale is 1000.
-	// A value of 0 or 1 means no scaling is applied.
+	// A value of 0 or 1 means no scaling is applied. Checks that map
+	// configured metrics set it from their "divisor" key; a "multiplier"
+	// key, where supported, is applied to the value before it is stored.
 	Scale int
+
+	// Stack names a stack group for graphing. Metrics sharing a non-empty
+	// Stack value are drawn as stacked areas in declaration order (e.g. the
+	// phases of a single HTTP request); members of a group must be declared
+	// contiguously. Metrics with an empty Stack are drawn as individual
+	// lines on top of any stacked areas.
+	Stack string
+
+	// Signed allows negative values (e.g. a clock offset). By default the
+	// RRD data source has a minimum of 0, so negative values would be
+	// stored as unknown. Signed is ignored when Min is set.
+	Signed bool
+
+	// DSType is the RRD data source type. Empty means DSGauge. Rate types
+	// (counter, derive and absolute) report raw counter values in
+	// Result.Metrics and are stored and graphed as per-second rates.
+	DSType DSType
+
+	// Min and Max bound the values the RRD accepts; anything outside is
+	// stored as unknown, which keeps a bogus reading or a counter glitch
+	// from flattening the graph. For rate types they bound the rate. A nil
+	// Min means 0 (or no lower bound if Signed) and a nil Max means no
+	// upper bound.
+	Min *float64
+	Max *float64
+
+	// Heartbeat is the longest gap between updates before the data source
+	// becomes unknown. Zero means two steps (twice the check's interval).
+	Heartbeat time.Duration
+
+	// Graph names a separate graph for the metric, for values whose unit
+	// does not belong on the check's main graph (e.g. packet loss beside
+	// round-trip times). Metrics sharing a Graph are drawn together, with
+	// the name as their title and axis label. Metrics with an empty Graph
+	// are drawn on the main graph.
+	Graph string
 }
 
 // Descriptor declares metadata about a check instance, including what
@@ -35,3 +111,16 @@ type Descriptor struct {
 	// Metrics lists the metrics this check instance produces.
 	Metrics []MetricDef
 }
+
+// Graphs returns the names of the separate graphs the descriptor's
+// metrics are drawn on, in the order they are first used. The main graph
+// is not included.
+func (d Descriptor) Graphs() []string {
+	var graphs []string
+	for _, m := range d.Metrics {
+		if m.Graph != "" && !slices.Contains(graphs, m.Graph) {
+			graphs = append(graphs, m.Graph)
+		}
+	}
+	return graphs
+}
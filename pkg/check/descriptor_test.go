@@ -45,3 +45,40 @@ func TestDescriptor_ScaleOneMeansNoScaling(t *testing.T) {
 		t.Errorf("expected Scale 1, got %d", d.Scale)
 	}
 }
+
+func TestDescriptor_Graphs(t *testing.T) {
+	d := Descriptor{
+		Metrics: []MetricDef{
+			{DSName: "a"},
+			{DSName: "a_loss", Graph: "loss"},
+			{DSName: "b"},
+			{DSName: "b_loss", Graph: "loss"},
+			{DSName: "b_errors", Graph: "errors"},
+		},
+	}
+	got := d.Graphs()
+	if len(got) != 2 || got[0] != "loss" || got[1] != "errors" {
+		t.Errorf("expected [loss errors], got %v", got)
+	}
+	if g := (Descriptor{Metrics: []MetricDef{{DSName: "a"}}}).Graphs(); g != nil {
+		t.Errorf("expected no separate graphs, got %v", g)
+	}
+}
+
+func TestDSType_IsRate(t *testing.T) {
+	tests := []struct {
+		dsType DSType
+		want   bool
+	}{
+		{"", false},
+		{DSGauge, false},
+		{DSCounter, true},
+		{DSDerive, true},
+		{DSAbsolute, true},
+	}
+	for _, tt := range tests {
+		if got := tt.dsType.IsRate(); got != tt.want {
+			t.Errorf("DSType(%q).IsRate() = %v, want %v", tt.dsType, got, tt.want)
+		}
+	}
+}
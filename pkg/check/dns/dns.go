@@ -1,17 +1,25 @@
 // Package dns implements a DNS query check that resolves one or more names
 // against a specific server and validates each answer against an expected value.
-// Supported record types are A, AAAA, and PTR. The check succeeds only when
-// every configured query resolves and its answer matches the expected value.
+// Supported record types are A, AAAA, PTR, MX, TXT, CNAME, NS, SRV, CAA and
+// SOA. The check succeeds only when every configured query resolves and its
+// answer matches the expected value. Queries are sent over UDP by default,
+// or over TCP, DNS over TLS, or DNS over HTTPS. Queries may opt in to DNSSEC
+// validation, which verifies the answer's signatures against the zone's
+// DNSKEY set and reports the days until the signatures expire.
 package dns
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"math"
 	"net"
 	"strings"
 	"time"
 
 	"github.com/kylerisse/wasgeht/pkg/check"
+	"github.com/kylerisse/wasgeht/pkg/check/internal/certs"
 	"github.com/miekg/dns"
 )
 
@@ -26,19 +34,26 @@ const (
 // queryConfig holds the parsed configuration for a single DNS query.
 type queryConfig struct {
 	name      string // query name as provided (without trailing dot)
-	qtype     uint16 // dns.TypeA, dns.TypeAAAA, dns.TypePTR
+	qtype     uint16 // dns.TypeA, dns.TypeMX, ... (see supportedTypes)
 	expect    string // expected value in the answer (normalized)
-	resultKey string // key in Result.Metrics (= name)
+	match     string // TXT only: matchExact (default) or matchContains
+	dnssec    bool   // validate RRSIGs and report days until expiry
+	resultKey string // key in Result.Metrics (name, disambiguated if shared)
 	dsName    string // RRD DS name (e.g. "q0", "q1")
 }
 
 // Check implements check.Check using DNS queries to a specific server.
 type Check struct {
-	server  string        // host:port of the DNS server
-	timeout time.Duration
-	queries []queryConfig
-	client  *dns.Client
-	desc    check.Descriptor
+	server     string // host:port of the DNS server, or URL for https
+	timeout    time.Duration
+	queries    []queryConfig
+	transport  string         // TransportUDP, TransportTCP, TransportTLS or TransportHTTPS
+	serverName string         // TLS server name override
+	skipVerify bool           // skip TLS certificate verification
+	rootCAs    *x509.CertPool // TLS roots; nil for the system roots
+	sigWarning int            // degrade below this many days of RRSIG validity
+	client     exchanger
+	desc       check.Descriptor
 }
 
 // Option is a functional option for configuring a DNS Check.
@@ -55,7 +70,63 @@ func WithTimeout(d time.Duration) Option {
 	}
 }
 
+// WithTransport sets the transport queries are sent over: TransportUDP
+// (the default), TransportTCP, TransportTLS or TransportHTTPS.
+func WithTransport(t string) Option {
+	return func(c *Check) error {
+		switch t {
+		case TransportUDP, TransportTCP, TransportTLS, TransportHTTPS:
+			c.transport = t
+			return nil
+		default:
+			return fmt.Errorf("transport must be %q, %q, %q or %q, got %q", TransportUDP, TransportTCP, TransportTLS, TransportHTTPS, t)
+		}
+	}
+}
+
+// WithServerName overrides the name used to verify the server's TLS
+// certificate, for servers addressed by IP.
+func WithServerName(name string) Option {
+	return func(c *Check) error {
+		c.serverName = name
+		return nil
+	}
+}
+
+// WithSkipVerify disables TLS certificate verification.
+func WithSkipVerify(skip bool) Option {
+	return func(c *Check) error {
+		c.skipVerify = skip
+		return nil
+	}
+}
+
+// WithRootCAs sets the roots used to verify the server's TLS certificate
+// instead of the system roots.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *Check) error {
+		if pool == nil {
+			return fmt.Errorf("root CA pool must not be nil")
+		}
+		c.rootCAs = pool
+		return nil
+	}
+}
+
+// WithSignatureWarningDays sets the remaining RRSIG validity, in days,
+// below which a DNSSEC query marks the check degraded.
+func WithSignatureWarningDays(n int) Option {
+	return func(c *Check) error {
+		if n < 0 {
+			return fmt.Errorf("signature warning days must not be negative, got %d", n)
+		}
+		c.sigWarning = n
+		return nil
+	}
+}
+
 // New creates a DNS Check targeting the given server with the given queries.
+// For the https transport the server is the DoH endpoint URL.
 func New(server string, queries []queryConfig, opts ...Option) (*Check, error) {
 	if server == "" {
 		return nil, fmt.Errorf("dns: server must not be empty")
@@ -65,9 +136,11 @@ func New(server string, queries []queryConfig, opts ...Option) (*Check, error) {
 	}
 
 	c := &Check{
-		server:  server,
-		timeout: DefaultTimeout,
-		queries: queries,
+		server:     server,
+		timeout:    DefaultTimeout,
+		queries:    queries,
+		transport:  TransportUDP,
+		sigWarning: DefaultSignatureWarningDays,
 	}
 
 	for _, opt := range opts {
@@ -76,18 +149,44 @@ func New(server string, queries []queryConfig, opts ...Option) (*Check, error) {
 		}
 	}
 
-	c.client = &dns.Client{
-		Timeout: c.timeout,
+	encrypted := c.transport == TransportTLS || c.transport == TransportHTTPS
+	if !encrypted && (c.serverName != "" || c.skipVerify || c.rootCAs != nil) {
+		return nil, fmt.Errorf("dns: TLS options require the tls or https transport")
 	}
 
-	metrics := make([]check.MetricDef, len(queries))
-	for i, q := range queries {
-		metrics[i] = check.MetricDef{
+	var err error
+	c.server, err = normalizeServer(c.transport, c.server)
+	if err != nil {
+		return nil, fmt.Errorf("dns: %w", err)
+	}
+
+	var tlsConfig *tls.Config
+	if encrypted {
+		tlsConfig = &tls.Config{
+			ServerName:         c.serverName,
+			InsecureSkipVerify: c.skipVerify,
+			RootCAs:            c.rootCAs,
+		}
+	}
+	c.client = newExchanger(c.transport, c.timeout, tlsConfig)
+
+	var metrics []check.MetricDef
+	for _, q := range queries {
+		metrics = append(metrics, check.MetricDef{
 			ResultKey: q.resultKey,
 			DSName:    q.dsName,
 			Label:     q.resultKey,
 			Unit:      "ms",
 			Scale:     1000,
+		})
+		if q.dnssec {
+			metrics = append(metrics, check.MetricDef{
+				ResultKey: signatureResultKey(q),
+				DSName:    signatureDSName(q),
+				Label:     q.resultKey + " rrsig",
+				Unit:      "days",
+				Scale:     1,
+			})
 		}
 	}
 	c.desc = check.Descriptor{
@@ -111,36 +210,50 @@ func (c *Check) Describe() check.Descriptor {
 // Run executes all configured DNS queries against the server and returns a Result.
 // Success requires every query to resolve and every answer to match its expected value.
 // Each query's RTT is stored in microseconds keyed by the query name.
+// DNSSEC queries additionally require valid signatures and store the whole
+// days until the earliest signature expiry; fewer than the warning days
+// remaining marks the result degraded.
 func (c *Check) Run(ctx context.Context) check.Result {
-	metrics := make(map[string]*int64, len(c.queries))
+	metrics := make(map[string]*float64, len(c.queries))
 	var lastErr error
 	succeeded := 0
+	degraded := false
 
 	for _, q := range c.queries {
-		msg := new(dns.Msg)
-		msg.SetQuestion(dns.Fqdn(q.name), q.qtype)
-		msg.RecursionDesired = true
+		if q.dnssec {
+			metrics[signatureResultKey(q)] = nil
+		}
 
-		resp, rtt, err := c.client.ExchangeContext(ctx, msg, c.server)
+		msg := newQuery(q.name, q.qtype, q.dnssec)
+
+		resp, rtt, err := exchange(ctx, c.client, c.server, msg)
 		if err != nil {
 			lastErr = fmt.Errorf("dns %s %s: %w", qtypeName(q.qtype), q.name, err)
 			metrics[q.resultKey] = nil
 			continue
 		}
 
-		if resp.Rcode != dns.RcodeSuccess {
-			lastErr = fmt.Errorf("dns %s %s: rcode %s", qtypeName(q.qtype), q.name, dns.RcodeToString[resp.Rcode])
+		if err := q.validate(resp.Answer); err != nil {
+			lastErr = fmt.Errorf("dns %s %s: %w", qtypeName(q.qtype), q.name, err)
 			metrics[q.resultKey] = nil
 			continue
 		}
 
-		if err := validateAnswer(resp.Answer, q.qtype, q.expect); err != nil {
-			lastErr = fmt.Errorf("dns %s %s: %w", qtypeName(q.qtype), q.name, err)
-			metrics[q.resultKey] = nil
-			continue
+		if q.dnssec {
+			remaining, err := c.validateDNSSEC(ctx, q, resp.Answer, time.Now())
+			if err != nil {
+				lastErr = fmt.Errorf("dns %s %s: dnssec: %w", qtypeName(q.qtype), q.name, err)
+				metrics[q.resultKey] = nil
+				continue
+			}
+			days := math.Floor(remaining.Hours() / 24)
+			metrics[signatureResultKey(q)] = &days
+			if days < float64(c.sigWarning) {
+				degraded = true
+			}
 		}
 
-		v := rtt.Microseconds()
+		v := float64(rtt.Microseconds())
 		metrics[q.resultKey] = &v
 		succeeded++
 	}
@@ -148,11 +261,38 @@ func (c *Check) Run(ctx context.Context) check.Result {
 	return check.Result{
 		Timestamp: time.Now(),
 		Success:   succeeded == len(c.queries),
+		Degraded:  degraded,
 		Err:       lastErr,
 		Metrics:   metrics,
 	}
 }
 
+// exchange sends msg to server and returns the response and round-trip
+// time. A response with a non-success rcode is returned as an error.
+func exchange(ctx context.Context, client exchanger, server string, msg *dns.Msg) (*dns.Msg, time.Duration, error) {
+	resp, rtt, err := client.ExchangeContext(ctx, msg, server)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return nil, 0, fmt.Errorf("rcode %s", dns.RcodeToString[resp.Rcode])
+	}
+	return resp, rtt, nil
+}
+
+// validate checks the answer section against the query's expectation.
+func (q queryConfig) validate(rrs []dns.RR) error {
+	if q.qtype == dns.TypeTXT && q.match == matchContains {
+		for _, rr := range rrs {
+			if txt, ok := rr.(*dns.TXT); ok && strings.Contains(strings.Join(txt.Txt, ""), q.expect) {
+				return nil
+			}
+		}
+		return fmt.Errorf("no TXT record contains %q", q.expect)
+	}
+	return validateAnswer(rrs, q.qtype, q.expect)
+}
+
 // validateAnswer checks that at least one RR in the answer section matches
 // the expected value for the given query type.
 func validateAnswer(rrs []dns.RR, qtype uint16, expect string) error {
@@ -176,6 +316,34 @@ func validateAnswer(rrs []dns.RR, qtype uint16, expect string) error {
 					return nil
 				}
 			}
+		case dns.TypeMX:
+			if mx, ok := rr.(*dns.MX); ok && matchMX(mx, expect) {
+				return nil
+			}
+		case dns.TypeTXT:
+			if txt, ok := rr.(*dns.TXT); ok && strings.Join(txt.Txt, "") == expect {
+				return nil
+			}
+		case dns.TypeCNAME:
+			if cname, ok := rr.(*dns.CNAME); ok && sameName(cname.Target, expect) {
+				return nil
+			}
+		case dns.TypeNS:
+			if ns, ok := rr.(*dns.NS); ok && sameName(ns.Ns, expect) {
+				return nil
+			}
+		case dns.TypeSRV:
+			if srv, ok := rr.(*dns.SRV); ok && matchSRV(srv, expect) {
+				return nil
+			}
+		case dns.TypeCAA:
+			if caa, ok := rr.(*dns.CAA); ok && matchCAA(caa, expect) {
+				return nil
+			}
+		case dns.TypeSOA:
+			if soa, ok := rr.(*dns.SOA); ok && matchSOA(soa, expect) {
+				return nil
+			}
 		}
 	}
 	return fmt.Errorf("expected %q not found in answer", expect)
@@ -199,40 +367,43 @@ func normalizeFQDN(s string) string {
 
 // qtypeName returns a human-readable record type name for error messages.
 func qtypeName(qtype uint16) string {
-	switch qtype {
-	case dns.TypeA:
-		return "A"
-	case dns.TypeAAAA:
-		return "AAAA"
-	case dns.TypePTR:
-		return "PTR"
-	default:
-		return fmt.Sprintf("TYPE%d", qtype)
+	if name, ok := dns.TypeToString[qtype]; ok {
+		return name
 	}
+	return fmt.Sprintf("TYPE%d", qtype)
 }
 
 // parseQType converts a record type string to a miekg/dns type constant.
-// Supported values (case-insensitive): A, AAAA, PTR.
+// Supported values (case-insensitive) are listed in supportedTypes.
 func parseQType(s string) (uint16, error) {
-	switch strings.ToUpper(s) {
-	case "A":
-		return dns.TypeA, nil
-	case "AAAA":
-		return dns.TypeAAAA, nil
-	case "PTR":
-		return dns.TypePTR, nil
-	default:
-		return 0, fmt.Errorf("unsupported query type %q (supported: A, AAAA, PTR)", s)
+	upper := strings.ToUpper(s)
+	for _, t := range supportedTypes {
+		if dns.TypeToString[t] == upper {
+			return t, nil
+		}
+	}
+	names := make([]string, len(supportedTypes))
+	for i, t := range supportedTypes {
+		names[i] = dns.TypeToString[t]
 	}
+	return 0, fmt.Errorf("unsupported query type %q (supported: %s)", s, strings.Join(names, ", "))
 }
 
 // Factory creates a DNS Check from a config map.
 // Required keys:
 //   - "server" (string) — host:port of the DNS server to query
-//   - "queries" (list of objects) — each with "name", "type", and "expect"
+//   - "queries" (list of objects) — each with "name", "type", and "expect",
+//     an optional "dnssec" (bool), and for TXT queries an optional
+//     "match" ("exact" or "contains")
 //
 // Optional keys:
 //   - "timeout" (string) — duration string (e.g. "5s"), default "3s"
+//   - "transport" (string) — "udp" (default), "tcp", "tls" or "https"
+//   - "server_name" (string) — TLS server name override
+//   - "skip_verify" (bool) — skip TLS certificate verification
+//   - "ca_file" (string) — PEM bundle of trusted roots instead of the system roots
+//   - "signature_warning_days" (number) — degrade below this many days of
+//     RRSIG validity on DNSSEC queries, default 3
 func Factory(config map[string]any) (check.Check, error) {
 	serverRaw, ok := config["server"]
 	if !ok {
@@ -265,6 +436,50 @@ func Factory(config map[string]any) (check.Check, error) {
 		opts = append(opts, WithTimeout(d))
 	}
 
+	if v, ok := config["transport"]; ok {
+		ts, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("dns: 'transport' must be a string, got %T", v)
+		}
+		opts = append(opts, WithTransport(strings.ToLower(ts)))
+	}
+
+	if v, ok := config["server_name"]; ok {
+		sn, ok := v.(string)
+		if !ok || sn == "" {
+			return nil, fmt.Errorf("dns: 'server_name' must be a non-empty string")
+		}
+		opts = append(opts, WithServerName(sn))
+	}
+
+	if v, ok := config["skip_verify"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("dns: 'skip_verify' must be a bool, got %T", v)
+		}
+		opts = append(opts, WithSkipVerify(b))
+	}
+
+	if v, ok := config["ca_file"]; ok {
+		path, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("dns: 'ca_file' must be a string, got %T", v)
+		}
+		pool, err := certs.LoadCAFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("dns: %w", err)
+		}
+		opts = append(opts, WithRootCAs(pool))
+	}
+
+	if v, ok := config["signature_warning_days"]; ok {
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("dns: 'signature_warning_days' must be a number, got %T", v)
+		}
+		opts = append(opts, WithSignatureWarningDays(int(n)))
+	}
+
 	return New(server, queries, opts...)
 }
 
@@ -310,14 +525,71 @@ func extractQueries(config map[string]any) ([]queryConfig, error) {
 			return nil, fmt.Errorf("dns: query at index %d missing required 'expect'", i)
 		}
 
+		if err := checkExpect(qtype, expect); err != nil {
+			return nil, fmt.Errorf("dns: query at index %d: %w", i, err)
+		}
+
+		match := ""
+		if v, ok := m["match"]; ok {
+			ms, ok := v.(string)
+			if !ok || (ms != matchExact && ms != matchContains) {
+				return nil, fmt.Errorf("dns: query at index %d: 'match' must be %q or %q", i, matchExact, matchContains)
+			}
+			if qtype != dns.TypeTXT {
+				return nil, fmt.Errorf("dns: query at index %d: 'match' is only supported for TXT queries", i)
+			}
+			match = ms
+		}
+
+		dnssec := false
+		if v, ok := m["dnssec"]; ok {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("dns: query at index %d: 'dnssec' must be a bool, got %T", i, v)
+			}
+			dnssec = b
+		}
+
 		queries = append(queries, queryConfig{
 			name:      name,
 			qtype:     qtype,
 			expect:    expect,
+			match:     match,
+			dnssec:    dnssec,
 			resultKey: name,
 			dsName:    fmt.Sprintf("q%d", i),
 		})
 	}
 
+	assignResultKeys(queries)
+
 	return queries, nil
 }
+
+// assignResultKeys makes result keys unique when several queries share a
+// name (e.g. MX and TXT for the same domain). Queries with a unique name
+// keep the bare name; others become "<name> <TYPE>", and "<name> <TYPE>
+// <expect>" if that is still ambiguous.
+func assignResultKeys(queries []queryConfig) {
+	count := func(key func(q queryConfig) string) map[string]int {
+		n := make(map[string]int, len(queries))
+		for _, q := range queries {
+			n[key(q)]++
+		}
+		return n
+	}
+	byName := count(func(q queryConfig) string { return q.name })
+	withType := func(q queryConfig) string { return q.name + " " + qtypeName(q.qtype) }
+	byType := count(withType)
+
+	for i, q := range queries {
+		switch {
+		case byName[q.name] == 1:
+			queries[i].resultKey = q.name
+		case byType[withType(q)] == 1:
+			queries[i].resultKey = withType(q)
+		default:
+			queries[i].resultKey = withType(q) + " " + q.expect
+		}
+	}
+}
@@ -286,7 +286,7 @@ func TestFactory_QueryUnsupportedType(t *testing.T) {
 	config := map[string]any{
 		"server": "127.0.0.1:53",
 		"queries": []any{
-			map[string]any{"name": "example.com", "type": "MX", "expect": "mail.example.com."},
+			map[string]any{"name": "example.com", "type": "HINFO", "expect": "x86 linux"},
 		},
 	}
 	_, err := Factory(config)
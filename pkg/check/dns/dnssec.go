@@ -0,0 +1,152 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultSignatureWarningDays is the default number of days of remaining
+// RRSIG validity below which a DNSSEC query marks the check degraded.
+const DefaultSignatureWarningDays = 3
+
+// dnssecUDPSize is the EDNS0 buffer size advertised for DNSSEC queries,
+// large enough for typical signed answers and DNSKEY sets.
+const dnssecUDPSize = 4096
+
+// signatureResultKey returns the Result.Metrics key for a query's days
+// until signature expiry.
+func signatureResultKey(q queryConfig) string {
+	return q.resultKey + " rrsig"
+}
+
+// signatureDSName returns the RRD DS name for a query's days until
+// signature expiry (e.g. "q0_rrsig").
+func signatureDSName(q queryConfig) string {
+	return q.dsName + "_rrsig"
+}
+
+// newQuery builds the query message for q. DNSSEC queries set the DO bit
+// to request signatures, and the CD bit so that a validating resolver
+// hands back bogus data for this check to diagnose instead of SERVFAIL.
+func newQuery(name string, qtype uint16, dnssec bool) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = true
+	if dnssec {
+		msg.SetEdns0(dnssecUDPSize, true)
+		msg.CheckingDisabled = true
+	}
+	return msg
+}
+
+// validateDNSSEC verifies the signatures over the answer to q against the
+// signer's DNSKEY set, and the DNSKEY set's own signatures, at now. It
+// returns how long the earliest-expiring of the two remains valid.
+// Validation stops at the zone's keys; the chain of trust to the parent
+// zone's DS record is not checked.
+func (c *Check) validateDNSSEC(ctx context.Context, q queryConfig, answer []dns.RR, now time.Time) (time.Duration, error) {
+	rrset, sigs := splitSigned(answer, q.qtype)
+	if len(sigs) == 0 {
+		return 0, fmt.Errorf("no RRSIG over %s records in answer", qtypeName(q.qtype))
+	}
+	signer := sigs[0].SignerName
+
+	resp, _, err := exchange(ctx, c.client, c.server, newQuery(signer, dns.TypeDNSKEY, true))
+	if err != nil {
+		return 0, fmt.Errorf("DNSKEY %s: %w", signer, err)
+	}
+	if resp.Truncated {
+		return 0, fmt.Errorf("DNSKEY %s: response truncated, use the tcp transport", signer)
+	}
+	keyRRs, keySigs := splitSigned(resp.Answer, dns.TypeDNSKEY)
+	if len(keyRRs) == 0 {
+		return 0, fmt.Errorf("DNSKEY %s: no keys in answer", signer)
+	}
+	keys := make([]*dns.DNSKEY, 0, len(keyRRs))
+	for _, rr := range keyRRs {
+		keys = append(keys, rr.(*dns.DNSKEY))
+	}
+
+	expires, err := verifySignatures(sigs, rrset, keys, now)
+	if err != nil {
+		return 0, fmt.Errorf("RRSIG %s: %w", qtypeName(q.qtype), err)
+	}
+	keyExpires, err := verifySignatures(keySigs, keyRRs, keys, now)
+	if err != nil {
+		return 0, fmt.Errorf("RRSIG DNSKEY %s: %w", signer, err)
+	}
+
+	if keyExpires.Before(expires) {
+		expires = keyExpires
+	}
+	return expires.Sub(now), nil
+}
+
+// splitSigned separates the records of type qtype in rrs from the RRSIGs
+// covering them.
+func splitSigned(rrs []dns.RR, qtype uint16) ([]dns.RR, []*dns.RRSIG) {
+	var rrset []dns.RR
+	var sigs []*dns.RRSIG
+	for _, rr := range rrs {
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			if sig.TypeCovered == qtype {
+				sigs = append(sigs, sig)
+			}
+			continue
+		}
+		if rr.Header().Rrtype == qtype {
+			rrset = append(rrset, rr)
+		}
+	}
+	return rrset, sigs
+}
+
+// verifySignatures returns the latest expiration among sigs that verify
+// rrset with one of keys and are inside their validity window at now. A
+// zone mid-rollover may carry several signatures; one valid signature is
+// enough for validating resolvers. The error describes why the last
+// signature tried was rejected.
+func verifySignatures(sigs []*dns.RRSIG, rrset []dns.RR, keys []*dns.DNSKEY, now time.Time) (time.Time, error) {
+	if len(sigs) == 0 {
+		return time.Time{}, fmt.Errorf("no RRSIG in answer")
+	}
+	var best time.Time
+	var lastErr error
+	for _, sig := range sigs {
+		key := findKey(sig, keys)
+		if key == nil {
+			lastErr = fmt.Errorf("no DNSKEY with tag %d for signature", sig.KeyTag)
+			continue
+		}
+		if err := sig.Verify(key, rrset); err != nil {
+			lastErr = fmt.Errorf("signature with key tag %d does not verify: %w", sig.KeyTag, err)
+			continue
+		}
+		if !sig.ValidityPeriod(now) {
+			lastErr = fmt.Errorf("signature with key tag %d is outside its validity window %s to %s",
+				sig.KeyTag, dns.TimeToString(sig.Inception), dns.TimeToString(sig.Expiration))
+			continue
+		}
+		if exp := time.Unix(int64(sig.Expiration), 0); exp.After(best) {
+			best = exp
+		}
+	}
+	if best.IsZero() {
+		return best, lastErr
+	}
+	return best, nil
+}
+
+// findKey returns the key in keys that matches the signature's key tag
+// and algorithm, or nil.
+func findKey(sig *dns.RRSIG, keys []*dns.DNSKEY) *dns.DNSKEY {
+	for _, k := range keys {
+		if k.KeyTag() == sig.KeyTag && k.Algorithm == sig.Algorithm {
+			return k
+		}
+	}
+	return nil
+}
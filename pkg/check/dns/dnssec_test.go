@@ -0,0 +1,264 @@
+package dns
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// testZone is a signed example.com zone served by a test server.
+type testZone struct {
+	key      *dns.DNSKEY
+	priv     crypto.Signer
+	a        []dns.RR
+	aSigs    []dns.RR
+	keySigs  []dns.RR
+	noSigFor uint16 // record type served without signatures
+}
+
+// newTestZone generates a key for example.com and signs an A record and
+// the DNSKEY set with signatures valid from inception to expiration.
+func newTestZone(t *testing.T, inception, expiration time.Time) *testZone {
+	t.Helper()
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	z := &testZone{key: key, priv: priv.(crypto.Signer)}
+	z.a = []dns.RR{&dns.A{
+		Hdr: dns.RR_Header{Name: "www.example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+		A:   net.ParseIP("192.0.2.10"),
+	}}
+	z.aSigs = []dns.RR{z.sign(t, z.a, inception, expiration)}
+	z.keySigs = []dns.RR{z.sign(t, []dns.RR{key}, inception, expiration)}
+	return z
+}
+
+func (z *testZone) sign(t *testing.T, rrset []dns.RR, inception, expiration time.Time) *dns.RRSIG {
+	t.Helper()
+	h := rrset[0].Header()
+	sig := &dns.RRSIG{
+		Hdr:         dns.RR_Header{Name: h.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: h.Ttl},
+		TypeCovered: h.Rrtype,
+		Algorithm:   z.key.Algorithm,
+		Labels:      uint8(dns.CountLabel(h.Name)),
+		OrigTtl:     h.Ttl,
+		Inception:   uint32(inception.Unix()),
+		Expiration:  uint32(expiration.Unix()),
+		KeyTag:      z.key.KeyTag(),
+		SignerName:  z.key.Hdr.Name,
+	}
+	if err := sig.Sign(z.priv, rrset); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return sig
+}
+
+func (z *testZone) handle(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	q := r.Question[0]
+	do := r.IsEdns0() != nil && r.IsEdns0().Do()
+	switch q.Qtype {
+	case dns.TypeA:
+		m.Answer = append(m.Answer, z.a...)
+		if do && z.noSigFor != dns.TypeA {
+			m.Answer = append(m.Answer, z.aSigs...)
+		}
+	case dns.TypeDNSKEY:
+		m.Answer = append(m.Answer, z.key)
+		if do && z.noSigFor != dns.TypeDNSKEY {
+			m.Answer = append(m.Answer, z.keySigs...)
+		}
+	}
+	_ = w.WriteMsg(m)
+}
+
+func dnssecQuery() []queryConfig {
+	return []queryConfig{
+		{name: "www.example.com", qtype: dns.TypeA, expect: "192.0.2.10", resultKey: "www.example.com", dsName: "q0", dnssec: true},
+	}
+}
+
+// --- config tests ---
+
+func TestFactory_DNSSEC(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"server":                 "127.0.0.1:53",
+		"signature_warning_days": float64(5),
+		"queries": []any{
+			map[string]any{"name": "www.example.com", "type": "A", "expect": "192.0.2.10", "dnssec": true},
+			map[string]any{"name": "router.example.com", "type": "A", "expect": "192.168.1.1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := chk.(*Check)
+	if !c.queries[0].dnssec || c.queries[1].dnssec {
+		t.Error("expected dnssec only on the first query")
+	}
+	if c.sigWarning != 5 {
+		t.Errorf("expected 5 warning days, got %d", c.sigWarning)
+	}
+
+	desc := c.Describe()
+	if len(desc.Metrics) != 3 {
+		t.Fatalf("expected 3 metrics, got %d", len(desc.Metrics))
+	}
+	if m := desc.Metrics[1]; m.DSName != "q0_rrsig" || m.ResultKey != "www.example.com rrsig" || m.Unit != "days" {
+		t.Errorf("unexpected signature metric: %+v", m)
+	}
+	if desc.Metrics[2].DSName != "q1" {
+		t.Errorf("expected plain query metric after signature metric, got %q", desc.Metrics[2].DSName)
+	}
+
+	if _, err := Factory(map[string]any{
+		"server":  "127.0.0.1:53",
+		"queries": []any{map[string]any{"name": "a", "type": "A", "expect": "10.0.0.1", "dnssec": "yes"}},
+	}); err == nil {
+		t.Error("expected error for non-bool dnssec")
+	}
+	if _, err := New("127.0.0.1:53", dnssecQuery(), WithSignatureWarningDays(-1)); err == nil {
+		t.Error("expected error for negative warning days")
+	}
+}
+
+// --- Run tests ---
+
+func TestRun_DNSSEC_Valid(t *testing.T) {
+	now := time.Now()
+	z := newTestZone(t, now.Add(-24*time.Hour), now.Add(10*24*time.Hour+time.Hour))
+	addr := startTestServer(t, z.handle)
+
+	c, err := New(addr, dnssecQuery())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if result.Degraded {
+		t.Error("expected no degradation with 10 days remaining")
+	}
+	if v := result.Metrics["www.example.com rrsig"]; v == nil || *v != 10 {
+		t.Errorf("expected 10 days until signature expiry, got %v", v)
+	}
+	if v := result.Metrics["www.example.com"]; v == nil {
+		t.Error("expected RTT metric")
+	}
+}
+
+func TestRun_DNSSEC_NearExpiryDegraded(t *testing.T) {
+	now := time.Now()
+	z := newTestZone(t, now.Add(-24*time.Hour), now.Add(36*time.Hour))
+	addr := startTestServer(t, z.handle)
+
+	c, err := New(addr, dnssecQuery())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := c.Run(context.Background())
+	if !result.Success || !result.Degraded {
+		t.Fatalf("expected degraded success, got success=%v degraded=%v err=%v", result.Success, result.Degraded, result.Err)
+	}
+	if v := result.Metrics["www.example.com rrsig"]; v == nil || *v != 1 {
+		t.Errorf("expected 1 day until signature expiry, got %v", v)
+	}
+}
+
+func TestRun_DNSSEC_Failures(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		zone   func() *testZone
+		errMsg string
+	}{
+		{
+			name:   "expired signatures",
+			zone:   func() *testZone { return newTestZone(t, now.Add(-30*24*time.Hour), now.Add(-time.Hour)) },
+			errMsg: "outside its validity window",
+		},
+		{
+			name: "tampered signature",
+			zone: func() *testZone {
+				z := newTestZone(t, now.Add(-time.Hour), now.Add(7*24*time.Hour))
+				z.aSigs[0].(*dns.RRSIG).Expiration++
+				return z
+			},
+			errMsg: "does not verify",
+		},
+		{
+			name: "unsigned answer",
+			zone: func() *testZone {
+				z := newTestZone(t, now.Add(-time.Hour), now.Add(7*24*time.Hour))
+				z.noSigFor = dns.TypeA
+				return z
+			},
+			errMsg: "no RRSIG over A",
+		},
+		{
+			name: "unsigned DNSKEY set",
+			zone: func() *testZone {
+				z := newTestZone(t, now.Add(-time.Hour), now.Add(7*24*time.Hour))
+				z.noSigFor = dns.TypeDNSKEY
+				return z
+			},
+			errMsg: "RRSIG DNSKEY",
+		},
+		{
+			name: "signature from a retired key",
+			zone: func() *testZone {
+				z := newTestZone(t, now.Add(-time.Hour), now.Add(7*24*time.Hour))
+				z.aSigs[0].(*dns.RRSIG).KeyTag++
+				return z
+			},
+			errMsg: "no DNSKEY with tag",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := startTestServer(t, tt.zone().handle)
+			c, err := New(addr, dnssecQuery())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result := c.Run(context.Background())
+			if result.Success {
+				t.Fatal("expected failure")
+			}
+			if !strings.Contains(result.Err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, result.Err)
+			}
+			if v, ok := result.Metrics["www.example.com rrsig"]; !ok || v != nil {
+				t.Errorf("expected nil signature metric, got %v (present=%v)", v, ok)
+			}
+		})
+	}
+}
+
+func TestNewQuery_DNSSECBits(t *testing.T) {
+	plain := newQuery("example.com", dns.TypeA, false)
+	if plain.IsEdns0() != nil || plain.CheckingDisabled {
+		t.Error("expected no EDNS0 or CD bit without dnssec")
+	}
+	signed := newQuery("example.com", dns.TypeA, true)
+	if opt := signed.IsEdns0(); opt == nil || !opt.Do() {
+		t.Error("expected DO bit with dnssec")
+	}
+	if !signed.CheckingDisabled {
+		t.Error("expected CD bit with dnssec")
+	}
+}
@@ -0,0 +1,165 @@
+package dns
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// supportedTypes lists the record types a query may use.
+var supportedTypes = []uint16{
+	dns.TypeA,
+	dns.TypeAAAA,
+	dns.TypePTR,
+	dns.TypeMX,
+	dns.TypeTXT,
+	dns.TypeCNAME,
+	dns.TypeNS,
+	dns.TypeSRV,
+	dns.TypeCAA,
+	dns.TypeSOA,
+}
+
+// TXT match modes.
+const (
+	matchExact    = "exact"
+	matchContains = "contains"
+)
+
+// checkExpect validates the format of an expected value for the structured
+// record types, so that typos are reported at startup rather than as a
+// failing check:
+//   - MX: "host" or "preference host"
+//   - SRV: "target", "port target" or "priority weight port target"
+//   - CAA: "tag value" or "flag tag value"
+//   - SOA: "mname" or "mname rname"
+func checkExpect(qtype uint16, expect string) error {
+	fields := strings.Fields(expect)
+	switch qtype {
+	case dns.TypeMX:
+		switch len(fields) {
+		case 1:
+		case 2:
+			if _, err := parseUint16(fields[0]); err != nil {
+				return fmt.Errorf("MX expect %q: invalid preference: %w", expect, err)
+			}
+		default:
+			return fmt.Errorf("MX expect %q must be \"host\" or \"preference host\"", expect)
+		}
+	case dns.TypeSRV:
+		switch len(fields) {
+		case 1, 2, 4:
+			for _, f := range fields[:len(fields)-1] {
+				if _, err := parseUint16(f); err != nil {
+					return fmt.Errorf("SRV expect %q: %w", expect, err)
+				}
+			}
+		default:
+			return fmt.Errorf("SRV expect %q must be \"target\", \"port target\" or \"priority weight port target\"", expect)
+		}
+	case dns.TypeCAA:
+		if _, _, _, err := parseCAA(expect); err != nil {
+			return err
+		}
+	case dns.TypeSOA:
+		if len(fields) < 1 || len(fields) > 2 {
+			return fmt.Errorf("SOA expect %q must be \"mname\" or \"mname rname\"", expect)
+		}
+	}
+	return nil
+}
+
+// matchMX matches "host" against the exchange, or "preference host"
+// against both.
+func matchMX(mx *dns.MX, expect string) bool {
+	fields := strings.Fields(expect)
+	switch len(fields) {
+	case 1:
+		return sameName(mx.Mx, fields[0])
+	case 2:
+		pref, err := parseUint16(fields[0])
+		return err == nil && mx.Preference == pref && sameName(mx.Mx, fields[1])
+	}
+	return false
+}
+
+// matchSRV matches "target", "port target" or "priority weight port target".
+func matchSRV(srv *dns.SRV, expect string) bool {
+	fields := strings.Fields(expect)
+	nums := make([]uint16, 0, 3)
+	for _, f := range fields[:max(len(fields)-1, 0)] {
+		n, err := parseUint16(f)
+		if err != nil {
+			return false
+		}
+		nums = append(nums, n)
+	}
+	switch len(fields) {
+	case 1:
+		return sameName(srv.Target, fields[0])
+	case 2:
+		return srv.Port == nums[0] && sameName(srv.Target, fields[1])
+	case 4:
+		return srv.Priority == nums[0] && srv.Weight == nums[1] && srv.Port == nums[2] && sameName(srv.Target, fields[3])
+	}
+	return false
+}
+
+// matchCAA matches "tag value" or "flag tag value". Tags compare
+// case-insensitively; values must match exactly.
+func matchCAA(caa *dns.CAA, expect string) bool {
+	flag, tag, value, err := parseCAA(expect)
+	if err != nil {
+		return false
+	}
+	if flag >= 0 && int(caa.Flag) != flag {
+		return false
+	}
+	return strings.EqualFold(caa.Tag, tag) && caa.Value == value
+}
+
+// parseCAA splits a CAA expectation into flag (-1 when omitted), tag and
+// value. The value may be quoted.
+func parseCAA(expect string) (flag int, tag, value string, err error) {
+	fields := strings.Fields(expect)
+	flag = -1
+	if len(fields) >= 3 {
+		if n, perr := strconv.ParseUint(fields[0], 10, 8); perr == nil {
+			flag = int(n)
+			fields = fields[1:]
+		}
+	}
+	if len(fields) < 2 {
+		return 0, "", "", fmt.Errorf("CAA expect %q must be \"tag value\" or \"flag tag value\"", expect)
+	}
+	return flag, fields[0], strings.Trim(strings.Join(fields[1:], " "), `"`), nil
+}
+
+// matchSOA matches "mname" or "mname rname".
+func matchSOA(soa *dns.SOA, expect string) bool {
+	fields := strings.Fields(expect)
+	switch len(fields) {
+	case 1:
+		return sameName(soa.Ns, fields[0])
+	case 2:
+		return sameName(soa.Ns, fields[0]) && sameName(soa.Mbox, fields[1])
+	}
+	return false
+}
+
+// sameName compares two domain names case-insensitively, ignoring any
+// trailing dot.
+func sameName(a, b string) bool {
+	return strings.EqualFold(normalizeFQDN(a), normalizeFQDN(b))
+}
+
+// parseUint16 parses a decimal 16-bit unsigned integer.
+func parseUint16(s string) (uint16, error) {
+	n, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(n), nil
+}
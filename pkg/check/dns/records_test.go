@@ -0,0 +1,256 @@
+package dns
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// mustRR parses a zone-file record for tests.
+func mustRR(t *testing.T, s string) dns.RR {
+	t.Helper()
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatalf("bad test record %q: %v", s, err)
+	}
+	return rr
+}
+
+// --- validateAnswer tests for structured types ---
+
+func TestValidateAnswer_RecordTypes(t *testing.T) {
+	tests := []struct {
+		name   string
+		rr     string
+		qtype  uint16
+		expect string
+		ok     bool
+	}{
+		{"MX host", "example.com. 60 IN MX 10 mail.example.com.", dns.TypeMX, "mail.example.com", true},
+		{"MX host case", "example.com. 60 IN MX 10 mail.example.com.", dns.TypeMX, "MAIL.example.com.", true},
+		{"MX preference and host", "example.com. 60 IN MX 10 mail.example.com.", dns.TypeMX, "10 mail.example.com", true},
+		{"MX wrong preference", "example.com. 60 IN MX 10 mail.example.com.", dns.TypeMX, "20 mail.example.com", false},
+		{"MX wrong host", "example.com. 60 IN MX 10 mail.example.com.", dns.TypeMX, "mx.example.com", false},
+		{"TXT exact", `example.com. 60 IN TXT "v=spf1 mx -all"`, dns.TypeTXT, "v=spf1 mx -all", true},
+		{"TXT split strings", `example.com. 60 IN TXT "v=DKIM1; k=rsa; " "p=MIGf"`, dns.TypeTXT, "v=DKIM1; k=rsa; p=MIGf", true},
+		{"TXT not exact", `example.com. 60 IN TXT "v=spf1 mx -all"`, dns.TypeTXT, "v=spf1", false},
+		{"CNAME target", "www.example.com. 60 IN CNAME web.example.net.", dns.TypeCNAME, "web.example.net", true},
+		{"CNAME wrong target", "www.example.com. 60 IN CNAME web.example.net.", dns.TypeCNAME, "web.example.com", false},
+		{"NS host", "example.com. 60 IN NS ns1.example.com.", dns.TypeNS, "ns1.example.com", true},
+		{"NS wrong host", "example.com. 60 IN NS ns1.example.com.", dns.TypeNS, "ns2.example.com", false},
+		{"SRV target", "_sip._udp.example.com. 60 IN SRV 10 5 5060 sip.example.com.", dns.TypeSRV, "sip.example.com", true},
+		{"SRV port target", "_sip._udp.example.com. 60 IN SRV 10 5 5060 sip.example.com.", dns.TypeSRV, "5060 sip.example.com", true},
+		{"SRV full", "_sip._udp.example.com. 60 IN SRV 10 5 5060 sip.example.com.", dns.TypeSRV, "10 5 5060 sip.example.com", true},
+		{"SRV wrong port", "_sip._udp.example.com. 60 IN SRV 10 5 5060 sip.example.com.", dns.TypeSRV, "5061 sip.example.com", false},
+		{"SRV wrong weight", "_sip._udp.example.com. 60 IN SRV 10 5 5060 sip.example.com.", dns.TypeSRV, "10 6 5060 sip.example.com", false},
+		{"CAA tag value", `example.com. 60 IN CAA 0 issue "letsencrypt.org"`, dns.TypeCAA, "issue letsencrypt.org", true},
+		{"CAA quoted", `example.com. 60 IN CAA 0 issue "letsencrypt.org"`, dns.TypeCAA, `0 issue "letsencrypt.org"`, true},
+		{"CAA wrong flag", `example.com. 60 IN CAA 0 issue "letsencrypt.org"`, dns.TypeCAA, "128 issue letsencrypt.org", false},
+		{"CAA wrong value", `example.com. 60 IN CAA 0 issue "letsencrypt.org"`, dns.TypeCAA, "issue pki.goog", false},
+		{"SOA mname", "example.com. 60 IN SOA ns1.example.com. hostmaster.example.com. 2024010101 3600 600 604800 300", dns.TypeSOA, "ns1.example.com", true},
+		{"SOA mname rname", "example.com. 60 IN SOA ns1.example.com. hostmaster.example.com. 2024010101 3600 600 604800 300", dns.TypeSOA, "ns1.example.com hostmaster.example.com", true},
+		{"SOA wrong rname", "example.com. 60 IN SOA ns1.example.com. hostmaster.example.com. 2024010101 3600 600 604800 300", dns.TypeSOA, "ns1.example.com root.example.com", false},
+		{"type mismatch", "example.com. 60 IN NS mail.example.com.", dns.TypeMX, "mail.example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAnswer([]dns.RR{mustRR(t, tt.rr)}, tt.qtype, tt.expect)
+			if tt.ok && err != nil {
+				t.Errorf("expected match, got: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("expected mismatch error")
+			}
+		})
+	}
+}
+
+func TestValidate_TXTContains(t *testing.T) {
+	rrs := []dns.RR{
+		mustRR(t, `example.com. 60 IN TXT "google-site-verification=abc"`),
+		mustRR(t, `example.com. 60 IN TXT "v=spf1 include:_spf.example.net -all"`),
+	}
+	q := queryConfig{name: "example.com", qtype: dns.TypeTXT, expect: "include:_spf.example.net", match: matchContains}
+	if err := q.validate(rrs); err != nil {
+		t.Errorf("expected substring match, got: %v", err)
+	}
+	q.match = matchExact
+	if err := q.validate(rrs); err == nil {
+		t.Error("expected exact match to fail on a substring")
+	}
+}
+
+// --- config parsing tests ---
+
+func TestCheckExpect(t *testing.T) {
+	tests := []struct {
+		qtype  uint16
+		expect string
+		ok     bool
+	}{
+		{dns.TypeMX, "mail.example.com", true},
+		{dns.TypeMX, "10 mail.example.com", true},
+		{dns.TypeMX, "ten mail.example.com", false},
+		{dns.TypeMX, "10 mail.example.com extra", false},
+		{dns.TypeSRV, "sip.example.com", true},
+		{dns.TypeSRV, "5060 sip.example.com", true},
+		{dns.TypeSRV, "10 5 5060 sip.example.com", true},
+		{dns.TypeSRV, "5 5060 sip.example.com", false},
+		{dns.TypeSRV, "70000 sip.example.com", false},
+		{dns.TypeCAA, "issue letsencrypt.org", true},
+		{dns.TypeCAA, "letsencrypt.org", false},
+		{dns.TypeSOA, "ns1.example.com a b", false},
+		{dns.TypeTXT, "anything at all", true},
+	}
+	for _, tt := range tests {
+		err := checkExpect(tt.qtype, tt.expect)
+		if tt.ok && err != nil {
+			t.Errorf("%s %q: unexpected error: %v", qtypeName(tt.qtype), tt.expect, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s %q: expected error", qtypeName(tt.qtype), tt.expect)
+		}
+	}
+}
+
+func TestFactory_NewRecordTypes(t *testing.T) {
+	for _, typ := range []string{"mx", "TXT", "CNAME", "NS", "SRV", "CAA", "SOA"} {
+		expect := "host.example.com"
+		if typ == "CAA" {
+			expect = "issue letsencrypt.org"
+		}
+		_, err := Factory(map[string]any{
+			"server":  "127.0.0.1:53",
+			"queries": []any{map[string]any{"name": "example.com", "type": typ, "expect": expect}},
+		})
+		if err != nil {
+			t.Errorf("type %s: unexpected error: %v", typ, err)
+		}
+	}
+}
+
+func TestFactory_Match(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"server": "127.0.0.1:53",
+		"queries": []any{
+			map[string]any{"name": "example.com", "type": "TXT", "expect": "v=spf1", "match": "contains"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m := chk.(*Check).queries[0].match; m != matchContains {
+		t.Errorf("expected contains match, got %q", m)
+	}
+
+	for _, q := range []map[string]any{
+		{"name": "example.com", "type": "TXT", "expect": "v=spf1", "match": "prefix"},
+		{"name": "example.com", "type": "A", "expect": "10.0.0.1", "match": "contains"},
+	} {
+		if _, err := Factory(map[string]any{"server": "127.0.0.1:53", "queries": []any{q}}); err == nil {
+			t.Errorf("expected error for %v", q)
+		}
+	}
+}
+
+func TestFactory_SharedNameResultKeys(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"server": "127.0.0.1:53",
+		"queries": []any{
+			map[string]any{"name": "router.example.com", "type": "A", "expect": "192.168.1.1"},
+			map[string]any{"name": "example.com", "type": "MX", "expect": "mail.example.com"},
+			map[string]any{"name": "example.com", "type": "TXT", "expect": "v=spf1 mx -all"},
+			map[string]any{"name": "example.com", "type": "NS", "expect": "ns1.example.com"},
+			map[string]any{"name": "example.com", "type": "NS", "expect": "ns2.example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"router.example.com",
+		"example.com MX",
+		"example.com TXT",
+		"example.com NS ns1.example.com",
+		"example.com NS ns2.example.com",
+	}
+	desc := chk.Describe()
+	for i, w := range want {
+		if got := desc.Metrics[i].ResultKey; got != w {
+			t.Errorf("query %d: expected result key %q, got %q", i, w, got)
+		}
+	}
+}
+
+// --- Run integration test using in-process test server ---
+
+func TestRun_RecordTypes(t *testing.T) {
+	zone := map[uint16][]string{
+		dns.TypeMX:  {"example.com. 60 IN MX 10 mail.example.com.", "example.com. 60 IN MX 20 backup.example.com."},
+		dns.TypeTXT: {`example.com. 60 IN TXT "v=spf1 mx -all"`},
+		dns.TypeNS:  {"example.com. 60 IN NS ns1.example.com."},
+		dns.TypeSRV: {"_imaps._tcp.example.com. 60 IN SRV 0 1 993 mail.example.com."},
+		dns.TypeCAA: {`example.com. 60 IN CAA 0 issue "letsencrypt.org"`},
+		dns.TypeSOA: {"example.com. 60 IN SOA ns1.example.com. hostmaster.example.com. 1 3600 600 604800 300"},
+	}
+	addr := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		switch {
+		case q.Qtype == dns.TypeCNAME && q.Name == "www.example.com.":
+			m.Answer = append(m.Answer, mustRR(t, "www.example.com. 60 IN CNAME web.example.net."))
+		case q.Qtype == dns.TypeSRV && q.Name != "_imaps._tcp.example.com.":
+			m.Rcode = dns.RcodeNameError
+		default:
+			for _, s := range zone[q.Qtype] {
+				m.Answer = append(m.Answer, mustRR(t, s))
+			}
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	chk, err := Factory(map[string]any{
+		"server": addr,
+		"queries": []any{
+			map[string]any{"name": "example.com", "type": "MX", "expect": "20 backup.example.com"},
+			map[string]any{"name": "example.com", "type": "TXT", "expect": "-all", "match": "contains"},
+			map[string]any{"name": "www.example.com", "type": "CNAME", "expect": "web.example.net."},
+			map[string]any{"name": "example.com", "type": "NS", "expect": "ns1.example.com"},
+			map[string]any{"name": "_imaps._tcp.example.com", "type": "SRV", "expect": "993 mail.example.com"},
+			map[string]any{"name": "example.com", "type": "CAA", "expect": "issue letsencrypt.org"},
+			map[string]any{"name": "example.com", "type": "SOA", "expect": "ns1.example.com hostmaster.example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := chk.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got failure: %v", result.Err)
+	}
+	for _, m := range chk.Describe().Metrics {
+		if v := result.Metrics[m.ResultKey]; v == nil {
+			t.Errorf("expected RTT for %q", m.ResultKey)
+		}
+	}
+
+	// A wrong MX preference fails with the record type in the error.
+	chk, err = Factory(map[string]any{
+		"server":  addr,
+		"queries": []any{map[string]any{"name": "example.com", "type": "MX", "expect": "10 backup.example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result = chk.Run(context.Background())
+	if result.Success {
+		t.Fatal("expected failure for wrong MX preference")
+	}
+	if !strings.Contains(result.Err.Error(), "dns MX example.com") {
+		t.Errorf("expected error to name the query, got %v", result.Err)
+	}
+}
@@ -0,0 +1,363 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+	"github.com/miekg/dns"
+)
+
+const (
+	// SerialTypeName is the registered name for the SOA serial consistency
+	// check type.
+	SerialTypeName = "dns_serial"
+
+	// DefaultSerialGrace is how long nameservers may disagree on a zone's
+	// serial before the check reports it.
+	DefaultSerialGrace = 30 * time.Minute
+)
+
+// Severities for a serial mismatch that outlasts the grace period.
+const (
+	SeverityDown     = "down"
+	SeverityDegraded = "degraded"
+)
+
+// nameserverConfig maps a single nameserver to its RRD data source.
+type nameserverConfig struct {
+	address   string // host:port of the nameserver
+	resultKey string // key in Result.Metrics for the RTT (= address)
+	dsName    string // RRD DS name (e.g. "ns0")
+}
+
+// serialResultKey returns the Result.Metrics key for a nameserver's serial.
+func serialResultKey(ns nameserverConfig) string {
+	return ns.resultKey + " serial"
+}
+
+// SerialCheck implements check.Check by querying the SOA record of a zone
+// on each of its authoritative nameservers and comparing the serials.
+// Nameservers may disagree for up to the grace period, which allows for
+// normal zone transfer delay; a longer mismatch marks the check down or
+// degraded depending on the configured severity.
+type SerialCheck struct {
+	zone        string
+	nameservers []nameserverConfig
+	timeout     time.Duration
+	grace       time.Duration
+	severity    string
+	client      *dns.Client
+	desc        check.Descriptor
+	now         func() time.Time
+
+	mu            sync.Mutex
+	mismatchSince time.Time // zero while all serials agree
+}
+
+// SerialOption is a functional option for configuring a SerialCheck.
+type SerialOption func(*SerialCheck) error
+
+// WithSerialTimeout sets the per-nameserver query timeout.
+func WithSerialTimeout(d time.Duration) SerialOption {
+	return func(c *SerialCheck) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout must be positive, got %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithGrace sets how long serials may differ before the check reports it.
+// Zero reports any mismatch immediately.
+func WithGrace(d time.Duration) SerialOption {
+	return func(c *SerialCheck) error {
+		if d < 0 {
+			return fmt.Errorf("grace must not be negative, got %v", d)
+		}
+		c.grace = d
+		return nil
+	}
+}
+
+// WithSeverity sets whether a mismatch outlasting the grace period marks
+// the check SeverityDown (the default) or SeverityDegraded.
+func WithSeverity(s string) SerialOption {
+	return func(c *SerialCheck) error {
+		if s != SeverityDown && s != SeverityDegraded {
+			return fmt.Errorf("severity must be %q or %q, got %q", SeverityDown, SeverityDegraded, s)
+		}
+		c.severity = s
+		return nil
+	}
+}
+
+// NewSerial creates a SerialCheck for zone on the given nameservers. A
+// nameserver without a port uses port 53.
+func NewSerial(zone string, nameservers []string, opts ...SerialOption) (*SerialCheck, error) {
+	if zone == "" {
+		return nil, fmt.Errorf("dns_serial: zone must not be empty")
+	}
+	if len(nameservers) < 2 {
+		return nil, fmt.Errorf("dns_serial: at least two nameservers are required")
+	}
+
+	nss := make([]nameserverConfig, len(nameservers))
+	for i, ns := range nameservers {
+		if ns == "" {
+			return nil, fmt.Errorf("dns_serial: nameserver at index %d must not be empty", i)
+		}
+		if _, _, err := net.SplitHostPort(ns); err != nil {
+			ns = net.JoinHostPort(ns, "53")
+		}
+		nss[i] = nameserverConfig{
+			address:   ns,
+			resultKey: ns,
+			dsName:    fmt.Sprintf("ns%d", i),
+		}
+	}
+
+	c := &SerialCheck{
+		zone:        zone,
+		nameservers: nss,
+		timeout:     DefaultTimeout,
+		grace:       DefaultSerialGrace,
+		severity:    SeverityDown,
+		now:         time.Now,
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("dns_serial: %w", err)
+		}
+	}
+
+	c.client = &dns.Client{
+		Timeout: c.timeout,
+	}
+
+	metrics := make([]check.MetricDef, len(nss))
+	for i, ns := range nss {
+		metrics[i] = check.MetricDef{
+			ResultKey: ns.resultKey,
+			DSName:    ns.dsName,
+			Label:     ns.address,
+			Unit:      "ms",
+			Scale:     1000,
+		}
+	}
+	c.desc = check.Descriptor{
+		Label:   "soa rtt",
+		Metrics: metrics,
+	}
+
+	return c, nil
+}
+
+// Type returns the check type name.
+func (c *SerialCheck) Type() string {
+	return SerialTypeName
+}
+
+// Describe returns the Descriptor for this check instance.
+func (c *SerialCheck) Describe() check.Descriptor {
+	return c.desc
+}
+
+// Run queries the zone's SOA on every nameserver and returns a Result.
+// Each nameserver's RTT is stored in microseconds keyed by its address,
+// and its serial under "<address> serial" (reported through the API but
+// not stored in the RRD). Success requires every nameserver to answer
+// authoritatively; serials that have differed for longer than the grace
+// period fail the check or mark it degraded.
+func (c *SerialCheck) Run(ctx context.Context) check.Result {
+	metrics := make(map[string]*float64, 2*len(c.nameservers))
+	serials := make(map[string]uint32, len(c.nameservers))
+	var lastErr error
+
+	for _, ns := range c.nameservers {
+		serial, rtt, err := c.querySOA(ctx, ns.address)
+		if err != nil {
+			lastErr = fmt.Errorf("dns_serial %s on %s: %w", c.zone, ns.address, err)
+			metrics[ns.resultKey] = nil
+			metrics[serialResultKey(ns)] = nil
+			continue
+		}
+		v, sv := float64(rtt.Microseconds()), float64(serial)
+		metrics[ns.resultKey] = &v
+		metrics[serialResultKey(ns)] = &sv
+		serials[ns.address] = serial
+	}
+
+	now := c.now()
+	result := check.Result{
+		Timestamp: now,
+		Success:   lastErr == nil,
+		Err:       lastErr,
+		Metrics:   metrics,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !serialsDiffer(serials) {
+		// Only a full, agreeing set of answers clears a mismatch; a
+		// nameserver that is down says nothing about its serial.
+		if len(serials) == len(c.nameservers) {
+			c.mismatchSince = time.Time{}
+		}
+		return result
+	}
+
+	if c.mismatchSince.IsZero() {
+		c.mismatchSince = now
+	}
+	elapsed := now.Sub(c.mismatchSince)
+	if elapsed < c.grace {
+		return result
+	}
+
+	mismatch := fmt.Errorf("dns_serial %s: serials differ for %s (grace %s): %s",
+		c.zone, elapsed.Truncate(time.Second), c.grace, formatSerials(serials))
+	if c.severity == SeverityDegraded {
+		result.Degraded = true
+		if result.Err == nil {
+			result.Err = mismatch
+		}
+		return result
+	}
+	result.Success = false
+	result.Err = mismatch
+	return result
+}
+
+// querySOA asks a nameserver for the zone's SOA without recursion and
+// returns its serial. The answer must be authoritative: a secondary that
+// has let the zone expire answers SERVFAIL or without the AA bit.
+func (c *SerialCheck) querySOA(ctx context.Context, server string) (uint32, time.Duration, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(c.zone), dns.TypeSOA)
+	msg.RecursionDesired = false
+
+	resp, rtt, err := exchange(ctx, c.client, server, msg)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !resp.Authoritative {
+		return 0, 0, fmt.Errorf("answer is not authoritative")
+	}
+	for _, rr := range resp.Answer {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return soa.Serial, rtt, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("no SOA record in answer")
+}
+
+// serialsDiffer reports whether the collected serials disagree.
+func serialsDiffer(serials map[string]uint32) bool {
+	first := true
+	var want uint32
+	for _, s := range serials {
+		if first {
+			want, first = s, false
+			continue
+		}
+		if s != want {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSerials renders serials as "addr=serial" pairs in address order.
+func formatSerials(serials map[string]uint32) string {
+	addrs := make([]string, 0, len(serials))
+	for a := range serials {
+		addrs = append(addrs, a)
+	}
+	sort.Strings(addrs)
+	parts := make([]string, len(addrs))
+	for i, a := range addrs {
+		parts[i] = fmt.Sprintf("%s=%d", a, serials[a])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SerialFactory creates a SerialCheck from a config map.
+// Required keys:
+//   - "zone" (string) — zone whose SOA serial is compared
+//   - "nameservers" (list of strings) — at least two, as host or host:port
+//
+// Optional keys:
+//   - "timeout" (string) — per-query duration string, default "3s"
+//   - "grace" (string) — how long serials may differ, default "30m"
+//   - "severity" (string) — "down" (default) or "degraded"
+func SerialFactory(config map[string]any) (check.Check, error) {
+	zone, ok := config["zone"].(string)
+	if !ok || zone == "" {
+		return nil, fmt.Errorf("dns_serial: config missing required key 'zone'")
+	}
+
+	raw, ok := config["nameservers"]
+	if !ok {
+		return nil, fmt.Errorf("dns_serial: config missing required key 'nameservers'")
+	}
+	var nameservers []string
+	switch v := raw.(type) {
+	case []string:
+		nameservers = v
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("dns_serial: 'nameservers' items must be strings, got %T", item)
+			}
+			nameservers = append(nameservers, s)
+		}
+	default:
+		return nil, fmt.Errorf("dns_serial: 'nameservers' must be a list, got %T", raw)
+	}
+
+	var opts []SerialOption
+
+	if v, ok := config["timeout"]; ok {
+		ts, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("dns_serial: 'timeout' must be a string, got %T", v)
+		}
+		d, err := time.ParseDuration(ts)
+		if err != nil {
+			return nil, fmt.Errorf("dns_serial: invalid timeout %q: %w", ts, err)
+		}
+		opts = append(opts, WithSerialTimeout(d))
+	}
+
+	if v, ok := config["grace"]; ok {
+		gs, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("dns_serial: 'grace' must be a string, got %T", v)
+		}
+		d, err := time.ParseDuration(gs)
+		if err != nil {
+			return nil, fmt.Errorf("dns_serial: invalid grace %q: %w", gs, err)
+		}
+		opts = append(opts, WithGrace(d))
+	}
+
+	if v, ok := config["severity"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("dns_serial: 'severity' must be a string, got %T", v)
+		}
+		opts = append(opts, WithSeverity(s))
+	}
+
+	return NewSerial(zone, nameservers, opts...)
+}
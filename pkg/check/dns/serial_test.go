@@ -0,0 +1,224 @@
+package dns
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// startSOAServer starts an authoritative test server for example.com whose
+// SOA serial is read from serial on every query.
+func startSOAServer(t *testing.T, serial *atomic.Uint32) string {
+	t.Helper()
+	return startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Authoritative = true
+		m.Answer = append(m.Answer, &dns.SOA{
+			Hdr:     dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 60},
+			Ns:      "ns1.example.com.",
+			Mbox:    "hostmaster.example.com.",
+			Serial:  serial.Load(),
+			Refresh: 3600, Retry: 600, Expire: 604800, Minttl: 300,
+		})
+		_ = w.WriteMsg(m)
+	})
+}
+
+// fakeClock is a manually advanced clock for grace period tests.
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time          { return f.t }
+func (f *fakeClock) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+// --- NewSerial / SerialFactory tests ---
+
+func TestNewSerial_Defaults(t *testing.T) {
+	c, err := NewSerial("example.com", []string{"ns1.example.com", "192.0.2.2:5353"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.nameservers[0].address != "ns1.example.com:53" {
+		t.Errorf("expected default port 53, got %q", c.nameservers[0].address)
+	}
+	if c.nameservers[1].address != "192.0.2.2:5353" {
+		t.Errorf("expected explicit port kept, got %q", c.nameservers[1].address)
+	}
+	if c.grace != DefaultSerialGrace || c.severity != SeverityDown {
+		t.Errorf("expected default grace and severity, got %v/%s", c.grace, c.severity)
+	}
+	desc := c.Describe()
+	if len(desc.Metrics) != 2 || desc.Metrics[1].DSName != "ns1" || desc.Metrics[1].Unit != "ms" {
+		t.Errorf("unexpected descriptor: %+v", desc.Metrics)
+	}
+	if c.Type() != SerialTypeName {
+		t.Errorf("expected type %q, got %q", SerialTypeName, c.Type())
+	}
+}
+
+func TestNewSerial_Errors(t *testing.T) {
+	if _, err := NewSerial("", []string{"a", "b"}); err == nil {
+		t.Error("expected error for empty zone")
+	}
+	if _, err := NewSerial("example.com", []string{"a"}); err == nil {
+		t.Error("expected error for a single nameserver")
+	}
+	if _, err := NewSerial("example.com", []string{"a", ""}); err == nil {
+		t.Error("expected error for empty nameserver")
+	}
+	if _, err := NewSerial("example.com", []string{"a", "b"}, WithGrace(-time.Second)); err == nil {
+		t.Error("expected error for negative grace")
+	}
+	if _, err := NewSerial("example.com", []string{"a", "b"}, WithSeverity("critical")); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
+
+func TestSerialFactory(t *testing.T) {
+	chk, err := SerialFactory(map[string]any{
+		"zone":        "example.com",
+		"nameservers": []any{"ns1.example.com", "ns2.example.com"},
+		"timeout":     "2s",
+		"grace":       "1h",
+		"severity":    "degraded",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := chk.(*SerialCheck)
+	if c.timeout != 2*time.Second || c.grace != time.Hour || c.severity != SeverityDegraded {
+		t.Errorf("unexpected config: timeout=%v grace=%v severity=%s", c.timeout, c.grace, c.severity)
+	}
+
+	bad := []map[string]any{
+		{"nameservers": []any{"a", "b"}},
+		{"zone": "example.com"},
+		{"zone": "example.com", "nameservers": "a,b"},
+		{"zone": "example.com", "nameservers": []any{"a", 2}},
+		{"zone": "example.com", "nameservers": []any{"a", "b"}, "grace": "soon"},
+		{"zone": "example.com", "nameservers": []any{"a", "b"}, "timeout": 3},
+		{"zone": "example.com", "nameservers": []any{"a", "b"}, "severity": true},
+	}
+	for _, cfg := range bad {
+		if _, err := SerialFactory(cfg); err == nil {
+			t.Errorf("expected error for %v", cfg)
+		}
+	}
+}
+
+// --- Run tests ---
+
+func TestSerialRun_Consistent(t *testing.T) {
+	var s1, s2 atomic.Uint32
+	s1.Store(2024010101)
+	s2.Store(2024010101)
+	a1, a2 := startSOAServer(t, &s1), startSOAServer(t, &s2)
+
+	c, err := NewSerial("example.com", []string{a1, a2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := c.Run(context.Background())
+	if !result.Success || result.Degraded {
+		t.Fatalf("expected clean success, got success=%v degraded=%v err=%v", result.Success, result.Degraded, result.Err)
+	}
+	for _, a := range []string{a1, a2} {
+		if v := result.Metrics[a+" serial"]; v == nil || *v != 2024010101 {
+			t.Errorf("expected serial for %s, got %v", a, v)
+		}
+		if v := result.Metrics[a]; v == nil || *v <= 0 {
+			t.Errorf("expected positive RTT for %s, got %v", a, v)
+		}
+	}
+}
+
+func TestSerialRun_GracePeriod(t *testing.T) {
+	var s1, s2 atomic.Uint32
+	s1.Store(2)
+	s2.Store(1)
+	a1, a2 := startSOAServer(t, &s1), startSOAServer(t, &s2)
+
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	c, err := NewSerial("example.com", []string{a1, a2}, WithGrace(10*time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.now = clock.now
+
+	// Within the grace period the mismatch is tolerated.
+	if r := c.Run(context.Background()); !r.Success {
+		t.Fatalf("expected success within grace, got %v", r.Err)
+	}
+	clock.advance(9 * time.Minute)
+	if r := c.Run(context.Background()); !r.Success {
+		t.Fatalf("expected success within grace, got %v", r.Err)
+	}
+
+	// Past the grace period the check fails and reports the serials.
+	clock.advance(2 * time.Minute)
+	r := c.Run(context.Background())
+	if r.Success {
+		t.Fatal("expected failure after grace period")
+	}
+	if !strings.Contains(r.Err.Error(), a1+"=2") || !strings.Contains(r.Err.Error(), a2+"=1") {
+		t.Errorf("expected error to list serials, got %v", r.Err)
+	}
+
+	// Once the secondary catches up the mismatch clears, and a new
+	// mismatch starts a fresh grace period.
+	s2.Store(2)
+	if r := c.Run(context.Background()); !r.Success {
+		t.Fatalf("expected success once serials agree, got %v", r.Err)
+	}
+	s1.Store(3)
+	clock.advance(time.Hour)
+	if r := c.Run(context.Background()); !r.Success {
+		t.Fatalf("expected new mismatch to start a fresh grace period, got %v", r.Err)
+	}
+}
+
+func TestSerialRun_SeverityDegraded(t *testing.T) {
+	var s1, s2 atomic.Uint32
+	s1.Store(2)
+	s2.Store(1)
+	a1, a2 := startSOAServer(t, &s1), startSOAServer(t, &s2)
+
+	c, err := NewSerial("example.com", []string{a1, a2}, WithGrace(0), WithSeverity(SeverityDegraded))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := c.Run(context.Background())
+	if !r.Success || !r.Degraded {
+		t.Errorf("expected degraded success, got success=%v degraded=%v", r.Success, r.Degraded)
+	}
+}
+
+func TestSerialRun_NotAuthoritative(t *testing.T) {
+	var s1 atomic.Uint32
+	s1.Store(1)
+	a1 := startSOAServer(t, &s1)
+	lame := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		_ = w.WriteMsg(m)
+	})
+
+	c, err := NewSerial("example.com", []string{a1, lame})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := c.Run(context.Background())
+	if r.Success {
+		t.Fatal("expected failure when a nameserver cannot answer for the zone")
+	}
+	if v, ok := r.Metrics[lame+" serial"]; !ok || v != nil {
+		t.Errorf("expected nil serial for failing nameserver, got %v (present=%v)", v, ok)
+	}
+	if v := r.Metrics[a1+" serial"]; v == nil || *v != 1 {
+		t.Errorf("expected serial for healthy nameserver, got %v", v)
+	}
+}
@@ -0,0 +1,138 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Transports a DNS Check can send queries over.
+const (
+	TransportUDP   = "udp"
+	TransportTCP   = "tcp"
+	TransportTLS   = "tls"   // DNS over TLS (RFC 7858)
+	TransportHTTPS = "https" // DNS over HTTPS (RFC 8484)
+)
+
+// DefaultTLSPort is the port used for DNS over TLS when the server has none.
+const DefaultTLSPort = "853"
+
+// dohMediaType is the content type of DNS over HTTPS messages.
+const dohMediaType = "application/dns-message"
+
+// maxDoHResponseBytes bounds how much of a DNS over HTTPS response is read.
+const maxDoHResponseBytes = 64 * 1024
+
+// exchanger sends a DNS message and returns the response and round-trip
+// time. The round-trip time covers only the query exchange, not connection
+// setup or TLS handshakes, so that it is comparable across transports.
+// *dns.Client implements it for the udp, tcp and tls transports.
+type exchanger interface {
+	ExchangeContext(ctx context.Context, m *dns.Msg, address string) (*dns.Msg, time.Duration, error)
+}
+
+// newExchanger returns the exchanger for a transport. tlsConfig is used
+// by the tls and https transports.
+func newExchanger(transport string, timeout time.Duration, tlsConfig *tls.Config) exchanger {
+	switch transport {
+	case TransportTCP:
+		return &dns.Client{Net: "tcp", Timeout: timeout}
+	case TransportTLS:
+		return &dns.Client{Net: "tcp-tls", Timeout: timeout, TLSConfig: tlsConfig}
+	case TransportHTTPS:
+		return &dohClient{
+			client: &http.Client{
+				Timeout:   timeout,
+				Transport: &http.Transport{TLSClientConfig: tlsConfig},
+			},
+		}
+	default:
+		return &dns.Client{Timeout: timeout}
+	}
+}
+
+// normalizeServer validates server for the transport and fills in
+// defaults: a DNS over TLS server without a port uses DefaultTLSPort, and
+// a DNS over HTTPS server must be an https URL.
+func normalizeServer(transport, server string) (string, error) {
+	switch transport {
+	case TransportHTTPS:
+		u, err := url.Parse(server)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return "", fmt.Errorf("server %q must be an https URL for the https transport", server)
+		}
+		return server, nil
+	case TransportTLS:
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			return net.JoinHostPort(server, DefaultTLSPort), nil
+		}
+		return server, nil
+	default:
+		return server, nil
+	}
+}
+
+// dohClient sends queries as DNS over HTTPS POST requests.
+type dohClient struct {
+	client *http.Client
+}
+
+// ExchangeContext posts m to the DoH endpoint at address (a URL). The
+// round-trip time is measured from when a connection is ready until the
+// response body has been read.
+func (d *dohClient) ExchangeContext(ctx context.Context, m *dns.Msg, address string) (*dns.Msg, time.Duration, error) {
+	// RFC 8484 recommends an ID of 0 so responses are cache friendly.
+	q := m.Copy()
+	q.Id = 0
+	body, err := q.Pack()
+	if err != nil {
+		return nil, 0, fmt.Errorf("pack query: %w", err)
+	}
+
+	var start time.Time
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) { start = time.Now() },
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", dohMediaType)
+	req.Header.Set("Accept", dohMediaType)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("http status %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != dohMediaType {
+		return nil, 0, fmt.Errorf("unexpected content type %q", ct)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxDoHResponseBytes))
+	if err != nil {
+		return nil, 0, fmt.Errorf("read response: %w", err)
+	}
+	rtt := time.Since(start)
+
+	r := new(dns.Msg)
+	if err := r.Unpack(raw); err != nil {
+		return nil, 0, fmt.Errorf("unpack response: %w", err)
+	}
+	r.Id = m.Id
+	return r, rtt, nil
+}
@@ -0,0 +1,271 @@
+package dns
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// answerA replies to every query with a fixed A record.
+func answerA(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Answer = append(m.Answer, &dns.A{
+		Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+		A:   net.ParseIP("192.168.168.1"),
+	})
+	_ = w.WriteMsg(m)
+}
+
+// testCert returns a certificate valid for 127.0.0.1 and example.com along
+// with a pool that trusts it.
+func testCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return srv.TLS.Certificates[0], pool
+}
+
+// startStreamServer starts an in-process DNS server on a TCP listener,
+// wrapped in TLS when cert is non-nil.
+func startStreamServer(t *testing.T, cert *tls.Certificate, handler dns.HandlerFunc) string {
+	t.Helper()
+	var ln net.Listener
+	var err error
+	if cert != nil {
+		ln, err = tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{*cert}})
+	} else {
+		ln, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	srv := &dns.Server{Listener: ln, Handler: handler}
+	go func() { _ = srv.ActivateAndServe() }()
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return ln.Addr().String()
+}
+
+// startDoHServer starts a DNS over HTTPS endpoint that answers with handler.
+func startDoHServer(t *testing.T, handler dns.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dohMediaType {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		q := new(dns.Msg)
+		if err := q.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rec := &recordingWriter{}
+		handler(rec, q)
+		out, _ := rec.msg.Pack()
+		w.Header().Set("Content-Type", dohMediaType)
+		_, _ = w.Write(out)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// recordingWriter is a dns.ResponseWriter that keeps the written message.
+type recordingWriter struct {
+	dns.ResponseWriter
+	msg *dns.Msg
+}
+
+func (w *recordingWriter) WriteMsg(m *dns.Msg) error { w.msg = m; return nil }
+
+var aQuery = []queryConfig{
+	{name: "router.example.com", qtype: dns.TypeA, expect: "192.168.168.1", resultKey: "router.example.com", dsName: "q0"},
+}
+
+// --- option and config tests ---
+
+func TestNew_DefaultTransport(t *testing.T) {
+	c, err := New("127.0.0.1:53", aQuery)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.transport != TransportUDP {
+		t.Errorf("expected udp transport, got %q", c.transport)
+	}
+}
+
+func TestNew_TransportErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		server string
+		opts   []Option
+	}{
+		{"unknown transport", "127.0.0.1:53", []Option{WithTransport("quic")}},
+		{"tls options on udp", "127.0.0.1:53", []Option{WithSkipVerify(true)}},
+		{"server name on tcp", "127.0.0.1:53", []Option{WithTransport(TransportTCP), WithServerName("dns.example.com")}},
+		{"https without URL", "127.0.0.1:443", []Option{WithTransport(TransportHTTPS)}},
+		{"https with http URL", "http://dns.example.com/dns-query", []Option{WithTransport(TransportHTTPS)}},
+		{"nil roots", "127.0.0.1:853", []Option{WithTransport(TransportTLS), WithRootCAs(nil)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.server, aQuery, tt.opts...); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestNew_TLSDefaultPort(t *testing.T) {
+	c, err := New("dns.example.com", aQuery, WithTransport(TransportTLS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.server != "dns.example.com:853" {
+		t.Errorf("expected default DoT port, got %q", c.server)
+	}
+}
+
+func TestFactory_Transport(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"server":      "1.1.1.1",
+		"transport":   "TLS",
+		"server_name": "cloudflare-dns.com",
+		"skip_verify": false,
+		"queries":     []any{map[string]any{"name": "example.com", "type": "A", "expect": "93.184.215.14"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := chk.(*Check)
+	if c.transport != TransportTLS || c.serverName != "cloudflare-dns.com" || c.server != "1.1.1.1:853" {
+		t.Errorf("unexpected config: transport=%s serverName=%s server=%s", c.transport, c.serverName, c.server)
+	}
+
+	base := func() map[string]any {
+		return map[string]any{
+			"server":  "127.0.0.1:853",
+			"queries": []any{map[string]any{"name": "example.com", "type": "A", "expect": "10.0.0.1"}},
+		}
+	}
+	for key, v := range map[string]any{
+		"transport":   1,
+		"server_name": "",
+		"skip_verify": "yes",
+		"ca_file":     "/nonexistent/ca.pem",
+	} {
+		cfg := base()
+		cfg["transport"] = "tls"
+		cfg[key] = v
+		if _, err := Factory(cfg); err == nil {
+			t.Errorf("expected error for %s=%v", key, v)
+		}
+	}
+}
+
+// --- Run over each transport ---
+
+func TestRun_TCPTransport(t *testing.T) {
+	addr := startStreamServer(t, nil, answerA)
+	c, err := New(addr, aQuery, WithTransport(TransportTCP))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if v := result.Metrics["router.example.com"]; v == nil || *v <= 0 {
+		t.Errorf("expected positive RTT, got %v", v)
+	}
+}
+
+func TestRun_TLSTransport(t *testing.T) {
+	cert, pool := testCert(t)
+	addr := startStreamServer(t, &cert, answerA)
+
+	c, err := New(addr, aQuery, WithTransport(TransportTLS), WithRootCAs(pool))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if v := result.Metrics["router.example.com"]; v == nil || *v <= 0 {
+		t.Errorf("expected positive RTT, got %v", v)
+	}
+
+	// Without the test roots the certificate must be rejected.
+	c, err = New(addr, aQuery, WithTransport(TransportTLS))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result := c.Run(context.Background()); result.Success {
+		t.Error("expected failure for untrusted certificate")
+	}
+
+	// A server name the certificate does not cover is rejected too.
+	c, err = New(addr, aQuery, WithTransport(TransportTLS), WithRootCAs(pool), WithServerName("dns.invalid"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result := c.Run(context.Background()); result.Success {
+		t.Error("expected failure for mismatched server name")
+	}
+}
+
+func TestRun_HTTPSTransport(t *testing.T) {
+	srv := startDoHServer(t, answerA)
+	_, pool := testCert(t)
+
+	c, err := New(srv.URL+"/dns-query", aQuery, WithTransport(TransportHTTPS), WithRootCAs(pool))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if v := result.Metrics["router.example.com"]; v == nil || *v <= 0 {
+		t.Errorf("expected positive RTT, got %v", v)
+	}
+}
+
+func TestRun_HTTPSTransport_RcodeFailure(t *testing.T) {
+	srv := startDoHServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNameError)
+		_ = w.WriteMsg(m)
+	})
+
+	c, err := New(srv.URL+"/dns-query", aQuery, WithTransport(TransportHTTPS), WithSkipVerify(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result := c.Run(context.Background()); result.Success {
+		t.Error("expected failure for NXDOMAIN over https")
+	}
+}
+
+func TestRun_HTTPSTransport_HTTPError(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	c, err := New(srv.URL+"/dns-query", aQuery, WithTransport(TransportHTTPS), WithSkipVerify(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result := c.Run(context.Background()); result.Success {
+		t.Error("expected failure for an endpoint that is not DoH")
+	}
+}
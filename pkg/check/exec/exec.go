@@ -0,0 +1,381 @@
+// Package exec implements a check that runs an external command following
+// the Nagios plugin conventions, so existing plugins can be reused without
+// porting them to Go.
+//
+// The command's exit code sets the check state:
+//
+//	0  OK        check up
+//	1  WARNING   check up, marked degraded
+//	2  CRITICAL  check down
+//	3  UNKNOWN   check down, marked unknown (as is any other code)
+//
+// The first line of output is the status text, and any performance data
+// after a "|" (label=value[UOM];warn;crit;min;max) can be recorded as
+// metrics by declaring the perfdata labels in the config. The run time of
+// the command is always recorded.
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	osexec "os/exec"
+	"strings"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+)
+
+const (
+	// TypeName is the registered name for this check type.
+	TypeName = "exec"
+
+	// DefaultTimeout is the default limit on how long the command may run.
+	DefaultTimeout = 10 * time.Second
+
+	// DurationResultKey is the key used in Result.Metrics for the command's
+	// run time.
+	DurationResultKey = "duration"
+)
+
+// Plugin exit codes.
+const (
+	exitOK       = 0
+	exitWarning  = 1
+	exitCritical = 2
+)
+
+// maxOutputBytes bounds how much of the command's output is kept.
+const maxOutputBytes = 64 * 1024
+
+// metricConfig maps a perfdata label to an RRD data source.
+type metricConfig struct {
+	perfLabel string // label in the plugin's perfdata (e.g. "/", "time")
+	resultKey string // key in Result.Metrics (= label)
+	dsName    string // RRD DS name (e.g. "perf0")
+	label     string // human-readable label (defaults to perfLabel)
+	unit      string
+	divisor   int // graphs show the value ÷ divisor
+}
+
+// Check implements check.Check by running a Nagios-style plugin.
+type Check struct {
+	command []string
+	metrics []metricConfig
+	timeout time.Duration
+	desc    check.Descriptor
+}
+
+// Option is a functional option for configuring an exec Check.
+type Option func(*Check) error
+
+// WithTimeout sets how long the command may run before it is killed.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Check) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout must be positive, got %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// newCheck creates an exec Check that runs command (program and arguments;
+// no shell is involved) and records the given perfdata metrics.
+func newCheck(command []string, metrics []metricConfig, opts ...Option) (*Check, error) {
+	if len(command) == 0 || command[0] == "" {
+		return nil, fmt.Errorf("exec: command must not be empty")
+	}
+
+	seen := map[string]bool{DurationResultKey: true}
+	for _, m := range metrics {
+		if seen[m.resultKey] {
+			return nil, fmt.Errorf("exec: duplicate metric %q", m.resultKey)
+		}
+		seen[m.resultKey] = true
+	}
+
+	c := &Check{
+		command: command,
+		metrics: metrics,
+		timeout: DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("exec: %w", err)
+		}
+	}
+
+	defs := make([]check.MetricDef, 0, len(metrics)+1)
+	defs = append(defs, check.MetricDef{
+		ResultKey: DurationResultKey,
+		DSName:    "duration",
+		Label:     "duration",
+		Unit:      "ms",
+		Scale:     1000,
+	})
+	for _, m := range metrics {
+		defs = append(defs, check.MetricDef{
+			ResultKey: m.resultKey,
+			DSName:    m.dsName,
+			Label:     m.label,
+			Unit:      m.unit,
+			Scale:     m.divisor,
+		})
+	}
+	c.desc = check.Descriptor{
+		Label:   "plugin",
+		Metrics: defs,
+	}
+
+	return c, nil
+}
+
+// Type returns the check type name.
+func (c *Check) Type() string {
+	return TypeName
+}
+
+// Describe returns the Descriptor for this check instance.
+func (c *Check) Describe() check.Descriptor {
+	return c.desc
+}
+
+// Run executes the command and returns a Result. The run time is stored in
+// microseconds under DurationResultKey, and each declared perfdata value
+// as reported; a perfdata label missing from the output, or reported as
+// "U", is recorded as nil. A command that runs past the timeout is killed
+// and reported down, as Nagios does; one that cannot be started is
+// reported unknown.
+func (c *Check) Run(ctx context.Context) check.Result {
+	now := time.Now()
+
+	timedOut := fmt.Errorf("%s timed out after %v", c.command[0], c.timeout)
+	runCtx, cancel := context.WithTimeoutCause(ctx, c.timeout, timedOut)
+	defer cancel()
+
+	cmd := osexec.CommandContext(runCtx, c.command[0], c.command[1:]...)
+	// Don't wait on grandchildren that inherited the output pipes.
+	cmd.WaitDelay = time.Second
+	var stdout, stderr limitedBuffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	elapsed := time.Since(start)
+
+	metrics := make(map[string]*float64, len(c.metrics)+1)
+	for _, m := range c.metrics {
+		metrics[m.resultKey] = nil
+	}
+
+	var exitErr *osexec.ExitError
+	switch {
+	case runCtx.Err() != nil:
+		// Report what ended the command: its own timeout, or the run's
+		// deadline or shutdown through ctx.
+		cause := context.Cause(runCtx)
+		if cause != timedOut {
+			cause = fmt.Errorf("%s stopped: %w", c.command[0], cause)
+		}
+		metrics[DurationResultKey] = nil
+		return check.Result{
+			Timestamp: now,
+			Success:   false,
+			Metrics:   metrics,
+			Err:       fmt.Errorf("exec: %w", cause),
+		}
+	case err != nil && !errors.As(err, &exitErr):
+		metrics[DurationResultKey] = nil
+		return check.Result{
+			Timestamp: now,
+			Success:   false,
+			Unknown:   true,
+			Metrics:   metrics,
+			Err:       fmt.Errorf("exec: %w", err),
+		}
+	}
+
+	d := float64(elapsed.Microseconds())
+	metrics[DurationResultKey] = &d
+
+	text, perf := parseOutput(stdout.String())
+	for _, m := range c.metrics {
+		pv, ok := perf[m.perfLabel]
+		if !ok || !pv.known {
+			continue
+		}
+		metrics[m.resultKey] = &pv.value
+	}
+
+	code := exitOK
+	if exitErr != nil {
+		code = exitErr.ExitCode()
+	}
+	if text == "" {
+		text, _, _ = strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+	}
+	if text == "" {
+		text = fmt.Sprintf("exit status %d", code)
+	}
+
+	result := check.Result{
+		Timestamp: now,
+		Metrics:   metrics,
+	}
+	switch code {
+	case exitOK:
+		result.Success = true
+	case exitWarning:
+		result.Success = true
+		result.Degraded = true
+		result.Err = fmt.Errorf("exec: %s", text)
+	case exitCritical:
+		result.Err = fmt.Errorf("exec: %s", text)
+	default: // UNKNOWN (3) or an unconventional code
+		result.Unknown = true
+		result.Err = fmt.Errorf("exec: %s", text)
+	}
+	return result
+}
+
+// limitedBuffer collects up to maxOutputBytes of output and discards the
+// rest, so a chatty command can neither exhaust memory nor block on a
+// full pipe.
+type limitedBuffer struct {
+	b strings.Builder
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxOutputBytes - l.b.Len(); room > 0 {
+		l.b.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+func (l *limitedBuffer) String() string {
+	return l.b.String()
+}
+
+// Factory creates an exec Check from a config map.
+// Required keys:
+//   - "command" (string or list of strings) — program, or program and
+//     arguments; no shell is involved
+//
+// Optional keys:
+//   - "timeout" (string) — duration string, default "10s"
+//   - "metrics" (list of objects) — perfdata values to record, see below
+//
+// Each metric object has:
+//   - "perfdata" (string, required) — label in the plugin's perfdata
+//   - "label" (string) — display label and result key, default the perfdata label
+//   - "unit" (string) — display unit
+//   - "divisor" (number) — integer graphs divide the value by, default 1
+func Factory(config map[string]any) (check.Check, error) {
+	raw, ok := config["command"]
+	if !ok {
+		return nil, fmt.Errorf("exec: config missing required key 'command'")
+	}
+	var command []string
+	switch v := raw.(type) {
+	case string:
+		command = []string{v}
+	case []string:
+		command = v
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("exec: 'command' items must be strings, got %T", item)
+			}
+			command = append(command, s)
+		}
+	default:
+		return nil, fmt.Errorf("exec: 'command' must be a string or list of strings, got %T", raw)
+	}
+
+	metrics, err := extractMetrics(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []Option
+
+	if v, ok := config["timeout"]; ok {
+		ts, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("exec: 'timeout' must be a duration string, got %T", v)
+		}
+		d, err := time.ParseDuration(ts)
+		if err != nil {
+			return nil, fmt.Errorf("exec: invalid timeout %q: %w", ts, err)
+		}
+		opts = append(opts, WithTimeout(d))
+	}
+
+	return newCheck(command, metrics, opts...)
+}
+
+// extractMetrics parses the optional "metrics" config key.
+func extractMetrics(config map[string]any) ([]metricConfig, error) {
+	raw, ok := config["metrics"]
+	if !ok {
+		return nil, nil
+	}
+
+	rawList, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("exec: 'metrics' must be a list, got %T", raw)
+	}
+
+	metrics := make([]metricConfig, 0, len(rawList))
+	for i, item := range rawList {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("exec: metric at index %d must be an object, got %T", i, item)
+		}
+
+		perfLabel, ok := m["perfdata"].(string)
+		if !ok || perfLabel == "" {
+			return nil, fmt.Errorf("exec: metric at index %d missing required 'perfdata'", i)
+		}
+
+		mc := metricConfig{
+			perfLabel: perfLabel,
+			label:     perfLabel,
+			dsName:    fmt.Sprintf("perf%d", i),
+		}
+
+		if v, ok := m["label"]; ok {
+			s, ok := v.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("exec: metric at index %d: 'label' must be a non-empty string", i)
+			}
+			mc.label = s
+		}
+		mc.resultKey = mc.label
+
+		if v, ok := m["unit"]; ok {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("exec: metric at index %d: 'unit' must be a string, got %T", i, v)
+			}
+			mc.unit = s
+		}
+
+		if v, ok := m["divisor"]; ok {
+			f, ok := v.(float64)
+			if !ok || f < 1 || f != math.Trunc(f) {
+				return nil, fmt.Errorf("exec: metric at index %d: 'divisor' must be a positive integer", i)
+			}
+			mc.divisor = int(f)
+		}
+
+		metrics = append(metrics, mc)
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,244 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// shell returns a command running script under /bin/sh.
+func shell(script string) []string {
+	return []string{"/bin/sh", "-c", script}
+}
+
+func diskMetrics() []metricConfig {
+	return []metricConfig{
+		{perfLabel: "/", resultKey: "root used", dsName: "perf0", label: "root used", unit: "GB", divisor: 1000},
+		{perfLabel: "time", resultKey: "time", dsName: "perf1", label: "time", unit: "s"},
+	}
+}
+
+// --- newCheck() tests ---
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := newCheck(nil, nil); err == nil {
+		t.Error("expected error for empty command")
+	}
+	if _, err := newCheck([]string{""}, nil); err == nil {
+		t.Error("expected error for empty program")
+	}
+	dup := []metricConfig{{perfLabel: "duration", resultKey: "duration", dsName: "perf0", label: "duration"}}
+	if _, err := newCheck([]string{"true"}, dup); err == nil {
+		t.Error("expected error for metric clashing with duration")
+	}
+	if _, err := newCheck([]string{"true"}, nil, WithTimeout(0)); err == nil {
+		t.Error("expected error for zero timeout")
+	}
+}
+
+func TestDescribe_DurationFirst(t *testing.T) {
+	c, err := newCheck([]string{"true"}, diskMetrics())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := c.Describe().Metrics
+	if len(m) != 3 {
+		t.Fatalf("expected 3 metrics, got %d", len(m))
+	}
+	if m[0].ResultKey != DurationResultKey || m[0].Scale != 1000 {
+		t.Errorf("expected duration metric first, got %+v", m[0])
+	}
+	if m[1].DSName != "perf0" || m[1].Scale != 1000 || m[2].DSName != "perf1" {
+		t.Errorf("unexpected perfdata metrics: %+v", m[1:])
+	}
+}
+
+// --- Run() tests ---
+
+func TestRun_ExitCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		success  bool
+		degraded bool
+		unknown  bool
+	}{
+		{"ok", "0", true, false, false},
+		{"warning", "1", true, true, false},
+		{"critical", "2", false, false, false},
+		{"unknown", "3", false, false, true},
+		{"unconventional", "42", false, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newCheck(shell("echo 'DISK STATE | /=2643MB;5948;5958;0;5968 time=0.012s'; exit "+tt.code), diskMetrics())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result := c.Run(context.Background())
+			if result.Success != tt.success || result.Degraded != tt.degraded || result.Unknown != tt.unknown {
+				t.Errorf("expected success=%v degraded=%v unknown=%v, got %v/%v/%v (err=%v)",
+					tt.success, tt.degraded, tt.unknown, result.Success, result.Degraded, result.Unknown, result.Err)
+			}
+			if tt.code != "0" && (result.Err == nil || !strings.Contains(result.Err.Error(), "DISK STATE")) {
+				t.Errorf("expected status text in error, got %v", result.Err)
+			}
+			// Perfdata is recorded whatever the state.
+			if v := result.Metrics["root used"]; v == nil || *v != 2643 {
+				t.Errorf("expected root used 2643, got %v", v)
+			}
+			if v := result.Metrics["time"]; v == nil || *v != 0.012 {
+				t.Errorf("expected time 0.012, got %v", v)
+			}
+			if v := result.Metrics[DurationResultKey]; v == nil || *v <= 0 {
+				t.Errorf("expected positive duration, got %v", v)
+			}
+		})
+	}
+}
+
+func TestRun_MissingPerfdata(t *testing.T) {
+	c, _ := newCheck(shell("echo 'OK | time=U'"), diskMetrics())
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	for _, k := range []string{"root used", "time"} {
+		if v, ok := result.Metrics[k]; !ok || v != nil {
+			t.Errorf("%s: expected nil metric, got %v (present=%v)", k, v, ok)
+		}
+	}
+}
+
+func TestRun_StderrFallback(t *testing.T) {
+	c, _ := newCheck(shell("echo 'cannot open device' >&2; exit 3"), nil)
+	result := c.Run(context.Background())
+	if result.Success || !result.Unknown {
+		t.Fatalf("expected unknown failure, got success=%v unknown=%v", result.Success, result.Unknown)
+	}
+	if !strings.Contains(result.Err.Error(), "cannot open device") {
+		t.Errorf("expected stderr text in error, got %v", result.Err)
+	}
+}
+
+func TestRun_NoOutput(t *testing.T) {
+	c, _ := newCheck(shell("exit 2"), nil)
+	result := c.Run(context.Background())
+	if result.Success || result.Unknown {
+		t.Fatalf("expected critical failure, got success=%v unknown=%v", result.Success, result.Unknown)
+	}
+	if !strings.Contains(result.Err.Error(), "exit status 2") {
+		t.Errorf("expected exit status in error, got %v", result.Err)
+	}
+}
+
+func TestRun_NotFound(t *testing.T) {
+	c, _ := newCheck([]string{"/nonexistent/check_nothing"}, diskMetrics())
+	result := c.Run(context.Background())
+	if result.Success || !result.Unknown {
+		t.Fatalf("expected unknown failure, got success=%v unknown=%v", result.Success, result.Unknown)
+	}
+	if v, ok := result.Metrics[DurationResultKey]; !ok || v != nil {
+		t.Errorf("expected nil duration, got %v (present=%v)", v, ok)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	c, _ := newCheck(shell("sleep 10"), nil, WithTimeout(100*time.Millisecond))
+	start := time.Now()
+	result := c.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the command to be killed promptly, took %v", elapsed)
+	}
+	if result.Success || result.Unknown {
+		t.Fatalf("expected down (not unknown) on timeout, got success=%v unknown=%v", result.Success, result.Unknown)
+	}
+	if !strings.Contains(result.Err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", result.Err)
+	}
+}
+
+func TestRun_ParentDeadline(t *testing.T) {
+	c, _ := newCheck(shell("sleep 10"), nil, WithTimeout(time.Minute))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result := c.Run(ctx)
+	if result.Success || result.Unknown {
+		t.Fatalf("expected down when the run's deadline passes, got success=%v unknown=%v", result.Success, result.Unknown)
+	}
+	if strings.Contains(result.Err.Error(), "timed out after") || !errors.Is(result.Err, context.DeadlineExceeded) {
+		t.Errorf("expected the run's deadline to be reported, got %v", result.Err)
+	}
+}
+
+func TestRun_OutputLimit(t *testing.T) {
+	c, _ := newCheck(shell("head -c 200000 /dev/zero | tr '\\0' x; echo"), nil)
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+}
+
+// --- Factory tests ---
+
+func TestFactory_Valid(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"command": []any{"/usr/lib/nagios/plugins/check_disk", "-w", "20%", "-c", "10%", "-p", "/"},
+		"timeout": "30s",
+		"metrics": []any{
+			map[string]any{"perfdata": "/", "label": "root used", "unit": "GB", "divisor": float64(1000)},
+			map[string]any{"perfdata": "time", "unit": "s"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := chk.(*Check)
+	if len(c.command) != 7 || c.command[0] != "/usr/lib/nagios/plugins/check_disk" {
+		t.Errorf("unexpected command %v", c.command)
+	}
+	if c.timeout != 30*time.Second {
+		t.Errorf("expected 30s timeout, got %v", c.timeout)
+	}
+	if c.metrics[0].perfLabel != "/" || c.metrics[0].resultKey != "root used" || c.metrics[0].divisor != 1000 {
+		t.Errorf("unexpected first metric %+v", c.metrics[0])
+	}
+	if c.metrics[1].resultKey != "time" {
+		t.Errorf("expected perfdata label as default result key, got %+v", c.metrics[1])
+	}
+}
+
+func TestFactory_CommandString(t *testing.T) {
+	chk, err := Factory(map[string]any{"command": "/usr/local/bin/check_ups"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m := chk.Describe().Metrics; len(m) != 1 || m[0].ResultKey != DurationResultKey {
+		t.Errorf("expected only the duration metric, got %+v", m)
+	}
+}
+
+func TestFactory_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{"missing command", map[string]any{}},
+		{"command wrong type", map[string]any{"command": float64(1)}},
+		{"command item wrong type", map[string]any{"command": []any{"check", float64(1)}}},
+		{"empty command list", map[string]any{"command": []any{}}},
+		{"metrics not list", map[string]any{"command": "true", "metrics": "x"}},
+		{"missing perfdata", map[string]any{"command": "true", "metrics": []any{map[string]any{"label": "x"}}}},
+		{"bad divisor", map[string]any{"command": "true", "metrics": []any{map[string]any{"perfdata": "x", "divisor": float64(-1)}}}},
+		{"bad timeout", map[string]any{"command": "true", "timeout": "forever"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Factory(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package exec
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// perfValue is a single parsed performance data value. Thresholds and
+// bounds (warn;crit;min;max) are not used and are discarded.
+type perfValue struct {
+	value float64
+	known bool   // false for the "U" (unknown) value
+	uom   string // unit of measurement, e.g. "ms", "%", "MB", "c"
+}
+
+// perfValueRe splits a perfdata value into its number and unit.
+var perfValueRe = regexp.MustCompile(`^([-+]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][-+]?[0-9]+)?)([a-zA-Z%]*)$`)
+
+// parseOutput splits plugin output into the status text and performance
+// data, following the Nagios plugin output format:
+//
+//	TEXT OUTPUT | OPTIONAL PERFDATA
+//	LONG TEXT LINE 1
+//	LONG TEXT LINE 2 | PERFDATA LINE 2
+//	PERFDATA LINE 3
+//
+// The status text is the first line before any "|". Perfdata is the rest
+// of the first line, plus everything after the first "|" on later lines.
+func parseOutput(out string) (string, map[string]perfValue) {
+	first, rest, _ := strings.Cut(out, "\n")
+	text, perf, _ := strings.Cut(first, "|")
+	if _, more, ok := strings.Cut(rest, "|"); ok {
+		perf += " " + more
+	}
+	return strings.TrimSpace(text), parsePerfdata(perf)
+}
+
+// parsePerfdata parses space-separated 'label'=value[UOM];warn;crit;min;max
+// items. Labels containing spaces are single-quoted, with a doubled quote
+// standing for a literal one. Malformed items are skipped, as Nagios does.
+func parsePerfdata(s string) map[string]perfValue {
+	values := make(map[string]perfValue)
+	i := 0
+	for i < len(s) {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+
+		var label string
+		if s[i] == '\'' {
+			var b strings.Builder
+			i++
+			for i < len(s) {
+				if s[i] == '\'' {
+					if i+1 < len(s) && s[i+1] == '\'' {
+						b.WriteByte('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(s[i])
+				i++
+			}
+			label = b.String()
+		} else {
+			start := i
+			for i < len(s) && s[i] != '=' && !isSpace(s[i]) {
+				i++
+			}
+			label = s[start:i]
+		}
+
+		start := i
+		for i < len(s) && !isSpace(s[i]) {
+			i++
+		}
+		item := s[start:i]
+		if label == "" || !strings.HasPrefix(item, "=") {
+			continue
+		}
+		raw, _, _ := strings.Cut(item[1:], ";")
+		if v, ok := parsePerfValue(raw); ok {
+			values[label] = v
+		}
+	}
+	return values
+}
+
+// parsePerfValue parses a value with an optional unit, or "U".
+func parsePerfValue(raw string) (perfValue, bool) {
+	if raw == "U" {
+		return perfValue{}, true
+	}
+	m := perfValueRe.FindStringSubmatch(raw)
+	if m == nil {
+		return perfValue{}, false
+	}
+	f, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return perfValue{}, false
+	}
+	return perfValue{value: f, known: true, uom: m[2]}, true
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
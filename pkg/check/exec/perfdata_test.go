@@ -0,0 +1,75 @@
+package exec
+
+import "testing"
+
+func TestParseOutput(t *testing.T) {
+	out := "DISK OK - free space: / 3326 MB (56%); | /=2643MB;5948;5958;0;5968\n" +
+		"/ 15272 MB (77%);\n" +
+		"/boot 68 MB (69%); | /boot=68MB;88;93;0;98\n" +
+		"/home=69357MB;253404;253409;0;253414\n"
+	text, perf := parseOutput(out)
+	if text != "DISK OK - free space: / 3326 MB (56%);" {
+		t.Errorf("unexpected text %q", text)
+	}
+	want := map[string]float64{"/": 2643, "/boot": 68, "/home": 69357}
+	if len(perf) != len(want) {
+		t.Fatalf("expected %d perfdata values, got %v", len(want), perf)
+	}
+	for label, v := range want {
+		pv, ok := perf[label]
+		if !ok || !pv.known || pv.value != v || pv.uom != "MB" {
+			t.Errorf("%s: expected %v MB, got %+v (ok=%v)", label, v, pv, ok)
+		}
+	}
+}
+
+func TestParseOutput_NoPerfdata(t *testing.T) {
+	text, perf := parseOutput("PING OK - Packet loss = 0%\n")
+	if text != "PING OK - Packet loss = 0%" {
+		t.Errorf("unexpected text %q", text)
+	}
+	if len(perf) != 0 {
+		t.Errorf("expected no perfdata, got %v", perf)
+	}
+}
+
+func TestParsePerfdata(t *testing.T) {
+	perf := parsePerfdata(`time=0.006s;;;0.000000 size=1024B 'in use'=85%;80;90 'it''s'=3 load1=0.42;1;2;0; bytes=5c temp=U rta=-1.5e2ms`)
+	tests := []struct {
+		label string
+		value float64
+		uom   string
+		known bool
+	}{
+		{"time", 0.006, "s", true},
+		{"size", 1024, "B", true},
+		{"in use", 85, "%", true},
+		{"it's", 3, "", true},
+		{"load1", 0.42, "", true},
+		{"bytes", 5, "c", true},
+		{"temp", 0, "", false},
+		{"rta", -150, "ms", true},
+	}
+	for _, tt := range tests {
+		pv, ok := perf[tt.label]
+		if !ok {
+			t.Errorf("%s: missing", tt.label)
+			continue
+		}
+		if pv.known != tt.known || pv.value != tt.value || pv.uom != tt.uom {
+			t.Errorf("%s: expected %v%s (known=%v), got %+v", tt.label, tt.value, tt.uom, tt.known, pv)
+		}
+	}
+}
+
+func TestParsePerfdata_SkipsMalformed(t *testing.T) {
+	perf := parsePerfdata(`good=1 novalue= noequals bad=abc 'unterminated=2 after=3`)
+	if pv, ok := perf["good"]; !ok || pv.value != 1 {
+		t.Errorf("expected good=1, got %+v", pv)
+	}
+	for _, label := range []string{"novalue", "noequals", "bad"} {
+		if _, ok := perf[label]; ok {
+			t.Errorf("expected malformed %q to be skipped", label)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// statusRange is an inclusive range of accepted HTTP status codes.
+type statusRange struct {
+	lo, hi int
+}
+
+// assertions holds the per-URL response checks. The zero value (apart from
+// maxBodyBytes) accepts any response.
+type assertions struct {
+	status       []statusRange     // accepted status codes; empty accepts any
+	bodyContains string            // substring the body must contain
+	bodyRegex    *regexp.Regexp    // pattern the body must match
+	headers      map[string]string // required headers; "" only requires presence
+	maxBodyBytes int64             // limit on body bytes read for body checks
+}
+
+// check evaluates the assertions against a response and returns an error
+// describing the first failing assertion. The body is only read when a body
+// assertion is configured, and never beyond maxBodyBytes.
+func (a assertions) check(resp *http.Response) error {
+	if len(a.status) > 0 && !a.statusAllowed(resp.StatusCode) {
+		return fmt.Errorf("unexpected status %d (expected %s)", resp.StatusCode, a.statusString())
+	}
+
+	names := make([]string, 0, len(a.headers))
+	for name := range a.headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want := a.headers[name]
+		values, ok := resp.Header[http.CanonicalHeaderKey(name)]
+		if !ok {
+			return fmt.Errorf("missing required header %q", name)
+		}
+		if want != "" && !containsAny(values, want) {
+			return fmt.Errorf("header %q is %q, expected it to contain %q", name, strings.Join(values, ", "), want)
+		}
+	}
+
+	if a.bodyContains == "" && a.bodyRegex == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, a.maxBodyBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+	if a.bodyContains != "" && !strings.Contains(string(body), a.bodyContains) {
+		return fmt.Errorf("body does not contain %q (read %d bytes)", a.bodyContains, len(body))
+	}
+	if a.bodyRegex != nil && !a.bodyRegex.Match(body) {
+		return fmt.Errorf("body does not match /%s/ (read %d bytes)", a.bodyRegex, len(body))
+	}
+	return nil
+}
+
+// statusAllowed reports whether code falls within any accepted range.
+func (a assertions) statusAllowed(code int) bool {
+	for _, r := range a.status {
+		if code >= r.lo && code <= r.hi {
+			return true
+		}
+	}
+	return false
+}
+
+// statusString renders the accepted status ranges for error messages.
+func (a assertions) statusString() string {
+	parts := make([]string, len(a.status))
+	for i, r := range a.status {
+		if r.lo == r.hi {
+			parts[i] = strconv.Itoa(r.lo)
+		} else {
+			parts[i] = fmt.Sprintf("%d-%d", r.lo, r.hi)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// containsAny reports whether any value contains the substring want.
+func containsAny(values []string, want string) bool {
+	for _, v := range values {
+		if strings.Contains(v, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAssertions reads the optional per-URL assertion keys from a URL object:
+//   - "expect_status" (number, string, or list) — accepted codes, e.g. 200, "2xx", "200-299"
+//   - "expect_body" (string) — substring the body must contain
+//   - "expect_body_regex" (string) — regular expression the body must match
+//   - "expect_headers" (object) — header name to required substring ("" for presence only)
+//   - "max_body_bytes" (number) — limit on body bytes read, default 1 MiB
+func parseAssertions(m map[string]any) (assertions, error) {
+	a := assertions{maxBodyBytes: DefaultMaxBodyBytes}
+
+	if v, ok := m["expect_status"]; ok {
+		items, ok := v.([]any)
+		if !ok {
+			items = []any{v}
+		}
+		if len(items) == 0 {
+			return a, fmt.Errorf("'expect_status' must not be empty")
+		}
+		for _, item := range items {
+			r, err := parseStatusRange(item)
+			if err != nil {
+				return a, err
+			}
+			a.status = append(a.status, r)
+		}
+	}
+
+	if v, ok := m["expect_body"]; ok {
+		s, ok := v.(string)
+		if !ok || s == "" {
+			return a, fmt.Errorf("'expect_body' must be a non-empty string")
+		}
+		a.bodyContains = s
+	}
+
+	if v, ok := m["expect_body_regex"]; ok {
+		s, ok := v.(string)
+		if !ok || s == "" {
+			return a, fmt.Errorf("'expect_body_regex' must be a non-empty string")
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return a, fmt.Errorf("invalid 'expect_body_regex': %w", err)
+		}
+		a.bodyRegex = re
+	}
+
+	if v, ok := m["expect_headers"]; ok {
+		hm, ok := v.(map[string]any)
+		if !ok {
+			return a, fmt.Errorf("'expect_headers' must be an object, got %T", v)
+		}
+		a.headers = make(map[string]string, len(hm))
+		for name, hv := range hm {
+			s, ok := hv.(string)
+			if !ok {
+				return a, fmt.Errorf("'expect_headers' value for %q must be a string, got %T", name, hv)
+			}
+			a.headers[name] = s
+		}
+	}
+
+	if v, ok := m["max_body_bytes"]; ok {
+		n, ok := v.(float64)
+		if !ok {
+			return a, fmt.Errorf("'max_body_bytes' must be a number, got %T", v)
+		}
+		if n < 1 {
+			return a, fmt.Errorf("'max_body_bytes' must be positive, got %v", n)
+		}
+		a.maxBodyBytes = int64(n)
+	}
+
+	return a, nil
+}
+
+// parseStatusRange parses a single expect_status entry. Numbers are exact
+// codes; strings may be an exact code ("204"), a class ("2xx"), or an
+// inclusive range ("200-299").
+func parseStatusRange(v any) (statusRange, error) {
+	switch s := v.(type) {
+	case float64:
+		code := int(s)
+		if float64(code) != s || code < 100 || code > 599 {
+			return statusRange{}, fmt.Errorf("invalid status code %v", s)
+		}
+		return statusRange{code, code}, nil
+	case string:
+		if len(s) == 3 && strings.HasSuffix(strings.ToLower(s), "xx") {
+			class, err := strconv.Atoi(s[:1])
+			if err != nil || class < 1 || class > 5 {
+				return statusRange{}, fmt.Errorf("invalid status class %q", s)
+			}
+			return statusRange{class * 100, class*100 + 99}, nil
+		}
+		loStr, hiStr, isRange := strings.Cut(s, "-")
+		lo, err := strconv.Atoi(strings.TrimSpace(loStr))
+		if err != nil {
+			return statusRange{}, fmt.Errorf("invalid status %q", s)
+		}
+		hi := lo
+		if isRange {
+			hi, err = strconv.Atoi(strings.TrimSpace(hiStr))
+			if err != nil {
+				return statusRange{}, fmt.Errorf("invalid status range %q", s)
+			}
+		}
+		if lo < 100 || hi > 599 || lo > hi {
+			return statusRange{}, fmt.Errorf("invalid status range %q", s)
+		}
+		return statusRange{lo, hi}, nil
+	default:
+		return statusRange{}, fmt.Errorf("'expect_status' entries must be numbers or strings, got %T", v)
+	}
+}
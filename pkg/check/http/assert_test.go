@@ -0,0 +1,264 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// --- parseStatusRange tests ---
+
+func TestParseStatusRange(t *testing.T) {
+	tests := []struct {
+		in     any
+		lo, hi int
+	}{
+		{float64(200), 200, 200},
+		{"204", 204, 204},
+		{"2xx", 200, 299},
+		{"3XX", 300, 399},
+		{"200-299", 200, 299},
+		{"401 - 403", 401, 403},
+	}
+	for _, tt := range tests {
+		r, err := parseStatusRange(tt.in)
+		if err != nil {
+			t.Errorf("parseStatusRange(%v): unexpected error: %v", tt.in, err)
+			continue
+		}
+		if r.lo != tt.lo || r.hi != tt.hi {
+			t.Errorf("parseStatusRange(%v) = %d-%d, want %d-%d", tt.in, r.lo, r.hi, tt.lo, tt.hi)
+		}
+	}
+}
+
+func TestParseStatusRange_Invalid(t *testing.T) {
+	for _, in := range []any{float64(99), float64(600), float64(200.5), "abc", "6xx", "0xx", "299-200", "200-", "100-700", true} {
+		if _, err := parseStatusRange(in); err == nil {
+			t.Errorf("parseStatusRange(%v): expected error", in)
+		}
+	}
+}
+
+// --- parseAssertions tests ---
+
+func TestParseAssertions_AllKeys(t *testing.T) {
+	a, err := parseAssertions(map[string]any{
+		"expect_status":     []any{float64(200), "3xx"},
+		"expect_body":       "ok",
+		"expect_body_regex": `"status":\s*"up"`,
+		"expect_headers":    map[string]any{"Content-Type": "json", "X-Request-Id": ""},
+		"max_body_bytes":    float64(4096),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(a.status) != 2 {
+		t.Errorf("expected 2 status ranges, got %d", len(a.status))
+	}
+	if a.bodyContains != "ok" || a.bodyRegex == nil {
+		t.Error("expected body assertions to be set")
+	}
+	if len(a.headers) != 2 {
+		t.Errorf("expected 2 headers, got %d", len(a.headers))
+	}
+	if a.maxBodyBytes != 4096 {
+		t.Errorf("expected max body 4096, got %d", a.maxBodyBytes)
+	}
+}
+
+func TestParseAssertions_SingleStatus(t *testing.T) {
+	a, err := parseAssertions(map[string]any{"expect_status": float64(204)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(a.status) != 1 || a.status[0].lo != 204 {
+		t.Errorf("expected single 204 range, got %v", a.status)
+	}
+}
+
+func TestParseAssertions_Defaults(t *testing.T) {
+	a, err := parseAssertions(map[string]any{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.maxBodyBytes != DefaultMaxBodyBytes {
+		t.Errorf("expected default max body bytes, got %d", a.maxBodyBytes)
+	}
+	if len(a.status) != 0 || a.bodyContains != "" || a.bodyRegex != nil || a.headers != nil {
+		t.Error("expected no assertions by default")
+	}
+}
+
+func TestParseAssertions_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		m    map[string]any
+	}{
+		{"empty status list", map[string]any{"expect_status": []any{}}},
+		{"bad status", map[string]any{"expect_status": "abc"}},
+		{"empty body", map[string]any{"expect_body": ""}},
+		{"body wrong type", map[string]any{"expect_body": 1}},
+		{"bad regex", map[string]any{"expect_body_regex": "("}},
+		{"headers wrong type", map[string]any{"expect_headers": []any{"X"}}},
+		{"header value wrong type", map[string]any{"expect_headers": map[string]any{"X": 1}}},
+		{"max body zero", map[string]any{"max_body_bytes": float64(0)}},
+		{"max body wrong type", map[string]any{"max_body_bytes": "1k"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseAssertions(tt.m); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+// --- Factory with URL objects ---
+
+func TestFactory_URLObjects(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"urls": []any{
+			"http://a.example.com",
+			map[string]any{"url": "http://b.example.com/health", "expect_status": "2xx"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := chk.(*Check)
+	if len(c.targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(c.targets))
+	}
+	if c.targets[1].url != "http://b.example.com/health" || c.targets[1].dsName != "url1" {
+		t.Errorf("unexpected second target %+v", c.targets[1])
+	}
+	if len(c.targets[1].assert.status) != 1 {
+		t.Error("expected status assertion on second target")
+	}
+	if desc := c.Describe(); desc.Metrics[1].ResultKey != "http://b.example.com/health" {
+		t.Errorf("expected ResultKey to be URL, got %q", desc.Metrics[1].ResultKey)
+	}
+}
+
+func TestFactory_URLObjectErrors(t *testing.T) {
+	for _, urls := range []any{
+		[]any{map[string]any{}},
+		[]any{map[string]any{"url": ""}},
+		[]any{map[string]any{"url": "http://a.example.com", "expect_status": "nope"}},
+	} {
+		if _, err := Factory(map[string]any{"urls": urls}); err == nil {
+			t.Errorf("expected error for %v", urls)
+		}
+	}
+}
+
+// --- Run with assertions ---
+
+func runOne(t *testing.T, handler http.HandlerFunc, opts map[string]any) (*float64, error) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	obj := map[string]any{"url": srv.URL}
+	for k, v := range opts {
+		obj[k] = v
+	}
+	chk, err := Factory(map[string]any{"urls": []any{obj}})
+	if err != nil {
+		t.Fatalf("unexpected factory error: %v", err)
+	}
+	result := chk.Run(context.Background())
+	if result.Success != (result.Err == nil) {
+		t.Fatalf("Success=%v inconsistent with Err=%v", result.Success, result.Err)
+	}
+	return result.Metrics[srv.URL], result.Err
+}
+
+func TestRun_DefaultAcceptsServerError(t *testing.T) {
+	_, err := runOne(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}, nil)
+	if err != nil {
+		t.Errorf("expected any status to pass without assertions, got %v", err)
+	}
+}
+
+func TestRun_StatusAssertion(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	metric, err := runOne(t, handler, map[string]any{"expect_status": []any{"2xx", "3xx"}})
+	if err == nil || !strings.Contains(err.Error(), "unexpected status 503") {
+		t.Errorf("expected unexpected status error, got %v", err)
+	}
+	if metric != nil {
+		t.Error("expected nil metric on failed assertion")
+	}
+
+	metric, err = runOne(t, handler, map[string]any{"expect_status": "500-599"})
+	if err != nil {
+		t.Errorf("expected 503 to satisfy 500-599, got %v", err)
+	}
+	if metric == nil {
+		t.Error("expected metric on success")
+	}
+}
+
+func TestRun_BodyAssertions(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "up", "db": "ok"}`))
+	}
+
+	if _, err := runOne(t, handler, map[string]any{"expect_body": `"db": "ok"`}); err != nil {
+		t.Errorf("expected body substring to match, got %v", err)
+	}
+	if _, err := runOne(t, handler, map[string]any{"expect_body": "maintenance"}); err == nil {
+		t.Error("expected body substring mismatch")
+	}
+	if _, err := runOne(t, handler, map[string]any{"expect_body_regex": `"status":\s*"up"`}); err != nil {
+		t.Errorf("expected body regex to match, got %v", err)
+	}
+	if _, err := runOne(t, handler, map[string]any{"expect_body_regex": `"status":\s*"down"`}); err == nil {
+		t.Error("expected body regex mismatch")
+	}
+}
+
+func TestRun_MaxBodyBytes(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100) + "needle"))
+	}
+
+	_, err := runOne(t, handler, map[string]any{"expect_body": "needle", "max_body_bytes": float64(50)})
+	if err == nil || !strings.Contains(err.Error(), "read 50 bytes") {
+		t.Errorf("expected mismatch after truncated read, got %v", err)
+	}
+	if _, err := runOne(t, handler, map[string]any{"expect_body": "needle"}); err != nil {
+		t.Errorf("expected match within default limit, got %v", err)
+	}
+}
+
+func TestRun_HeaderAssertions(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("X-Backend", "app-3")
+	}
+
+	ok := map[string]any{"expect_headers": map[string]any{"content-type": "application/json", "X-Backend": ""}}
+	if _, err := runOne(t, handler, ok); err != nil {
+		t.Errorf("expected headers to match, got %v", err)
+	}
+
+	missing := map[string]any{"expect_headers": map[string]any{"X-Cache": ""}}
+	if _, err := runOne(t, handler, missing); err == nil || !strings.Contains(err.Error(), "missing required header") {
+		t.Errorf("expected missing header error, got %v", err)
+	}
+
+	wrong := map[string]any{"expect_headers": map[string]any{"Content-Type": "text/html"}}
+	if _, err := runOne(t, handler, wrong); err == nil {
+		t.Error("expected header value mismatch")
+	}
+}
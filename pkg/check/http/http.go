@@ -1,14 +1,27 @@
-// Package http implements an HTTP GET check that probes one or more URLs
+// Package http implements an HTTP check that probes one or more URLs
 // and reports per-URL response times. The check succeeds only when every
-// configured URL returns a response; redirects are not followed.
+// configured URL returns a response that satisfies its assertions (expected
+// status codes, body match, and required headers); with no assertions any
+// response counts. Redirects are not followed unless a URL enables them,
+// optionally with a hop limit and an expected final URL.
+//
+// Requests are plain GETs by default; each URL may set its own method,
+// headers, body and basic auth, with secret values resolved from
+// environment variables or files.
+//
+// In trace mode each request is additionally broken down into DNS, connect,
+// TLS and time-to-first-byte phases, recorded as separate metrics that are
+// graphed as a stack beneath the total response time.
 package http
 
 import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/kylerisse/wasgeht/pkg/check"
@@ -20,13 +33,28 @@ const (
 
 	// DefaultTimeout is the default HTTP request timeout.
 	DefaultTimeout = 10 * time.Second
+
+	// DefaultMaxBodyBytes is the default limit on how much of the response
+	// body is read when evaluating body assertions.
+	DefaultMaxBodyBytes = 1 << 20
 )
 
-// Check implements check.Check using HTTP GET requests to one or more URLs.
+// targetConfig holds the parsed configuration for a single URL.
+type targetConfig struct {
+	url       string         // full URL to request
+	resultKey string         // key in Result.Metrics (= url)
+	dsName    string         // RRD DS name (e.g. "url0")
+	req       requestConfig  // how the request is built
+	redirect  redirectPolicy // whether and how far redirects are followed
+	assert    assertions     // response checks applied after the request
+}
+
+// Check implements check.Check using HTTP requests to one or more URLs.
 type Check struct {
-	urls       []string
+	targets    []targetConfig
 	timeout    time.Duration
 	skipVerify bool
+	trace      bool
 	client     *http.Client
 	desc       check.Descriptor
 }
@@ -53,24 +81,52 @@ func WithSkipVerify(skip bool) Option {
 	}
 }
 
-// New creates an HTTP Check for the given URLs.
+// WithTrace enables per-phase request timing. Keep-alives are disabled in
+// trace mode so that every request includes DNS, connect and TLS phases.
+func WithTrace(trace bool) Option {
+	return func(c *Check) error {
+		c.trace = trace
+		return nil
+	}
+}
+
+// New creates an HTTP Check for the given URLs with no response assertions.
 func New(urls []string, opts ...Option) (*Check, error) {
-	if len(urls) == 0 {
+	targets := make([]targetConfig, len(urls))
+	for i, u := range urls {
+		targets[i] = newTargetConfig(i, u)
+	}
+	return newCheck(targets, opts...)
+}
+
+// newTargetConfig builds the default targetConfig for the URL at index i.
+func newTargetConfig(i int, u string) targetConfig {
+	return targetConfig{
+		url:       u,
+		resultKey: u,
+		dsName:    fmt.Sprintf("url%d", i),
+		assert:    assertions{maxBodyBytes: DefaultMaxBodyBytes},
+	}
+}
+
+// newCheck creates an HTTP Check for fully parsed targets.
+func newCheck(targets []targetConfig, opts ...Option) (*Check, error) {
+	if len(targets) == 0 {
 		return nil, fmt.Errorf("http: at least one URL is required")
 	}
 
-	for _, u := range urls {
-		parsed, err := url.Parse(u)
+	for _, t := range targets {
+		parsed, err := url.Parse(t.url)
 		if err != nil {
-			return nil, fmt.Errorf("http: invalid URL %q: %w", u, err)
+			return nil, fmt.Errorf("http: invalid URL %q: %w", t.url, err)
 		}
 		if parsed.Scheme != "http" && parsed.Scheme != "https" {
-			return nil, fmt.Errorf("http: URL %q must use http or https scheme", u)
+			return nil, fmt.Errorf("http: URL %q must use http or https scheme", t.url)
 		}
 	}
 
 	c := &Check{
-		urls:       urls,
+		targets:    targets,
 		timeout:    DefaultTimeout,
 		skipVerify: false,
 	}
@@ -84,19 +140,34 @@ func New(urls []string, opts ...Option) (*Check, error) {
 	c.client = &http.Client{
 		Timeout: c.timeout,
 		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: c.skipVerify},
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: c.skipVerify},
+			DisableKeepAlives: c.trace,
 		},
 	}
 
-	// Build descriptor: one metric per URL
-	metrics := make([]check.MetricDef, len(urls))
-	for i, url := range urls {
-		metrics[i] = check.MetricDef{
-			ResultKey: url,
-			DSName:    fmt.Sprintf("url%d", i),
-			Label:     url,
+	// Build descriptor: one metric per URL, followed in trace mode by
+	// that URL's phases as a stack group.
+	metrics := make([]check.MetricDef, 0, len(targets))
+	for _, t := range targets {
+		metrics = append(metrics, check.MetricDef{
+			ResultKey: t.resultKey,
+			DSName:    t.dsName,
+			Label:     t.url,
 			Unit:      "ms",
 			Scale:     1000,
+		})
+		if !c.trace {
+			continue
+		}
+		for _, p := range phases {
+			metrics = append(metrics, check.MetricDef{
+				ResultKey: phaseResultKey(t, p),
+				DSName:    phaseDSName(t, p),
+				Label:     fmt.Sprintf("%s %s", t.url, p.label),
+				Unit:      "ms",
+				Scale:     1000,
+				Stack:     t.dsName,
+			})
 		}
 	}
 	c.desc = check.Descriptor{
@@ -117,52 +188,119 @@ func (c *Check) Describe() check.Descriptor {
 	return c.desc
 }
 
-// Run executes HTTP GET requests to all configured URLs and returns a Result.
+// Run executes HTTP requests to all configured URLs concurrently and
+// returns a Result. Response time is measured until the response headers
+// arrive. A URL whose response fails any of its assertions is recorded as
+// a nil metric and the failing assertion is reported in Result.Err. In
+// trace mode each phase is also stored in microseconds; phases are nil
+// whenever the URL fails. URLs that follow redirects also report the
+// number of hops followed and the final status code whenever a final
+// response was received; these are not stored in the RRD.
 func (c *Check) Run(ctx context.Context) check.Result {
-	metrics := make(map[string]*int64, len(c.urls))
+	results := make([]targetResult, len(c.targets))
+	var wg sync.WaitGroup
+	for i, t := range c.targets {
+		wg.Go(func() {
+			results[i] = c.runTarget(ctx, t)
+		})
+	}
+	wg.Wait()
+
+	metrics := make(map[string]*float64, len(c.desc.Metrics))
 	var lastErr error
 	succeeded := 0
 
-	for _, url := range c.urls {
-		start := time.Now()
-
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-		if err != nil {
-			lastErr = fmt.Errorf("failed to create request for %s: %w", url, err)
-			metrics[url] = nil
-			continue
-		}
-
-		resp, err := c.client.Do(req)
-		elapsed := time.Since(start)
-
-		if err != nil {
-			lastErr = fmt.Errorf("request to %s failed: %w", url, err)
-			metrics[url] = nil
+	for _, r := range results {
+		maps.Copy(metrics, r.metrics)
+		if r.err != nil {
+			lastErr = r.err
 			continue
 		}
-		resp.Body.Close()
-
-		v := elapsed.Microseconds()
-		metrics[url] = &v
 		succeeded++
 	}
 
 	return check.Result{
 		Timestamp: time.Now(),
-		Success:   succeeded == len(c.urls),
+		Success:   succeeded == len(c.targets),
 		Err:       lastErr,
 		Metrics:   metrics,
 	}
 }
 
+// targetResult holds the metrics recorded for one URL and the reason it
+// failed, if it did.
+type targetResult struct {
+	metrics map[string]*float64
+	err     error
+}
+
+// runTarget requests a single URL and checks the response against its
+// assertions.
+func (c *Check) runTarget(ctx context.Context, t targetConfig) targetResult {
+	metrics := make(map[string]*float64)
+	if c.trace {
+		for _, p := range phases {
+			metrics[phaseResultKey(t, p)] = nil
+		}
+	}
+	metrics[t.resultKey] = nil
+
+	reqCtx := ctx
+	tm := &timings{}
+	if c.trace {
+		reqCtx = tm.withTrace(ctx)
+	}
+
+	start := time.Now()
+
+	req, err := t.req.build(reqCtx, t.url)
+	if err != nil {
+		return targetResult{metrics, fmt.Errorf("failed to create request for %s: %w", t.url, err)}
+	}
+
+	hops := 0
+	resp, err := t.redirect.client(c.client, &hops).Do(req)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		return targetResult{metrics, fmt.Errorf("request to %s failed: %w", t.url, err)}
+	}
+
+	if t.redirect.enabled() {
+		h, status := float64(hops), float64(resp.StatusCode)
+		metrics[redirectsResultKey(t)] = &h
+		metrics[statusResultKey(t)] = &status
+	}
+
+	err = t.redirect.checkFinal(resp, hops)
+	if err == nil {
+		err = t.assert.check(resp)
+	}
+	resp.Body.Close()
+	if err != nil {
+		return targetResult{metrics, fmt.Errorf("%s: %w", t.url, err)}
+	}
+
+	v := float64(elapsed.Microseconds())
+	metrics[t.resultKey] = &v
+	if c.trace {
+		durations := tm.durations()
+		for _, p := range phases {
+			pv := float64(durations[p.key].Microseconds())
+			metrics[phaseResultKey(t, p)] = &pv
+		}
+	}
+	return targetResult{metrics: metrics}
+}
+
 // Factory creates an HTTP Check from a config map.
-// Required keys: "urls" (list of strings).
+// Required keys: "urls" (list of URL strings or objects, see extractURLs).
 // Optional keys:
 //   - "timeout" (string) — duration string (e.g. "10s")
 //   - "skip_verify" (bool) — skip TLS cert verification (default: false)
+//   - "trace" (bool) — record DNS/connect/TLS/first-byte phases (default: false)
 func Factory(config map[string]any) (check.Check, error) {
-	urls, err := extractURLs(config)
+	targets, err := extractURLs(config)
 	if err != nil {
 		return nil, err
 	}
@@ -189,11 +327,22 @@ func Factory(config map[string]any) (check.Check, error) {
 		opts = append(opts, WithSkipVerify(b))
 	}
 
-	return New(urls, opts...)
+	if v, ok := config["trace"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("http: 'trace' must be a bool, got %T", v)
+		}
+		opts = append(opts, WithTrace(b))
+	}
+
+	return newCheck(targets, opts...)
 }
 
-// extractURLs pulls the URL list from the config map.
-func extractURLs(config map[string]any) ([]string, error) {
+// extractURLs pulls the URL list from the config map. Each item is either
+// a URL string or an object with a required "url" key and optional
+// per-URL request keys (see parseRequest), redirect keys (see
+// parseRedirects) and assertion keys (see parseAssertions).
+func extractURLs(config map[string]any) ([]targetConfig, error) {
 	raw, ok := config["urls"]
 	if !ok {
 		return nil, fmt.Errorf("http: config missing required key 'urls'")
@@ -204,20 +353,47 @@ func extractURLs(config map[string]any) ([]string, error) {
 		if len(v) == 0 {
 			return nil, fmt.Errorf("http: 'urls' must not be empty")
 		}
-		return v, nil
+		targets := make([]targetConfig, len(v))
+		for i, u := range v {
+			targets[i] = newTargetConfig(i, u)
+		}
+		return targets, nil
 	case []any:
-		urls := make([]string, 0, len(v))
-		for _, item := range v {
-			s, ok := item.(string)
-			if !ok {
-				return nil, fmt.Errorf("http: 'urls' items must be strings, got %T", item)
+		targets := make([]targetConfig, 0, len(v))
+		for i, item := range v {
+			switch it := item.(type) {
+			case string:
+				targets = append(targets, newTargetConfig(i, it))
+			case map[string]any:
+				u, ok := it["url"].(string)
+				if !ok || u == "" {
+					return nil, fmt.Errorf("http: URL object at index %d missing required 'url'", i)
+				}
+				t := newTargetConfig(i, u)
+				rc, err := parseRequest(it)
+				if err != nil {
+					return nil, fmt.Errorf("http: %s: %w", u, err)
+				}
+				t.req = rc
+				rp, err := parseRedirects(it)
+				if err != nil {
+					return nil, fmt.Errorf("http: %s: %w", u, err)
+				}
+				t.redirect = rp
+				a, err := parseAssertions(it)
+				if err != nil {
+					return nil, fmt.Errorf("http: %s: %w", u, err)
+				}
+				t.assert = a
+				targets = append(targets, t)
+			default:
+				return nil, fmt.Errorf("http: 'urls' items must be strings or objects, got %T", item)
 			}
-			urls = append(urls, s)
 		}
-		if len(urls) == 0 {
+		if len(targets) == 0 {
 			return nil, fmt.Errorf("http: 'urls' must not be empty")
 		}
-		return urls, nil
+		return targets, nil
 	default:
 		return nil, fmt.Errorf("http: 'urls' must be a list, got %T", raw)
 	}
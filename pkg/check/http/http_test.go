@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -250,8 +251,8 @@ func TestFactory_StringSliceURLs(t *testing.T) {
 	}
 
 	httpChk := chk.(*Check)
-	if len(httpChk.urls) != 1 {
-		t.Errorf("expected 1 URL, got %d", len(httpChk.urls))
+	if len(httpChk.targets) != 1 {
+		t.Errorf("expected 1 URL, got %d", len(httpChk.targets))
 	}
 }
 
@@ -267,7 +268,7 @@ func TestFactory_IgnoresTargetKey(t *testing.T) {
 	}
 
 	httpChk := chk.(*Check)
-	if len(httpChk.urls) != 1 || httpChk.urls[0] != "http://localhost:8080" {
+	if len(httpChk.targets) != 1 || httpChk.targets[0].url != "http://localhost:8080" {
 		t.Errorf("expected URL from urls config, not target")
 	}
 }
@@ -357,6 +358,47 @@ func TestRun_MultipleURLs_AllSuccess(t *testing.T) {
 	}
 }
 
+func TestRun_MultipleURLs_Concurrent(t *testing.T) {
+	const n = 3
+
+	// The handler holds each request until all of them have arrived,
+	// which only happens when the URLs are requested at the same time.
+	var arrived sync.WaitGroup
+	arrived.Add(n)
+	all := make(chan struct{})
+	go func() {
+		arrived.Wait()
+		close(all)
+	}()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived.Done()
+		select {
+		case <-all:
+			w.WriteHeader(http.StatusOK)
+		case <-time.After(2 * time.Second):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/%d", srv.URL, i)
+	}
+	c, err := New(urls)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Errorf("expected URLs to be requested concurrently, got failure: %v", result.Err)
+	}
+	if len(result.Metrics) != n {
+		t.Errorf("expected %d metrics, got %d", n, len(result.Metrics))
+	}
+}
+
 func TestDescribe_SingleURL_MetricFields(t *testing.T) {
 	url := "http://example.com"
 	c, err := New([]string{url})
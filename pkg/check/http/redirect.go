@@ -0,0 +1,103 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// DefaultMaxRedirects is the hop limit used when "follow_redirects" is true.
+const DefaultMaxRedirects = 10
+
+// redirectPolicy controls whether and how far redirects are followed for
+// a URL, and optionally which URL the chain must end at.
+type redirectPolicy struct {
+	maxHops  int    // 0 means redirects are never followed
+	finalURL string // expected URL of the final response; "" for no check
+}
+
+// enabled reports whether redirect handling was configured for the URL,
+// in which case hop count and final status are reported as metrics.
+func (p redirectPolicy) enabled() bool {
+	return p.maxHops > 0
+}
+
+// client returns a copy of base that applies the policy, counting each
+// followed hop into hops.
+func (p redirectPolicy) client(base *http.Client, hops *int) *http.Client {
+	cl := *base
+	cl.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if p.maxHops == 0 {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > p.maxHops {
+			return fmt.Errorf("stopped after %d redirects", p.maxHops)
+		}
+		*hops = len(via)
+		return nil
+	}
+	return &cl
+}
+
+// checkFinal verifies the URL of the final response against finalURL.
+func (p redirectPolicy) checkFinal(resp *http.Response, hops int) error {
+	if p.finalURL == "" {
+		return nil
+	}
+	if got := resp.Request.URL.String(); got != p.finalURL {
+		return fmt.Errorf("redirect chain ended at %s after %d hops with status %d, expected %s", got, hops, resp.StatusCode, p.finalURL)
+	}
+	return nil
+}
+
+// redirectsResultKey returns the Result.Metrics key for a URL's hop count.
+func redirectsResultKey(t targetConfig) string {
+	return t.resultKey + " redirects"
+}
+
+// statusResultKey returns the Result.Metrics key for a URL's final status code.
+func statusResultKey(t targetConfig) string {
+	return t.resultKey + " status"
+}
+
+// parseRedirects reads the optional per-URL redirect keys from a URL object:
+//   - "follow_redirects" (bool or number) — false (default) never follows,
+//     true follows up to DefaultMaxRedirects hops, a number sets the hop limit
+//   - "expect_final_url" (string) — URL the redirect chain must end at;
+//     requires redirects to be followed
+func parseRedirects(m map[string]any) (redirectPolicy, error) {
+	var p redirectPolicy
+
+	if v, ok := m["follow_redirects"]; ok {
+		switch f := v.(type) {
+		case bool:
+			if f {
+				p.maxHops = DefaultMaxRedirects
+			}
+		case float64:
+			n := int(f)
+			if float64(n) != f || n < 0 {
+				return p, fmt.Errorf("'follow_redirects' must be a non-negative integer, got %v", f)
+			}
+			p.maxHops = n
+		default:
+			return p, fmt.Errorf("'follow_redirects' must be a bool or number, got %T", v)
+		}
+	}
+
+	if v, ok := m["expect_final_url"]; ok {
+		s, ok := v.(string)
+		if !ok || s == "" {
+			return p, fmt.Errorf("'expect_final_url' must be a non-empty string")
+		}
+		if _, err := url.Parse(s); err != nil {
+			return p, fmt.Errorf("invalid 'expect_final_url': %w", err)
+		}
+		if p.maxHops == 0 {
+			return p, fmt.Errorf("'expect_final_url' requires 'follow_redirects'")
+		}
+		p.finalURL = s
+	}
+
+	return p, nil
+}
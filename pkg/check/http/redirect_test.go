@@ -0,0 +1,171 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+)
+
+// newRedirectChain starts a server where /hop/N redirects to /hop/N-1 and
+// /hop/0 serves the final page.
+func newRedirectChain(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if n == 0 {
+			fmt.Fprint(w, "landed")
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func runRedirect(t *testing.T, item map[string]any) check.Result {
+	t.Helper()
+	chk, err := Factory(map[string]any{"urls": []any{item}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return chk.Run(context.Background())
+}
+
+// --- parseRedirects tests ---
+
+func TestParseRedirects(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       map[string]any
+		hops    int
+		wantErr bool
+	}{
+		{"absent", map[string]any{}, 0, false},
+		{"false", map[string]any{"follow_redirects": false}, 0, false},
+		{"true", map[string]any{"follow_redirects": true}, DefaultMaxRedirects, false},
+		{"number", map[string]any{"follow_redirects": float64(3)}, 3, false},
+		{"negative", map[string]any{"follow_redirects": float64(-1)}, 0, true},
+		{"fraction", map[string]any{"follow_redirects": 1.5}, 0, true},
+		{"string", map[string]any{"follow_redirects": "yes"}, 0, true},
+		{"final url without follow", map[string]any{"expect_final_url": "http://a.example.com/"}, 0, true},
+		{"final url empty", map[string]any{"follow_redirects": true, "expect_final_url": ""}, 0, true},
+		{"final url", map[string]any{"follow_redirects": true, "expect_final_url": "http://a.example.com/"}, DefaultMaxRedirects, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parseRedirects(tt.m)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.maxHops != tt.hops {
+				t.Errorf("expected %d hops, got %d", tt.hops, p.maxHops)
+			}
+		})
+	}
+}
+
+// --- Run with redirects ---
+
+func TestRun_Redirect_NotFollowedByDefault(t *testing.T) {
+	srv := newRedirectChain(t)
+	u := srv.URL + "/hop/2"
+
+	r := runRedirect(t, map[string]any{"url": u, "expect_status": float64(200)})
+	if r.Success {
+		t.Fatal("expected failure: the 302 must be measured, not followed")
+	}
+	if !strings.Contains(r.Err.Error(), "302") {
+		t.Errorf("expected error to mention the 302, got %v", r.Err)
+	}
+	if _, ok := r.Metrics[u+" redirects"]; ok {
+		t.Error("expected no redirect metrics when redirects are not followed")
+	}
+}
+
+func TestRun_Redirect_FollowWithinLimit(t *testing.T) {
+	srv := newRedirectChain(t)
+	u := srv.URL + "/hop/3"
+
+	r := runRedirect(t, map[string]any{
+		"url":              u,
+		"follow_redirects": float64(3),
+		"expect_status":    float64(200),
+		"expect_body":      "landed",
+	})
+	if !r.Success {
+		t.Fatalf("expected success, got %v", r.Err)
+	}
+	if v := r.Metrics[u+" redirects"]; v == nil || *v != 3 {
+		t.Errorf("expected 3 redirects, got %v", v)
+	}
+	if v := r.Metrics[u+" status"]; v == nil || *v != 200 {
+		t.Errorf("expected final status 200, got %v", v)
+	}
+	if r.Metrics[u] == nil {
+		t.Error("expected response time metric")
+	}
+}
+
+func TestRun_Redirect_TooManyHops(t *testing.T) {
+	srv := newRedirectChain(t)
+	u := srv.URL + "/hop/4"
+
+	r := runRedirect(t, map[string]any{"url": u, "follow_redirects": float64(2)})
+	if r.Success {
+		t.Fatal("expected failure when the hop limit is exceeded")
+	}
+	if !strings.Contains(r.Err.Error(), "stopped after 2 redirects") {
+		t.Errorf("expected hop limit error, got %v", r.Err)
+	}
+	if v, ok := r.Metrics[u]; !ok || v != nil {
+		t.Errorf("expected nil metric, got %v (present=%v)", v, ok)
+	}
+}
+
+func TestRun_Redirect_FinalURL(t *testing.T) {
+	srv := newRedirectChain(t)
+	u := srv.URL + "/hop/2"
+
+	r := runRedirect(t, map[string]any{
+		"url":              u,
+		"follow_redirects": true,
+		"expect_final_url": srv.URL + "/hop/0",
+	})
+	if !r.Success {
+		t.Fatalf("expected success, got %v", r.Err)
+	}
+
+	r = runRedirect(t, map[string]any{
+		"url":              u,
+		"follow_redirects": true,
+		"expect_final_url": srv.URL + "/sso/login",
+	})
+	if r.Success {
+		t.Fatal("expected failure for unexpected final URL")
+	}
+	if !strings.Contains(r.Err.Error(), "/hop/0 after 2 hops with status 200") {
+		t.Errorf("expected error to report where the chain ended, got %v", r.Err)
+	}
+	if v := r.Metrics[u+" redirects"]; v == nil || *v != 2 {
+		t.Errorf("expected hop count on failure, got %v", v)
+	}
+	if v, ok := r.Metrics[u]; !ok || v != nil {
+		t.Errorf("expected nil response time on failure, got %v (present=%v)", v, ok)
+	}
+}
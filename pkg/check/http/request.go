@@ -0,0 +1,208 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// secret is a configuration value that is either given inline or resolved
+// from an environment variable or file each time a request is built, so
+// that tokens and passwords need not be stored in the hosts file.
+type secret struct {
+	value string // inline value
+	env   string // environment variable name
+	file  string // path to a file holding the value
+}
+
+// resolve returns the secret's current value. File contents have trailing
+// newlines trimmed.
+func (s secret) resolve() (string, error) {
+	switch {
+	case s.env != "":
+		v, ok := os.LookupEnv(s.env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", s.env)
+		}
+		return v, nil
+	case s.file != "":
+		b, err := os.ReadFile(s.file)
+		if err != nil {
+			return "", fmt.Errorf("could not read secret file: %w", err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	default:
+		return s.value, nil
+	}
+}
+
+// parseSecret parses a config value that is either a plain string or an
+// object with exactly one of "env" or "file".
+func parseSecret(v any) (secret, error) {
+	switch s := v.(type) {
+	case string:
+		return secret{value: s}, nil
+	case map[string]any:
+		if len(s) != 1 {
+			return secret{}, fmt.Errorf("secret reference must have exactly one of 'env' or 'file'")
+		}
+		if env, ok := s["env"]; ok {
+			name, ok := env.(string)
+			if !ok || name == "" {
+				return secret{}, fmt.Errorf("'env' must be a non-empty string")
+			}
+			return secret{env: name}, nil
+		}
+		if file, ok := s["file"]; ok {
+			path, ok := file.(string)
+			if !ok || path == "" {
+				return secret{}, fmt.Errorf("'file' must be a non-empty string")
+			}
+			return secret{file: path}, nil
+		}
+		return secret{}, fmt.Errorf("secret reference must have exactly one of 'env' or 'file'")
+	default:
+		return secret{}, fmt.Errorf("must be a string or an object with 'env' or 'file', got %T", v)
+	}
+}
+
+// header is a single request header with a possibly secret value.
+type header struct {
+	name  string
+	value secret
+}
+
+// requestConfig describes how the request for a URL is built.
+type requestConfig struct {
+	method    string   // HTTP method, default GET
+	headers   []header // request headers; "Host" overrides the Host header
+	body      *secret  // request body, nil for none
+	basicUser *secret  // basic auth username, nil for none
+	basicPass *secret  // basic auth password
+}
+
+// build creates the HTTP request for url, resolving any secrets.
+func (rc requestConfig) build(ctx context.Context, url string) (*http.Request, error) {
+	var body io.Reader
+	if rc.body != nil {
+		b, err := rc.body.resolve()
+		if err != nil {
+			return nil, fmt.Errorf("body: %w", err)
+		}
+		body = strings.NewReader(b)
+	}
+
+	method := rc.method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, h := range rc.headers {
+		v, err := h.value.resolve()
+		if err != nil {
+			return nil, fmt.Errorf("header %q: %w", h.name, err)
+		}
+		if strings.EqualFold(h.name, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(h.name, v)
+	}
+
+	if rc.basicUser != nil {
+		user, err := rc.basicUser.resolve()
+		if err != nil {
+			return nil, fmt.Errorf("basic auth username: %w", err)
+		}
+		pass, err := rc.basicPass.resolve()
+		if err != nil {
+			return nil, fmt.Errorf("basic auth password: %w", err)
+		}
+		req.SetBasicAuth(user, pass)
+	}
+
+	return req, nil
+}
+
+// parseRequest reads the optional per-URL request keys from a URL object:
+//   - "method" (string) — HTTP method, default "GET"
+//   - "headers" (object) — header name to value or secret reference
+//   - "body" (string or secret reference) — request body
+//   - "basic_auth" (object) — "username" and "password", each a value or secret reference
+//
+// A secret reference is an object with either "env" (environment variable
+// name) or "file" (path), resolved on every request.
+func parseRequest(m map[string]any) (requestConfig, error) {
+	var rc requestConfig
+
+	if v, ok := m["method"]; ok {
+		s, ok := v.(string)
+		if !ok || s == "" {
+			return rc, fmt.Errorf("'method' must be a non-empty string")
+		}
+		if strings.ContainsAny(s, " \t\r\n") {
+			return rc, fmt.Errorf("invalid method %q", s)
+		}
+		rc.method = strings.ToUpper(s)
+	}
+
+	if v, ok := m["headers"]; ok {
+		hm, ok := v.(map[string]any)
+		if !ok {
+			return rc, fmt.Errorf("'headers' must be an object, got %T", v)
+		}
+		for name, hv := range hm {
+			if name == "" {
+				return rc, fmt.Errorf("'headers' names must not be empty")
+			}
+			s, err := parseSecret(hv)
+			if err != nil {
+				return rc, fmt.Errorf("header %q: %w", name, err)
+			}
+			rc.headers = append(rc.headers, header{name: name, value: s})
+		}
+	}
+
+	if v, ok := m["body"]; ok {
+		s, err := parseSecret(v)
+		if err != nil {
+			return rc, fmt.Errorf("'body': %w", err)
+		}
+		rc.body = &s
+	}
+
+	if v, ok := m["basic_auth"]; ok {
+		am, ok := v.(map[string]any)
+		if !ok {
+			return rc, fmt.Errorf("'basic_auth' must be an object, got %T", v)
+		}
+		userRaw, ok := am["username"]
+		if !ok {
+			return rc, fmt.Errorf("'basic_auth' missing required 'username'")
+		}
+		user, err := parseSecret(userRaw)
+		if err != nil {
+			return rc, fmt.Errorf("'basic_auth' username: %w", err)
+		}
+		passRaw, ok := am["password"]
+		if !ok {
+			return rc, fmt.Errorf("'basic_auth' missing required 'password'")
+		}
+		pass, err := parseSecret(passRaw)
+		if err != nil {
+			return rc, fmt.Errorf("'basic_auth' password: %w", err)
+		}
+		rc.basicUser = &user
+		rc.basicPass = &pass
+	}
+
+	return rc, nil
+}
@@ -0,0 +1,223 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// --- parseSecret / resolve tests ---
+
+func TestParseSecret_Inline(t *testing.T) {
+	s, err := parseSecret("hunter2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, err := s.resolve(); err != nil || v != "hunter2" {
+		t.Errorf("expected inline value, got %q (%v)", v, err)
+	}
+}
+
+func TestParseSecret_Env(t *testing.T) {
+	t.Setenv("WASGEHT_TEST_TOKEN", "from-env")
+	s, err := parseSecret(map[string]any{"env": "WASGEHT_TEST_TOKEN"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, err := s.resolve(); err != nil || v != "from-env" {
+		t.Errorf("expected env value, got %q (%v)", v, err)
+	}
+}
+
+func TestParseSecret_EnvUnset(t *testing.T) {
+	s, err := parseSecret(map[string]any{"env": "WASGEHT_TEST_DEFINITELY_UNSET"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.resolve(); err == nil {
+		t.Error("expected error resolving unset environment variable")
+	}
+}
+
+func TestParseSecret_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	s, err := parseSecret(map[string]any{"file": path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, err := s.resolve(); err != nil || v != "from-file" {
+		t.Errorf("expected trimmed file value, got %q (%v)", v, err)
+	}
+
+	// Rotated secrets are picked up on the next resolve.
+	if err := os.WriteFile(path, []byte("rotated"), 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if v, _ := s.resolve(); v != "rotated" {
+		t.Errorf("expected rotated value, got %q", v)
+	}
+}
+
+func TestParseSecret_Errors(t *testing.T) {
+	for _, v := range []any{
+		42,
+		map[string]any{},
+		map[string]any{"env": ""},
+		map[string]any{"file": 1},
+		map[string]any{"env": "A", "file": "/b"},
+		map[string]any{"vault": "x"},
+	} {
+		if _, err := parseSecret(v); err == nil {
+			t.Errorf("parseSecret(%v): expected error", v)
+		}
+	}
+}
+
+// --- parseRequest tests ---
+
+func TestParseRequest_Defaults(t *testing.T) {
+	rc, err := parseRequest(map[string]any{"url": "http://a.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req, err := rc.build(context.Background(), "http://a.example.com")
+	if err != nil {
+		t.Fatalf("unexpected build error: %v", err)
+	}
+	if req.Method != http.MethodGet {
+		t.Errorf("expected GET, got %s", req.Method)
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		t.Error("expected no body")
+	}
+}
+
+func TestParseRequest_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		m    map[string]any
+	}{
+		{"empty method", map[string]any{"method": ""}},
+		{"method with space", map[string]any{"method": "GET /"}},
+		{"headers wrong type", map[string]any{"headers": "X-A: b"}},
+		{"header bad secret", map[string]any{"headers": map[string]any{"X-A": 1}}},
+		{"body bad secret", map[string]any{"body": map[string]any{"env": ""}}},
+		{"basic_auth wrong type", map[string]any{"basic_auth": "user:pass"}},
+		{"basic_auth missing username", map[string]any{"basic_auth": map[string]any{"password": "p"}}},
+		{"basic_auth missing password", map[string]any{"basic_auth": map[string]any{"username": "u"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseRequest(tt.m); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+// --- Run with request options ---
+
+func TestRun_RequestOptions(t *testing.T) {
+	type seen struct {
+		method, host, token, contentType, body string
+		user, pass                             string
+		basicOK                                bool
+	}
+	got := make(chan seen, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		u, p, ok := r.BasicAuth()
+		got <- seen{
+			method:      r.Method,
+			host:        r.Host,
+			token:       r.Header.Get("X-Api-Token"),
+			contentType: r.Header.Get("Content-Type"),
+			body:        string(b),
+			user:        u,
+			pass:        p,
+			basicOK:     ok,
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("WASGEHT_TEST_PASSWORD", "s3cret")
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("tok-123\n"), 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	chk, err := Factory(map[string]any{
+		"urls": []any{map[string]any{
+			"url":    srv.URL,
+			"method": "post",
+			"headers": map[string]any{
+				"Host":         "portal.example.com",
+				"Content-Type": "application/json",
+				"X-Api-Token":  map[string]any{"file": tokenFile},
+			},
+			"body": `{"ping":true}`,
+			"basic_auth": map[string]any{
+				"username": "monitor",
+				"password": map[string]any{"env": "WASGEHT_TEST_PASSWORD"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := chk.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+
+	s := <-got
+	if s.method != http.MethodPost {
+		t.Errorf("expected POST, got %s", s.method)
+	}
+	if s.host != "portal.example.com" {
+		t.Errorf("expected Host override, got %q", s.host)
+	}
+	if s.token != "tok-123" {
+		t.Errorf("expected token from file, got %q", s.token)
+	}
+	if s.contentType != "application/json" {
+		t.Errorf("expected content type, got %q", s.contentType)
+	}
+	if s.body != `{"ping":true}` {
+		t.Errorf("expected body, got %q", s.body)
+	}
+	if !s.basicOK || s.user != "monitor" || s.pass != "s3cret" {
+		t.Errorf("expected basic auth monitor/s3cret, got %q/%q (ok=%v)", s.user, s.pass, s.basicOK)
+	}
+}
+
+func TestRun_UnresolvableSecretFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	chk, err := Factory(map[string]any{
+		"urls": []any{map[string]any{
+			"url":     srv.URL,
+			"headers": map[string]any{"Authorization": map[string]any{"env": "WASGEHT_TEST_DEFINITELY_UNSET"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := chk.Run(context.Background())
+	if result.Success {
+		t.Error("expected failure when a secret cannot be resolved")
+	}
+	if v, ok := result.Metrics[srv.URL]; !ok || v != nil {
+		t.Errorf("expected nil metric, got %v (present=%v)", v, ok)
+	}
+}
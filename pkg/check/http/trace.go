@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// phase is a single timed stage of an HTTP request recorded in trace mode.
+type phase struct {
+	key   string // suffix for the result key and DS name
+	label string // human-readable label for graphs
+}
+
+// phases lists the traced request stages in the order they occur, which is
+// also the order they are stacked on the graph. Together they add up to the
+// time until the first response byte.
+var phases = []phase{
+	{key: "dns", label: "dns"},
+	{key: "connect", label: "connect"},
+	{key: "tls", label: "tls"},
+	{key: "ttfb", label: "first byte"},
+}
+
+// phaseResultKey returns the Result.Metrics key for a traced phase of a URL.
+func phaseResultKey(t targetConfig, p phase) string {
+	return fmt.Sprintf("%s %s", t.resultKey, p.key)
+}
+
+// phaseDSName returns the RRD DS name for a traced phase of a URL
+// (e.g. "url0_connect").
+func phaseDSName(t targetConfig, p phase) string {
+	return fmt.Sprintf("%s_%s", t.dsName, p.key)
+}
+
+// timings collects httptrace callbacks for a single request. Callbacks may
+// fire on transport goroutines, so access is guarded by a mutex.
+type timings struct {
+	mu        sync.Mutex
+	dnsStart  time.Time
+	dnsDone   time.Time
+	connStart time.Time
+	connDone  time.Time
+	tlsStart  time.Time
+	tlsDone   time.Time
+	gotConn   time.Time
+	firstByte time.Time
+}
+
+// withTrace returns a context that records request phase timings into tm.
+func (tm *timings) withTrace(ctx context.Context) context.Context {
+	set := func(field *time.Time) {
+		tm.mu.Lock()
+		defer tm.mu.Unlock()
+		if field.IsZero() {
+			*field = time.Now()
+		}
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:     func(httptrace.DNSStartInfo) { set(&tm.dnsStart) },
+		DNSDone:      func(httptrace.DNSDoneInfo) { set(&tm.dnsDone) },
+		ConnectStart: func(string, string) { set(&tm.connStart) },
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				set(&tm.connDone)
+			}
+		},
+		TLSHandshakeStart:    func() { set(&tm.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { set(&tm.tlsDone) },
+		GotConn:              func(httptrace.GotConnInfo) { set(&tm.gotConn) },
+		GotFirstResponseByte: func() { set(&tm.firstByte) },
+	})
+}
+
+// durations returns the duration of each phase keyed by phase key.
+// Phases that did not occur (e.g. dns for an IP literal, tls for plain
+// http) are reported as zero.
+func (tm *timings) durations() map[string]time.Duration {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	between := func(start, end time.Time) time.Duration {
+		if start.IsZero() || end.IsZero() || end.Before(start) {
+			return 0
+		}
+		return end.Sub(start)
+	}
+
+	return map[string]time.Duration{
+		"dns":     between(tm.dnsStart, tm.dnsDone),
+		"connect": between(tm.connStart, tm.connDone),
+		"tls":     between(tm.tlsStart, tm.tlsDone),
+		"ttfb":    between(tm.gotConn, tm.firstByte),
+	}
+}
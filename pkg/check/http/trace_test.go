@@ -0,0 +1,173 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFactory_Trace(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"urls":  []any{"http://localhost"},
+		"trace": true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !chk.(*Check).trace {
+		t.Error("expected trace enabled")
+	}
+
+	if _, err := Factory(map[string]any{"urls": []any{"http://localhost"}, "trace": "yes"}); err == nil {
+		t.Error("expected error for non-bool trace")
+	}
+}
+
+func TestDescribe_TraceDisabled(t *testing.T) {
+	c, err := New([]string{"http://a.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	desc := c.Describe()
+	if len(desc.Metrics) != 1 {
+		t.Fatalf("expected 1 metric without trace, got %d", len(desc.Metrics))
+	}
+	if desc.Metrics[0].Stack != "" {
+		t.Errorf("expected total metric to be unstacked, got %q", desc.Metrics[0].Stack)
+	}
+}
+
+func TestDescribe_TraceEnabled(t *testing.T) {
+	c, err := New([]string{"http://a.com", "http://b.com"}, WithTrace(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	desc := c.Describe()
+	want := []struct {
+		ds, key, stack string
+	}{
+		{"url0", "http://a.com", ""},
+		{"url0_dns", "http://a.com dns", "url0"},
+		{"url0_connect", "http://a.com connect", "url0"},
+		{"url0_tls", "http://a.com tls", "url0"},
+		{"url0_ttfb", "http://a.com ttfb", "url0"},
+		{"url1", "http://b.com", ""},
+		{"url1_dns", "http://b.com dns", "url1"},
+		{"url1_connect", "http://b.com connect", "url1"},
+		{"url1_tls", "http://b.com tls", "url1"},
+		{"url1_ttfb", "http://b.com ttfb", "url1"},
+	}
+	if len(desc.Metrics) != len(want) {
+		t.Fatalf("expected %d metrics, got %d", len(want), len(desc.Metrics))
+	}
+	for i, w := range want {
+		m := desc.Metrics[i]
+		if m.DSName != w.ds || m.ResultKey != w.key || m.Stack != w.stack {
+			t.Errorf("metric %d: got {%s %q %q}, want {%s %q %q}", i, m.DSName, m.ResultKey, m.Stack, w.ds, w.key, w.stack)
+		}
+		if len(m.DSName) > 19 {
+			t.Errorf("metric %d: DS name %q exceeds rrdtool's 19 character limit", i, m.DSName)
+		}
+		if m.Unit != "ms" || m.Scale != 1000 {
+			t.Errorf("metric %d: expected ms/1000, got %s/%d", i, m.Unit, m.Scale)
+		}
+	}
+}
+
+func TestRun_Trace_HTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	// Use localhost so the request performs a name lookup.
+	u := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	c, err := New([]string{u}, WithTrace(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if len(result.Metrics) != 5 {
+		t.Errorf("expected 5 metrics, got %d", len(result.Metrics))
+	}
+
+	total := result.Metrics[u]
+	ttfb := result.Metrics[u+" ttfb"]
+	tlsPhase := result.Metrics[u+" tls"]
+	if total == nil || ttfb == nil || tlsPhase == nil {
+		t.Fatalf("expected total, ttfb and tls metrics, got %v", result.Metrics)
+	}
+	if *ttfb < 5000 {
+		t.Errorf("expected ttfb to include the 5ms handler delay, got %vus", *ttfb)
+	}
+	if *tlsPhase != 0 {
+		t.Errorf("expected zero tls phase for plain http, got %vus", *tlsPhase)
+	}
+
+	var sum float64
+	for _, p := range phases {
+		v := result.Metrics[u+" "+p.key]
+		if v == nil {
+			t.Fatalf("expected %s phase metric", p.key)
+		}
+		sum += *v
+	}
+	if sum > *total {
+		t.Errorf("expected phases (%vus) not to exceed total (%vus)", sum, *total)
+	}
+}
+
+func TestRun_Trace_TLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	c, err := New([]string{srv.URL}, WithTrace(true), WithSkipVerify(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Run twice: with keep-alives disabled the second run must also
+	// perform a fresh handshake.
+	for i := 0; i < 2; i++ {
+		result := c.Run(context.Background())
+		if !result.Success {
+			t.Fatalf("run %d: expected success, got %v", i, result.Err)
+		}
+		if v := result.Metrics[srv.URL+" tls"]; v == nil || *v <= 0 {
+			t.Errorf("run %d: expected positive tls phase, got %v", i, v)
+		}
+		if v := result.Metrics[srv.URL+" connect"]; v == nil || *v <= 0 {
+			t.Errorf("run %d: expected positive connect phase, got %v", i, v)
+		}
+	}
+}
+
+func TestRun_Trace_FailureNilsPhases(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	u := srv.URL
+	srv.Close()
+
+	c, err := New([]string{u}, WithTrace(true), WithTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Fatal("expected failure for closed server")
+	}
+	for _, p := range phases {
+		v, ok := result.Metrics[u+" "+p.key]
+		if !ok || v != nil {
+			t.Errorf("expected nil %s phase on failure, got %v (present=%v)", p.key, v, ok)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Package certs holds the certificate handling shared by the checks that
+// report the days until a server certificate expires: tls_cert, and the
+// mail checks with STARTTLS. It verifies presented chains, applies the
+// expiry thresholds, and parses the "targets" config key both checks
+// accept. LoadCAFile also serves the dns check's "ca_file" key.
+package certs
+
+import (
+	"crypto/x509"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultWarningDays is the default number of remaining days below
+	// which a check is marked degraded.
+	DefaultWarningDays = 21
+
+	// DefaultCriticalDays is the default number of remaining days below
+	// which a check fails.
+	DefaultCriticalDays = 7
+)
+
+// day is the length of one day used for expiry arithmetic.
+const day = 24 * time.Hour
+
+// Policy holds what a presented certificate is checked against: the
+// trusted roots and the expiry thresholds.
+type Policy struct {
+	WarningDays  int            // degrade below this many days remaining
+	CriticalDays int            // fail below this many days remaining
+	Roots        *x509.CertPool // nil means the system roots
+}
+
+// DefaultPolicy returns a Policy with the default thresholds that trusts
+// the system roots.
+func DefaultPolicy() Policy {
+	return Policy{
+		WarningDays:  DefaultWarningDays,
+		CriticalDays: DefaultCriticalDays,
+	}
+}
+
+// SetWarningDays sets the warning threshold.
+func (p *Policy) SetWarningDays(n int) error {
+	if n < 0 {
+		return fmt.Errorf("warning_days must not be negative, got %d", n)
+	}
+	p.WarningDays = n
+	return nil
+}
+
+// SetCriticalDays sets the critical threshold.
+func (p *Policy) SetCriticalDays(n int) error {
+	if n < 0 {
+		return fmt.Errorf("critical_days must not be negative, got %d", n)
+	}
+	p.CriticalDays = n
+	return nil
+}
+
+// SetRoots sets the pool of trusted roots used instead of the system roots.
+func (p *Policy) SetRoots(pool *x509.CertPool) error {
+	if pool == nil {
+		return fmt.Errorf("root CA pool must not be nil")
+	}
+	p.Roots = pool
+	return nil
+}
+
+// Validate checks that the warning threshold is not below the critical one.
+func (p Policy) Validate() error {
+	if p.WarningDays < p.CriticalDays {
+		return fmt.Errorf("warning_days (%d) must not be less than critical_days (%d)", p.WarningDays, p.CriticalDays)
+	}
+	return nil
+}
+
+// Verify verifies a presented chain, leaf first, for serverName at now.
+func (p Policy) Verify(chain []*x509.Certificate, serverName string, now time.Time) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := chain[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         p.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	}); err != nil {
+		return fmt.Errorf("certificate verification failed: %w", err)
+	}
+	return nil
+}
+
+// CheckExpiry applies the thresholds to leaf at now. It fails if fewer
+// than CriticalDays remain and reports degraded if fewer than WarningDays
+// remain.
+func (p Policy) CheckExpiry(leaf *x509.Certificate, now time.Time) (degraded bool, err error) {
+	remaining := leaf.NotAfter.Sub(now)
+	if remaining < time.Duration(p.CriticalDays)*day {
+		return false, fmt.Errorf("certificate expires in %.0f days (critical threshold %d)", DaysLeft(leaf, now), p.CriticalDays)
+	}
+	return remaining < time.Duration(p.WarningDays)*day, nil
+}
+
+// DaysLeft returns the whole days until leaf expires, negative once it
+// has expired.
+func DaysLeft(leaf *x509.Certificate, now time.Time) float64 {
+	return math.Floor(leaf.NotAfter.Sub(now).Hours() / 24)
+}
+
+// LoadCAFile reads a PEM bundle of trusted roots.
+func LoadCAFile(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read ca_file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in ca_file %s", path)
+	}
+	return pool, nil
+}
+
+// Target is a TLS server taken from a check's "targets" list.
+type Target struct {
+	Address    string // host:port to connect to
+	ServerName string // SNI and verification hostname
+	Label      string // the address, plus the server name when overridden
+}
+
+// ParseTargets parses the "targets" list from a check config. Each entry
+// is either an address string or an object with "address" and an optional
+// "server_name" (SNI and verification name override). See NewTarget for
+// how addresses are handled.
+func ParseTargets(config map[string]any, defaultPort string) ([]Target, error) {
+	raw, ok := config["targets"]
+	if !ok {
+		return nil, fmt.Errorf("config missing required key 'targets'")
+	}
+
+	rawList, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("'targets' must be a list, got %T", raw)
+	}
+	if len(rawList) == 0 {
+		return nil, fmt.Errorf("'targets' must not be empty")
+	}
+
+	targets := make([]Target, 0, len(rawList))
+	for i, item := range rawList {
+		var address, serverName string
+		switch v := item.(type) {
+		case string:
+			address = v
+		case map[string]any:
+			address, _ = v["address"].(string)
+			if sn, ok := v["server_name"]; ok {
+				s, ok := sn.(string)
+				if !ok || s == "" {
+					return nil, fmt.Errorf("target at index %d: 'server_name' must be a non-empty string", i)
+				}
+				serverName = s
+			}
+		default:
+			return nil, fmt.Errorf("target at index %d must be a string or object, got %T", i, item)
+		}
+
+		t, err := NewTarget(address, serverName, defaultPort)
+		if err != nil {
+			return nil, fmt.Errorf("target at index %d: %w", i, err)
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
+
+// NewTarget validates a server address and builds its Target. An address
+// without a port uses defaultPort, or is rejected if defaultPort is empty.
+// An empty serverName defaults to the host part of the address.
+func NewTarget(address, serverName, defaultPort string) (Target, error) {
+	if address == "" {
+		return Target{}, fmt.Errorf("missing required 'address'")
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		if defaultPort == "" {
+			return Target{}, fmt.Errorf("invalid address %q: %w", address, err)
+		}
+		host, port = strings.Trim(address, "[]"), defaultPort
+		address = net.JoinHostPort(host, port)
+	}
+	if host == "" || port == "" {
+		return Target{}, fmt.Errorf("address %q must be in host:port form", address)
+	}
+
+	label := address
+	if serverName == "" {
+		serverName = host
+	} else {
+		label = fmt.Sprintf("%s (%s)", address, serverName)
+	}
+
+	return Target{
+		Address:    address,
+		ServerName: serverName,
+		Label:      label,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package certs
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestNewTarget(t *testing.T) {
+	tests := []struct {
+		address, serverName, defaultPort string
+		want                             Target
+	}{
+		{"example.com:443", "", "", Target{"example.com:443", "example.com", "example.com:443"}},
+		{"10.0.0.5:8443", "portal.example.com", "", Target{"10.0.0.5:8443", "portal.example.com", "10.0.0.5:8443 (portal.example.com)"}},
+		{"mail.example.com", "", "25", Target{"mail.example.com:25", "mail.example.com", "mail.example.com:25"}},
+		{"[2001:db8::1]", "", "110", Target{"[2001:db8::1]:110", "2001:db8::1", "[2001:db8::1]:110"}},
+	}
+	for _, tt := range tests {
+		got, err := NewTarget(tt.address, tt.serverName, tt.defaultPort)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.address, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.address, tt.want, got)
+		}
+	}
+
+	for _, bad := range []string{"", "example.com", ":443"} {
+		if _, err := NewTarget(bad, "", ""); err == nil {
+			t.Errorf("expected error for address %q", bad)
+		}
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	targets, err := ParseTargets(map[string]any{
+		"targets": []any{
+			"a.example.com",
+			map[string]any{"address": "10.0.0.5:587", "server_name": "b.example.com"},
+		},
+	}, "25")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(targets) != 2 || targets[0].Address != "a.example.com:25" || targets[1].ServerName != "b.example.com" {
+		t.Errorf("unexpected targets: %+v", targets)
+	}
+
+	for name, config := range map[string]map[string]any{
+		"missing targets":   {},
+		"not a list":        {"targets": "a.example.com:443"},
+		"empty":             {"targets": []any{}},
+		"wrong type":        {"targets": []any{443}},
+		"missing address":   {"targets": []any{map[string]any{"server_name": "x"}}},
+		"empty server_name": {"targets": []any{map[string]any{"address": "a:443", "server_name": ""}}},
+	} {
+		if _, err := ParseTargets(config, ""); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestPolicy_Thresholds(t *testing.T) {
+	p := DefaultPolicy()
+	if err := p.SetWarningDays(-1); err == nil {
+		t.Error("expected error for negative warning_days")
+	}
+	if err := p.SetCriticalDays(-1); err == nil {
+		t.Error("expected error for negative critical_days")
+	}
+	if err := p.SetRoots(nil); err == nil {
+		t.Error("expected error for nil root pool")
+	}
+	if err := p.SetWarningDays(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err == nil {
+		t.Error("expected error when warning_days < critical_days")
+	}
+}
+
+func TestPolicy_CheckExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	p := DefaultPolicy()
+	tests := []struct {
+		name         string
+		left         time.Duration
+		wantDays     float64
+		wantDegraded bool
+		wantErr      bool
+	}{
+		{"healthy", 60*day + time.Hour, 60, false, false},
+		{"warning", 10 * day, 10, true, false},
+		{"critical", 3 * day, 3, false, true},
+		{"expired", -day - time.Hour, -2, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaf := &x509.Certificate{NotAfter: now.Add(tt.left)}
+			if got := DaysLeft(leaf, now); got != tt.wantDays {
+				t.Errorf("expected %v days left, got %v", tt.wantDays, got)
+			}
+			degraded, err := p.CheckExpiry(leaf, now)
+			if degraded != tt.wantDegraded || (err != nil) != tt.wantErr {
+				t.Errorf("expected degraded=%v err=%v, got degraded=%v err=%v", tt.wantDegraded, tt.wantErr, degraded, err)
+			}
+		})
+	}
+}
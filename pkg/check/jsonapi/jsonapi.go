@@ -0,0 +1,444 @@
+// Package jsonapi implements a check that fetches a JSON document over HTTP
+// and extracts numeric values from it with JSONPath-like expressions. It
+// suits devices such as PDUs, UPSes and solar inverters that report their
+// status as JSON rather than Prometheus metrics.
+//
+// Given a response like
+//
+//	{"ups": {"load": 23, "battery": {"charge": "100"}}, "outlets": [{"amps": 0.8}]}
+//
+// the paths "$.ups.load", "$.ups.battery.charge" and "$.outlets[0].amps"
+// each become a data source. Values may optionally be asserted to equal a
+// number or to fall within a range; a failed assertion fails the check.
+package jsonapi
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+)
+
+const (
+	// TypeName is the registered name for this check type.
+	TypeName = "json"
+
+	// DefaultTimeout is the default HTTP request timeout.
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultMaxBodyBytes is the default limit on how much of the response
+	// body is read.
+	DefaultMaxBodyBytes = 1 << 20
+)
+
+// valueConfig maps a path in the document to an RRD data source.
+type valueConfig struct {
+	path      string     // path expression as configured
+	steps     []pathStep // parsed path
+	resultKey string     // key in Result.Metrics (= label)
+	dsName    string     // RRD DS name (e.g. "v0")
+	label     string     // human-readable label (defaults to the path)
+	unit      string
+	divisor   int      // graphs show the value ÷ divisor
+	equals    *float64 // value must equal this
+	min, max  *float64 // value must lie within [min, max]
+}
+
+// assert checks v against the value's equality and range assertions.
+func (vc valueConfig) assert(v float64) error {
+	if vc.equals != nil && v != *vc.equals {
+		return fmt.Errorf("%s is %s, expected %s", vc.label, formatFloat(v), formatFloat(*vc.equals))
+	}
+	if vc.min != nil && v < *vc.min {
+		return fmt.Errorf("%s is %s, below minimum %s", vc.label, formatFloat(v), formatFloat(*vc.min))
+	}
+	if vc.max != nil && v > *vc.max {
+		return fmt.Errorf("%s is %s, above maximum %s", vc.label, formatFloat(v), formatFloat(*vc.max))
+	}
+	return nil
+}
+
+// Check implements check.Check by fetching a JSON document over HTTP.
+type Check struct {
+	url          string
+	values       []valueConfig
+	timeout      time.Duration
+	skipVerify   bool
+	maxBodyBytes int64
+	client       *http.Client
+	desc         check.Descriptor
+}
+
+// Option is a functional option for configuring a JSON Check.
+type Option func(*Check) error
+
+// WithTimeout sets the HTTP request timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Check) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout must be positive, got %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithSkipVerify sets whether to skip TLS certificate verification.
+func WithSkipVerify(skip bool) Option {
+	return func(c *Check) error {
+		c.skipVerify = skip
+		return nil
+	}
+}
+
+// WithMaxBodyBytes limits how much of the response body is read.
+func WithMaxBodyBytes(n int64) Option {
+	return func(c *Check) error {
+		if n <= 0 {
+			return fmt.Errorf("max body bytes must be positive, got %d", n)
+		}
+		c.maxBodyBytes = n
+		return nil
+	}
+}
+
+// newCheck creates a JSON Check that fetches url and extracts the given values.
+func newCheck(url string, values []valueConfig, opts ...Option) (*Check, error) {
+	if url == "" {
+		return nil, fmt.Errorf("json: url must not be empty")
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("json: at least one value is required")
+	}
+
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		if seen[v.resultKey] {
+			return nil, fmt.Errorf("json: duplicate value %q", v.resultKey)
+		}
+		seen[v.resultKey] = true
+	}
+
+	c := &Check{
+		url:          url,
+		values:       values,
+		timeout:      DefaultTimeout,
+		maxBodyBytes: DefaultMaxBodyBytes,
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("json: %w", err)
+		}
+	}
+
+	c.client = &http.Client{
+		Timeout: c.timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: c.skipVerify},
+		},
+	}
+
+	metrics := make([]check.MetricDef, len(values))
+	for i, v := range values {
+		metrics[i] = check.MetricDef{
+			ResultKey: v.resultKey,
+			DSName:    v.dsName,
+			Label:     v.label,
+			Unit:      v.unit,
+			Scale:     v.divisor,
+		}
+	}
+	c.desc = check.Descriptor{
+		Label:   "json values",
+		Metrics: metrics,
+	}
+
+	return c, nil
+}
+
+// Type returns the check type name.
+func (c *Check) Type() string {
+	return TypeName
+}
+
+// Describe returns the Descriptor for this check instance.
+func (c *Check) Describe() check.Descriptor {
+	return c.desc
+}
+
+// Run fetches the document and returns a Result with one metric per
+// configured value. A value whose path is missing or not numeric is
+// recorded as nil. The check fails if the fetch fails, any value cannot be
+// extracted, or any assertion fails; the error describes the first
+// problem found.
+func (c *Check) Run(ctx context.Context) check.Result {
+	now := time.Now()
+	metrics := make(map[string]*float64, len(c.values))
+
+	doc, err := c.fetch(ctx)
+	if err != nil {
+		for _, vc := range c.values {
+			metrics[vc.resultKey] = nil
+		}
+		return check.Result{
+			Timestamp: now,
+			Success:   false,
+			Metrics:   metrics,
+			Err:       fmt.Errorf("json: %w", err),
+		}
+	}
+
+	var firstErr error
+	for _, vc := range c.values {
+		v, err := extract(doc, vc)
+		if err != nil {
+			metrics[vc.resultKey] = nil
+		} else {
+			metrics[vc.resultKey] = &v
+			err = vc.assert(v)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	result := check.Result{
+		Timestamp: now,
+		Success:   firstErr == nil,
+		Metrics:   metrics,
+	}
+	if firstErr != nil {
+		result.Err = fmt.Errorf("json: %w", firstErr)
+	}
+	return result
+}
+
+// fetch requests the document and decodes it. Numbers are kept as
+// json.Number so large integers are not rounded before extraction.
+func (c *Check) fetch(ctx context.Context) (any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	dec := json.NewDecoder(io.LimitReader(resp.Body, c.maxBodyBytes))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON (read up to %d bytes): %w", c.maxBodyBytes, err)
+	}
+	return doc, nil
+}
+
+// extract looks up vc's path in doc and converts the value to a number.
+func extract(doc any, vc valueConfig) (float64, error) {
+	raw, err := lookup(doc, vc.steps)
+	if err != nil {
+		return 0, err
+	}
+	v, err := number(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", vc.path, err)
+	}
+	return v, nil
+}
+
+// number converts an extracted value to a float. Numbers, numeric
+// strings (as some devices quote their readings) and booleans (1 or 0)
+// are accepted.
+func number(v any) (float64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Float64()
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil {
+			return 0, fmt.Errorf("string %q is not numeric", n)
+		}
+		return f, nil
+	case bool:
+		if n {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("value is %s, not a number", kind(v))
+	}
+}
+
+// formatFloat renders a float compactly for error messages.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Factory creates a JSON Check from a config map.
+// Required keys:
+//   - "url" (string) — URL of the JSON document
+//   - "values" (list of objects) — values to extract, see below
+//
+// Optional keys:
+//   - "timeout" (string) — duration string, default "10s"
+//   - "skip_verify" (bool) — skip TLS cert verification (default: false)
+//   - "max_body_bytes" (number) — limit on body bytes read, default 1 MiB
+//
+// Each value object has:
+//   - "path" (string, required) — e.g. "$.ups.load" or "$.outlets[0].amps"
+//   - "label" (string) — display label and result key, default the path
+//   - "unit" (string) — display unit
+//   - "divisor" (number) — integer graphs divide the value by, default 1
+//   - "equals" (number) — the value must equal this
+//   - "min", "max" (number) — the value must lie within this range
+func Factory(config map[string]any) (check.Check, error) {
+	url, ok := config["url"].(string)
+	if !ok || url == "" {
+		return nil, fmt.Errorf("json: config missing required key 'url'")
+	}
+
+	values, err := extractValues(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []Option
+
+	if v, ok := config["timeout"]; ok {
+		ts, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("json: 'timeout' must be a duration string, got %T", v)
+		}
+		d, err := time.ParseDuration(ts)
+		if err != nil {
+			return nil, fmt.Errorf("json: invalid timeout %q: %w", ts, err)
+		}
+		opts = append(opts, WithTimeout(d))
+	}
+
+	if v, ok := config["skip_verify"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("json: 'skip_verify' must be a bool, got %T", v)
+		}
+		opts = append(opts, WithSkipVerify(b))
+	}
+
+	if v, ok := config["max_body_bytes"]; ok {
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("json: 'max_body_bytes' must be a number, got %T", v)
+		}
+		opts = append(opts, WithMaxBodyBytes(int64(n)))
+	}
+
+	return newCheck(url, values, opts...)
+}
+
+// extractValues parses the "values" config key into valueConfigs.
+func extractValues(config map[string]any) ([]valueConfig, error) {
+	raw, ok := config["values"]
+	if !ok {
+		return nil, fmt.Errorf("json: config missing required key 'values'")
+	}
+
+	rawList, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("json: 'values' must be a list, got %T", raw)
+	}
+	if len(rawList) == 0 {
+		return nil, fmt.Errorf("json: 'values' must not be empty")
+	}
+
+	values := make([]valueConfig, 0, len(rawList))
+	for i, item := range rawList {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("json: value at index %d must be an object, got %T", i, item)
+		}
+
+		path, ok := m["path"].(string)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("json: value at index %d missing required 'path'", i)
+		}
+		steps, err := parsePath(path)
+		if err != nil {
+			return nil, fmt.Errorf("json: value at index %d: %w", i, err)
+		}
+
+		vc := valueConfig{
+			path:   path,
+			steps:  steps,
+			label:  path,
+			dsName: fmt.Sprintf("v%d", i),
+		}
+
+		if v, ok := m["label"]; ok {
+			s, ok := v.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("json: value at index %d: 'label' must be a non-empty string", i)
+			}
+			vc.label = s
+		}
+		vc.resultKey = vc.label
+
+		if v, ok := m["unit"]; ok {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("json: value at index %d: 'unit' must be a string, got %T", i, v)
+			}
+			vc.unit = s
+		}
+
+		if v, ok := m["divisor"]; ok {
+			f, ok := v.(float64)
+			if !ok || f < 1 || f != math.Trunc(f) {
+				return nil, fmt.Errorf("json: value at index %d: 'divisor' must be a positive integer", i)
+			}
+			vc.divisor = int(f)
+		}
+
+		for _, key := range []string{"equals", "min", "max"} {
+			v, ok := m[key]
+			if !ok {
+				continue
+			}
+			f, ok := v.(float64)
+			if !ok {
+				return nil, fmt.Errorf("json: value at index %d: '%s' must be a number, got %T", i, key, v)
+			}
+			switch key {
+			case "equals":
+				vc.equals = &f
+			case "min":
+				vc.min = &f
+			case "max":
+				vc.max = &f
+			}
+		}
+		if vc.min != nil && vc.max != nil && *vc.min > *vc.max {
+			return nil, fmt.Errorf("json: value at index %d: 'min' must not exceed 'max'", i)
+		}
+
+		values = append(values, vc)
+	}
+
+	return values, nil
+}
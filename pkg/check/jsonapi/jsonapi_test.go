@@ -0,0 +1,246 @@
+package jsonapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newJSONServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func ptr(f float64) *float64 { return &f }
+
+func mustValue(t *testing.T, i int, path, label string) valueConfig {
+	t.Helper()
+	steps, err := parsePath(path)
+	if err != nil {
+		t.Fatalf("parsePath(%q): %v", path, err)
+	}
+	return valueConfig{path: path, steps: steps, resultKey: label, label: label, dsName: fmt.Sprintf("v%d", i)}
+}
+
+// --- newCheck() tests ---
+
+func TestNew_Valid(t *testing.T) {
+	c, err := newCheck("http://pdu.local/status.json", []valueConfig{mustValue(t, 0, "$.ups.load", "load")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.timeout != DefaultTimeout || c.maxBodyBytes != DefaultMaxBodyBytes {
+		t.Errorf("expected defaults, got timeout=%v max=%d", c.timeout, c.maxBodyBytes)
+	}
+	if c.Type() != TypeName {
+		t.Errorf("expected type %q, got %q", TypeName, c.Type())
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	v := mustValue(t, 0, "$.ups.load", "load")
+	if _, err := newCheck("", []valueConfig{v}); err == nil {
+		t.Error("expected error for empty url")
+	}
+	if _, err := newCheck("http://x", nil); err == nil {
+		t.Error("expected error for no values")
+	}
+	if _, err := newCheck("http://x", []valueConfig{v, v}); err == nil {
+		t.Error("expected error for duplicate value")
+	}
+	if _, err := newCheck("http://x", []valueConfig{v}, WithMaxBodyBytes(0)); err == nil {
+		t.Error("expected error for zero max body bytes")
+	}
+}
+
+// --- Run() tests ---
+
+func TestRun_Success(t *testing.T) {
+	srv := newJSONServer(t, http.StatusOK, sampleDoc)
+	load := mustValue(t, 0, "$.ups.load", "load")
+	load.min, load.max = ptr(0), ptr(80)
+	amps := mustValue(t, 1, "$.outlets[0].amps", "nas amps")
+	amps.divisor = 100
+	charge := mustValue(t, 2, "$.ups.battery.charge", "charge")
+	online := mustValue(t, 3, "$.online", "online")
+	online.equals = ptr(1)
+
+	c, err := newCheck(srv.URL, []valueConfig{load, amps, charge, online})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	want := map[string]float64{"load": 23, "nas amps": 0.8, "charge": 100, "online": 1}
+	for k, w := range want {
+		if v := result.Metrics[k]; v == nil || *v != w {
+			t.Errorf("%s: expected %v, got %v", k, w, v)
+		}
+	}
+}
+
+func TestRun_AssertionFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*valueConfig)
+		errMsg string
+	}{
+		{"equals", func(v *valueConfig) { v.equals = ptr(0) }, "load is 23, expected 0"},
+		{"below min", func(v *valueConfig) { v.min = ptr(50) }, "below minimum 50"},
+		{"above max", func(v *valueConfig) { v.max = ptr(20) }, "above maximum 20"},
+	}
+	srv := newJSONServer(t, http.StatusOK, sampleDoc)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := mustValue(t, 0, "$.ups.load", "load")
+			tt.modify(&v)
+			c, _ := newCheck(srv.URL, []valueConfig{v})
+			result := c.Run(context.Background())
+			if result.Success {
+				t.Fatal("expected failure")
+			}
+			if !strings.Contains(result.Err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, result.Err)
+			}
+			// The value is still recorded so the graph shows what tripped.
+			if m := result.Metrics["load"]; m == nil || *m != 23 {
+				t.Errorf("expected load recorded as 23, got %v", m)
+			}
+		})
+	}
+}
+
+func TestRun_MissingValue(t *testing.T) {
+	srv := newJSONServer(t, http.StatusOK, sampleDoc)
+	c, _ := newCheck(srv.URL, []valueConfig{
+		mustValue(t, 0, "$.ups.load", "load"),
+		mustValue(t, 1, "$.ups.temperature", "temp"),
+		mustValue(t, 2, "$.outlets[1].name", "name"),
+	})
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Fatal("expected failure for missing value")
+	}
+	if !strings.Contains(result.Err.Error(), "temperature") {
+		t.Errorf("expected first problem in error, got %v", result.Err)
+	}
+	if v := result.Metrics["load"]; v == nil || *v != 23 {
+		t.Errorf("expected load recorded, got %v", v)
+	}
+	for _, k := range []string{"temp", "name"} {
+		if v, ok := result.Metrics[k]; !ok || v != nil {
+			t.Errorf("%s: expected nil metric, got %v (present=%v)", k, v, ok)
+		}
+	}
+}
+
+func TestRun_FetchFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		errMsg string
+	}{
+		{"server error", http.StatusServiceUnavailable, `{}`, "unexpected status 503"},
+		{"invalid json", http.StatusOK, `<html>`, "invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newJSONServer(t, tt.status, tt.body)
+			c, _ := newCheck(srv.URL, []valueConfig{mustValue(t, 0, "$.ups.load", "load")})
+			result := c.Run(context.Background())
+			if result.Success {
+				t.Fatal("expected failure")
+			}
+			if !strings.Contains(result.Err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, result.Err)
+			}
+			if v, ok := result.Metrics["load"]; !ok || v != nil {
+				t.Errorf("expected nil metric, got %v (present=%v)", v, ok)
+			}
+		})
+	}
+}
+
+func TestRun_BodyLimit(t *testing.T) {
+	srv := newJSONServer(t, http.StatusOK, sampleDoc)
+	c, _ := newCheck(srv.URL, []valueConfig{mustValue(t, 0, "$.ups.load", "load")}, WithMaxBodyBytes(10))
+	if result := c.Run(context.Background()); result.Success {
+		t.Error("expected failure when the document exceeds the body limit")
+	}
+}
+
+// --- Factory tests ---
+
+func TestFactory_Valid(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"url":            "https://inverter.local/api/status",
+		"timeout":        "5s",
+		"skip_verify":    true,
+		"max_body_bytes": float64(4096),
+		"values": []any{
+			map[string]any{"path": "$.pv.power", "label": "pv power", "unit": "W", "min": float64(0)},
+			map[string]any{"path": "$.battery.soc", "unit": "%", "divisor": float64(10), "max": float64(100)},
+			map[string]any{"path": "$.grid.connected", "equals": float64(1)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := chk.(*Check)
+	if c.timeout != 5*time.Second || !c.skipVerify || c.maxBodyBytes != 4096 {
+		t.Errorf("unexpected options: timeout=%v skip=%v max=%d", c.timeout, c.skipVerify, c.maxBodyBytes)
+	}
+	m := c.Describe().Metrics
+	if len(m) != 3 {
+		t.Fatalf("expected 3 metrics, got %d", len(m))
+	}
+	if m[0].ResultKey != "pv power" || m[0].Unit != "W" || m[0].DSName != "v0" {
+		t.Errorf("unexpected first metric: %+v", m[0])
+	}
+	if m[1].ResultKey != "$.battery.soc" || m[1].Scale != 10 {
+		t.Errorf("expected path as default label and divisor 10, got %+v", m[1])
+	}
+	if c.values[2].equals == nil || *c.values[2].equals != 1 {
+		t.Error("expected equals assertion on third value")
+	}
+}
+
+func TestFactory_Errors(t *testing.T) {
+	value := map[string]any{"path": "$.a"}
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{"missing url", map[string]any{"values": []any{value}}},
+		{"missing values", map[string]any{"url": "http://x"}},
+		{"empty values", map[string]any{"url": "http://x", "values": []any{}}},
+		{"missing path", map[string]any{"url": "http://x", "values": []any{map[string]any{"label": "a"}}}},
+		{"invalid path", map[string]any{"url": "http://x", "values": []any{map[string]any{"path": "$.a["}}}},
+		{"min not number", map[string]any{"url": "http://x", "values": []any{map[string]any{"path": "$.a", "min": "0"}}}},
+		{"min above max", map[string]any{"url": "http://x", "values": []any{map[string]any{"path": "$.a", "min": float64(5), "max": float64(1)}}}},
+		{"bad divisor", map[string]any{"url": "http://x", "values": []any{map[string]any{"path": "$.a", "divisor": float64(0)}}}},
+		{"duplicate path", map[string]any{"url": "http://x", "values": []any{value, value}}},
+		{"bad skip_verify", map[string]any{"url": "http://x", "values": []any{value}, "skip_verify": "yes"}},
+		{"bad timeout", map[string]any{"url": "http://x", "values": []any{value}, "timeout": float64(5)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Factory(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
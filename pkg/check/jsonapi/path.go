@@ -0,0 +1,140 @@
+package jsonapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathStep is one step of a parsed path: an object key or an array index.
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (s pathStep) String() string {
+	if s.isIndex {
+		return fmt.Sprintf("[%d]", s.index)
+	}
+	return "." + s.key
+}
+
+// parsePath parses a JSONPath-like expression into steps. Supported forms
+// are dotted keys ($.ups.battery.charge), array indexes (outlets[0],
+// negative indexes count from the end) and bracketed quoted keys for names
+// containing dots or spaces ($['input voltage']). The leading "$" is
+// optional.
+func parsePath(expr string) ([]pathStep, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(expr), "$")
+	var steps []pathStep
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '.':
+			i++
+			j := i
+			for j < len(s) && s[j] != '.' && s[j] != '[' {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("path %q: empty key at offset %d", expr, i)
+			}
+			steps = append(steps, pathStep{key: s[i:j]})
+			i = j
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q: unterminated '['", expr)
+			}
+			inner := s[i+1 : i+end]
+			// A quoted key may itself contain ']', so find the closing quote.
+			if len(inner) > 0 && (inner[0] == '\'' || inner[0] == '"') {
+				q := inner[0]
+				qend := strings.IndexByte(s[i+2:], q)
+				if qend < 0 || i+2+qend+1 >= len(s) || s[i+2+qend+1] != ']' {
+					return nil, fmt.Errorf("path %q: unterminated quoted key", expr)
+				}
+				steps = append(steps, pathStep{key: s[i+2 : i+2+qend]})
+				i += 2 + qend + 2
+				continue
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(inner))
+			if err != nil {
+				return nil, fmt.Errorf("path %q: invalid index %q", expr, inner)
+			}
+			steps = append(steps, pathStep{index: n, isIndex: true})
+			i += end + 1
+		default:
+			// A bare leading key, as in "ups.load".
+			if len(steps) > 0 || i > 0 {
+				return nil, fmt.Errorf("path %q: unexpected %q at offset %d", expr, s[i], i)
+			}
+			s = "." + s
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("path %q selects the whole document", expr)
+	}
+	return steps, nil
+}
+
+// lookup walks doc (as decoded by encoding/json) along steps.
+func lookup(doc any, steps []pathStep) (any, error) {
+	cur := doc
+	for i, st := range steps {
+		at := pathString(steps[:i])
+		if st.isIndex {
+			arr, ok := cur.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s is %s, not an array", at, kind(cur))
+			}
+			idx := st.index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, fmt.Errorf("%s has %d elements, index %d out of range", at, len(arr), st.index)
+			}
+			cur = arr[idx]
+			continue
+		}
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s is %s, not an object", at, kind(cur))
+		}
+		v, ok := obj[st.key]
+		if !ok {
+			return nil, fmt.Errorf("%s has no key %q", at, st.key)
+		}
+		cur = v
+	}
+	return cur, nil
+}
+
+// pathString renders steps as a path for error messages.
+func pathString(steps []pathStep) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, st := range steps {
+		b.WriteString(st.String())
+	}
+	return b.String()
+}
+
+// kind names the JSON type of a decoded value for error messages.
+func kind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case bool:
+		return "a bool"
+	default:
+		return "a number"
+	}
+}
@@ -0,0 +1,96 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, s string) any {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return doc
+}
+
+const sampleDoc = `{
+	"ups": {"load": 23, "battery": {"charge": "100", "runtime": 1800}},
+	"outlets": [{"name": "nas", "amps": 0.8}, {"name": "switch", "amps": 0.25}],
+	"input voltage": 231.5,
+	"online": true,
+	"note": null
+}`
+
+func TestLookup(t *testing.T) {
+	doc := decode(t, sampleDoc)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"$.ups.load", "23"},
+		{"ups.load", "23"},
+		{"$.ups.battery.runtime", "1800"},
+		{"$.outlets[0].amps", "0.8"},
+		{"$.outlets[-1].amps", "0.25"},
+		{"$['input voltage']", "231.5"},
+		{`$["ups"]["battery"].charge`, "100"},
+	}
+	for _, tt := range tests {
+		steps, err := parsePath(tt.path)
+		if err != nil {
+			t.Errorf("%s: unexpected parse error: %v", tt.path, err)
+			continue
+		}
+		v, err := lookup(doc, steps)
+		if err != nil {
+			t.Errorf("%s: unexpected lookup error: %v", tt.path, err)
+			continue
+		}
+		var got string
+		switch x := v.(type) {
+		case json.Number:
+			got = x.String()
+		case string:
+			got = x
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %s, got %v", tt.path, tt.want, v)
+		}
+	}
+}
+
+func TestLookup_Errors(t *testing.T) {
+	doc := decode(t, sampleDoc)
+	tests := []struct {
+		path   string
+		errMsg string
+	}{
+		{"$.ups.temperature", `$.ups has no key "temperature"`},
+		{"$.outlets[5].amps", "index 5 out of range"},
+		{"$.ups[0]", "$.ups is an object, not an array"},
+		{"$.ups.load.value", "$.ups.load is a number, not an object"},
+		{"$.note.value", "$.note is null"},
+	}
+	for _, tt := range tests {
+		steps, err := parsePath(tt.path)
+		if err != nil {
+			t.Fatalf("%s: unexpected parse error: %v", tt.path, err)
+		}
+		_, err = lookup(doc, steps)
+		if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.path, tt.errMsg, err)
+		}
+	}
+}
+
+func TestParsePath_Invalid(t *testing.T) {
+	for _, p := range []string{"$", "", "$..a", "$.a[", "$.a[x]", "$['a", "$.a[0]b"} {
+		if _, err := parsePath(p); err == nil {
+			t.Errorf("expected error for %q", p)
+		}
+	}
+}
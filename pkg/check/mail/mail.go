@@ -0,0 +1,558 @@
+// Package mail implements protocol-aware checks for mail servers: smtp,
+// imap and pop3. Each connects to one or more servers, reads the greeting,
+// asks for the server's capabilities (EHLO, CAPABILITY or CAPA) and reports
+// how long the handshake took. This catches servers that accept
+// connections but are not actually serving mail, which a TCP connect alone
+// does not.
+//
+// With STARTTLS enabled the check also upgrades the connection, verifies
+// the certificate presented and reports the days until it expires, with
+// the same thresholds as the tls_cert check. The greeting can be matched
+// against an expected-banner regular expression. No credentials are ever
+// sent.
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+	"github.com/kylerisse/wasgeht/pkg/check/internal/certs"
+)
+
+const (
+	// SMTPTypeName is the registered name for the SMTP check type.
+	SMTPTypeName = "smtp"
+
+	// IMAPTypeName is the registered name for the IMAP check type.
+	IMAPTypeName = "imap"
+
+	// POP3TypeName is the registered name for the POP3 check type.
+	POP3TypeName = "pop3"
+
+	// DefaultTimeout is the default per-server timeout.
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultEHLOName is the default name sent in the SMTP EHLO command.
+	DefaultEHLOName = "localhost"
+
+	// DefaultWarningDays is the default number of remaining certificate
+	// days below which the check is marked degraded.
+	DefaultWarningDays = certs.DefaultWarningDays
+
+	// DefaultCriticalDays is the default number of remaining certificate
+	// days below which the check fails.
+	DefaultCriticalDays = certs.DefaultCriticalDays
+)
+
+// protocol describes the parts of a mail protocol that differ between
+// the check types.
+type protocol struct {
+	defaultPort   string
+	tlsCapability string // capability advertising STARTTLS
+}
+
+var protocols = map[string]protocol{
+	SMTPTypeName: {defaultPort: "25", tlsCapability: "STARTTLS"},
+	IMAPTypeName: {defaultPort: "143", tlsCapability: "STARTTLS"},
+	POP3TypeName: {defaultPort: "110", tlsCapability: "STLS"},
+}
+
+// targetConfig holds the parsed configuration for a single server.
+type targetConfig struct {
+	address    string // host:port to connect to
+	serverName string // SNI and verification hostname (defaults to the host part of address)
+	resultKey  string // key in Result.Metrics for the handshake time
+	dsName     string // RRD DS name (e.g. "addr0")
+	label      string // human-readable label
+}
+
+// certResultKey returns the Result.Metrics key for a server's days until
+// certificate expiry.
+func certResultKey(t targetConfig) string {
+	return t.resultKey + " cert"
+}
+
+// certDSName returns the RRD DS name for a server's days until
+// certificate expiry (e.g. "addr0_cert").
+func certDSName(t targetConfig) string {
+	return t.dsName + "_cert"
+}
+
+// Check implements check.Check for the smtp, imap and pop3 check types.
+type Check struct {
+	typeName   string
+	proto      protocol
+	targets    []targetConfig
+	timeout    time.Duration
+	startTLS   bool
+	banner     *regexp.Regexp // nil accepts any greeting
+	ehloName   string
+	policy     certs.Policy
+	skipVerify bool
+	dialer     *net.Dialer
+	desc       check.Descriptor
+}
+
+// Option is a functional option for configuring a mail Check.
+type Option func(*Check) error
+
+// WithTimeout sets the per-server timeout covering connect and the whole
+// handshake.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Check) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout must be positive, got %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithStartTLS sets whether the check requires STARTTLS, upgrades the
+// connection and reports the days until the server certificate expires.
+func WithStartTLS(startTLS bool) Option {
+	return func(c *Check) error {
+		c.startTLS = startTLS
+		return nil
+	}
+}
+
+// WithBanner sets a regular expression the greeting must match.
+func WithBanner(pattern string) Option {
+	return func(c *Check) error {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid banner pattern: %w", err)
+		}
+		c.banner = re
+		return nil
+	}
+}
+
+// WithEHLOName sets the name the SMTP check sends with EHLO.
+func WithEHLOName(name string) Option {
+	return func(c *Check) error {
+		if c.typeName != SMTPTypeName {
+			return fmt.Errorf("ehlo_name applies only to smtp")
+		}
+		if name == "" {
+			return fmt.Errorf("ehlo_name must not be empty")
+		}
+		c.ehloName = name
+		return nil
+	}
+}
+
+// WithWarningDays sets the remaining certificate days below which the
+// check is marked degraded.
+func WithWarningDays(n int) Option {
+	return func(c *Check) error {
+		return c.policy.SetWarningDays(n)
+	}
+}
+
+// WithCriticalDays sets the remaining certificate days below which the
+// check fails.
+func WithCriticalDays(n int) Option {
+	return func(c *Check) error {
+		return c.policy.SetCriticalDays(n)
+	}
+}
+
+// WithRootCAs sets the pool of trusted root certificates used for chain
+// verification instead of the system roots.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *Check) error {
+		return c.policy.SetRoots(pool)
+	}
+}
+
+// WithSkipVerify disables certificate chain and hostname verification.
+// The expiry is still reported.
+func WithSkipVerify(skip bool) Option {
+	return func(c *Check) error {
+		c.skipVerify = skip
+		return nil
+	}
+}
+
+// New creates a mail Check of the given type (SMTPTypeName, IMAPTypeName
+// or POP3TypeName) for the given server addresses. Addresses without a
+// port use the protocol's standard port.
+func New(typeName string, addresses []string, opts ...Option) (*Check, error) {
+	proto, ok := protocols[typeName]
+	if !ok {
+		return nil, fmt.Errorf("mail: unknown protocol %q", typeName)
+	}
+	targets := make([]targetConfig, len(addresses))
+	for i, a := range addresses {
+		t, err := certs.NewTarget(a, "", proto.defaultPort)
+		if err != nil {
+			return nil, fmt.Errorf("%s: target at index %d: %w", typeName, i, err)
+		}
+		targets[i] = newTargetConfig(i, t)
+	}
+	return newCheck(typeName, targets, opts...)
+}
+
+// newCheck creates a mail Check of the given type for fully parsed targets.
+func newCheck(typeName string, targets []targetConfig, opts ...Option) (*Check, error) {
+	proto, ok := protocols[typeName]
+	if !ok {
+		return nil, fmt.Errorf("mail: unknown protocol %q", typeName)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%s: at least one target is required", typeName)
+	}
+
+	c := &Check{
+		typeName: typeName,
+		proto:    proto,
+		targets:  targets,
+		timeout:  DefaultTimeout,
+		ehloName: DefaultEHLOName,
+		policy:   certs.DefaultPolicy(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("%s: %w", typeName, err)
+		}
+	}
+
+	if err := c.policy.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", typeName, err)
+	}
+
+	c.dialer = &net.Dialer{}
+
+	var metrics []check.MetricDef
+	for _, t := range targets {
+		metrics = append(metrics, check.MetricDef{
+			ResultKey: t.resultKey,
+			DSName:    t.dsName,
+			Label:     t.label,
+			Unit:      "ms",
+			Scale:     1000,
+		})
+		if c.startTLS {
+			metrics = append(metrics, check.MetricDef{
+				ResultKey: certResultKey(t),
+				DSName:    certDSName(t),
+				Label:     t.label + " cert",
+				Unit:      "days",
+				Scale:     1,
+				Signed:    true,   // expired certificates have negative days left
+				Graph:     "cert", // days do not share an axis with handshake times
+			})
+		}
+	}
+	c.desc = check.Descriptor{
+		Label:   "handshake",
+		Metrics: metrics,
+	}
+
+	return c, nil
+}
+
+// Type returns the check type name.
+func (c *Check) Type() string {
+	return c.typeName
+}
+
+// Describe returns the Descriptor for this check instance.
+func (c *Check) Describe() check.Descriptor {
+	return c.desc
+}
+
+// Run performs the handshake with every server and returns a Result. Each
+// server's handshake time (from connection established until the
+// capabilities are known, including STARTTLS and the capabilities asked
+// again over TLS) is stored in microseconds. With STARTTLS, the days until
+// the certificate expires are stored too, even when verification fails.
+// Success requires every server to complete the handshake, match the
+// expected banner and, with STARTTLS, present a valid certificate with
+// more than critical_days remaining. The result is degraded if any
+// certificate has fewer than warning_days remaining.
+func (c *Check) Run(ctx context.Context) check.Result {
+	metrics := make(map[string]*float64, len(c.desc.Metrics))
+	var lastErr error
+	degraded := false
+
+	for _, t := range c.targets {
+		now := time.Now()
+		res, err := c.probe(ctx, t, now)
+
+		metrics[t.resultKey] = nil
+		if res.done {
+			v := float64(res.elapsed.Microseconds())
+			metrics[t.resultKey] = &v
+		}
+
+		if c.startTLS {
+			metrics[certResultKey(t)] = nil
+			if res.leaf != nil {
+				days := certs.DaysLeft(res.leaf, now)
+				metrics[certResultKey(t)] = &days
+			}
+		}
+
+		if err == nil && c.startTLS {
+			var warn bool
+			warn, err = c.policy.CheckExpiry(res.leaf, now)
+			degraded = degraded || warn
+		}
+		if err != nil {
+			lastErr = fmt.Errorf("%s %s: %w", c.typeName, t.label, err)
+		}
+	}
+
+	return check.Result{
+		Timestamp: time.Now(),
+		Success:   lastErr == nil,
+		Degraded:  degraded,
+		Err:       lastErr,
+		Metrics:   metrics,
+	}
+}
+
+// probeResult holds what was measured of one server, which may be partial
+// when the probe fails.
+type probeResult struct {
+	done    bool              // the handshake completed
+	elapsed time.Duration     // handshake time, valid if done
+	leaf    *x509.Certificate // certificate seen via STARTTLS, if any
+}
+
+// probe performs the handshake with a single server.
+func (c *Check) probe(ctx context.Context, t targetConfig, now time.Time) (probeResult, error) {
+	var res probeResult
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	conn, err := c.dialer.DialContext(ctx, "tcp", t.address)
+	if err != nil {
+		return res, err
+	}
+	s := newSession(conn)
+	defer func() { s.conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	d := c.dialect()
+	start := time.Now()
+
+	greeting, err := d.greet(s)
+	if err != nil {
+		return res, fmt.Errorf("greeting: %w", err)
+	}
+	// A wrong banner is reported once the handshake has been measured.
+	var bannerErr error
+	if c.banner != nil && !c.banner.MatchString(greeting) {
+		bannerErr = fmt.Errorf("greeting %q does not match %q", greeting, c.banner)
+	}
+
+	caps, err := d.capabilities(s)
+	if err != nil {
+		return res, err
+	}
+
+	var verifyErr error
+	if c.startTLS {
+		if !hasCapability(caps, c.proto.tlsCapability) {
+			return res, fmt.Errorf("server does not advertise %s", c.proto.tlsCapability)
+		}
+		if err := d.startTLS(s); err != nil {
+			return res, fmt.Errorf("%s: %w", c.proto.tlsCapability, err)
+		}
+		state, err := s.upgrade(ctx, &tls.Config{
+			ServerName: t.serverName,
+			// Verification is done below so that the leaf is still
+			// available for the expiry metric when the chain is bad.
+			InsecureSkipVerify: true,
+		})
+		if err != nil {
+			return res, err
+		}
+		if len(state.PeerCertificates) == 0 {
+			return res, fmt.Errorf("no certificate presented")
+		}
+		res.leaf = state.PeerCertificates[0]
+		if !c.skipVerify {
+			verifyErr = c.policy.Verify(state.PeerCertificates, t.serverName, now)
+		}
+		if _, err := d.capabilities(s); err != nil {
+			return res, fmt.Errorf("after %s: %w", c.proto.tlsCapability, err)
+		}
+	}
+
+	res.elapsed = time.Since(start)
+	res.done = true
+	d.quit(s)
+
+	if bannerErr != nil {
+		return res, bannerErr
+	}
+	return res, verifyErr
+}
+
+// dialect returns the protocol implementation for this check.
+func (c *Check) dialect() dialect {
+	switch c.typeName {
+	case IMAPTypeName:
+		return imapDialect{}
+	case POP3TypeName:
+		return pop3Dialect{}
+	default:
+		return smtpDialect{ehloName: c.ehloName}
+	}
+}
+
+// SMTPFactory creates an smtp Check from a config map. See factory for
+// the accepted keys; "ehlo_name" (string, default "localhost") sets the
+// name sent with EHLO.
+func SMTPFactory(config map[string]any) (check.Check, error) {
+	return factory(SMTPTypeName, config)
+}
+
+// IMAPFactory creates an imap Check from a config map. See factory for
+// the accepted keys.
+func IMAPFactory(config map[string]any) (check.Check, error) {
+	return factory(IMAPTypeName, config)
+}
+
+// POP3Factory creates a pop3 Check from a config map. See factory for the
+// accepted keys.
+func POP3Factory(config map[string]any) (check.Check, error) {
+	return factory(POP3TypeName, config)
+}
+
+// factory creates a mail Check of the given type from a config map.
+// Required keys:
+//   - "targets" (list) — each either a "host" or "host:port" string (the
+//     protocol's standard port by default) or an object with "address" and
+//     optional "server_name" (SNI and verification name override)
+//
+// Optional keys:
+//   - "timeout" (string) — per-server duration string, default "10s"
+//   - "starttls" (bool) — require STARTTLS and report certificate expiry
+//   - "banner" (string) — regular expression the greeting must match
+//   - "warning_days" (number) — degrade below this many certificate days, default 21
+//   - "critical_days" (number) — fail below this many certificate days, default 7
+//   - "ca_file" (string) — PEM bundle of trusted roots instead of the system roots
+//   - "skip_verify" (bool) — skip certificate verification
+func factory(typeName string, config map[string]any) (check.Check, error) {
+	targets, err := extractTargets(typeName, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []Option
+
+	if v, ok := config["timeout"]; ok {
+		ts, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: 'timeout' must be a duration string, got %T", typeName, v)
+		}
+		d, err := time.ParseDuration(ts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid timeout %q: %w", typeName, ts, err)
+		}
+		opts = append(opts, WithTimeout(d))
+	}
+
+	if v, ok := config["starttls"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s: 'starttls' must be a bool, got %T", typeName, v)
+		}
+		opts = append(opts, WithStartTLS(b))
+	}
+
+	if v, ok := config["banner"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: 'banner' must be a string, got %T", typeName, v)
+		}
+		opts = append(opts, WithBanner(s))
+	}
+
+	if v, ok := config["ehlo_name"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: 'ehlo_name' must be a string, got %T", typeName, v)
+		}
+		opts = append(opts, WithEHLOName(s))
+	}
+
+	if v, ok := config["warning_days"]; ok {
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s: 'warning_days' must be a number, got %T", typeName, v)
+		}
+		opts = append(opts, WithWarningDays(int(n)))
+	}
+
+	if v, ok := config["critical_days"]; ok {
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s: 'critical_days' must be a number, got %T", typeName, v)
+		}
+		opts = append(opts, WithCriticalDays(int(n)))
+	}
+
+	if v, ok := config["ca_file"]; ok {
+		path, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: 'ca_file' must be a string, got %T", typeName, v)
+		}
+		pool, err := certs.LoadCAFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", typeName, err)
+		}
+		opts = append(opts, WithRootCAs(pool))
+	}
+
+	if v, ok := config["skip_verify"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s: 'skip_verify' must be a bool, got %T", typeName, v)
+		}
+		opts = append(opts, WithSkipVerify(b))
+	}
+
+	return newCheck(typeName, targets, opts...)
+}
+
+// extractTargets parses the "targets" list from the config map. Addresses
+// without a port use the protocol's standard port.
+func extractTargets(typeName string, config map[string]any) ([]targetConfig, error) {
+	parsed, err := certs.ParseTargets(config, protocols[typeName].defaultPort)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", typeName, err)
+	}
+	targets := make([]targetConfig, len(parsed))
+	for i, t := range parsed {
+		targets[i] = newTargetConfig(i, t)
+	}
+	return targets, nil
+}
+
+// newTargetConfig builds the targetConfig for the server at index.
+func newTargetConfig(index int, t certs.Target) targetConfig {
+	return targetConfig{
+		address:    t.Address,
+		serverName: t.ServerName,
+		resultKey:  t.Label,
+		dsName:     fmt.Sprintf("addr%d", index),
+		label:      t.Label,
+	}
+}
@@ -0,0 +1,533 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+	"github.com/kylerisse/wasgeht/pkg/check/internal/certs"
+)
+
+// day is the length of one day.
+const day = 24 * time.Hour
+
+// newCert returns a self-signed certificate for 127.0.0.1 and
+// mail.example.com expiring after validFor, and a pool trusting it.
+func newCert(t *testing.T, validFor time.Duration) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mail.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"mail.example.com"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
+
+// fakeServer is a minimal scripted mail server for one protocol.
+type fakeServer struct {
+	proto     string
+	greeting  string           // sent verbatim on connect; CRLF added
+	cert      *tls.Certificate // nil means STARTTLS is not offered
+	inject    bool             // send plaintext right after accepting STARTTLS
+	rejectTLS bool             // refuse the STARTTLS command
+}
+
+// start runs the server on a localhost listener and returns its address.
+func (f *fakeServer) start(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func (f *fakeServer) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if f.greeting == "" {
+		time.Sleep(2 * time.Second)
+		return
+	}
+	r := bufio.NewReader(conn)
+	write := func(lines ...string) {
+		for _, l := range lines {
+			_, _ = conn.Write([]byte(l + "\r\n"))
+		}
+	}
+	write(f.greeting)
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		tag, cmd := "", line
+		if f.proto == IMAPTypeName {
+			tag, cmd, _ = strings.Cut(line, " ")
+		}
+		verb, _, _ := strings.Cut(cmd, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			if f.cert != nil {
+				write("250-mail.example.com", "250-PIPELINING", "250 STARTTLS")
+			} else {
+				write("250-mail.example.com", "250 8BITMIME")
+			}
+		case "CAPABILITY":
+			caps := "* CAPABILITY IMAP4rev1"
+			if f.cert != nil {
+				caps += " STARTTLS"
+			}
+			write(caps, tag+" OK done")
+		case "CAPA":
+			write("+OK capability list follows", "USER")
+			if f.cert != nil {
+				write("STLS")
+			}
+			write(".")
+		case "STARTTLS", "STLS":
+			if f.rejectTLS {
+				switch f.proto {
+				case SMTPTypeName:
+					write("454 TLS not available")
+				case IMAPTypeName:
+					write(tag + " NO TLS not available")
+				default:
+					write("-ERR TLS not available")
+				}
+				continue
+			}
+			switch f.proto {
+			case SMTPTypeName:
+				write("220 go ahead")
+			case IMAPTypeName:
+				write(tag + " OK begin TLS")
+			default:
+				write("+OK begin TLS")
+			}
+			if f.inject {
+				write("250 injected")
+			}
+			tc := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*f.cert}})
+			if err := tc.Handshake(); err != nil {
+				return
+			}
+			conn, r = tc, bufio.NewReader(tc)
+		case "QUIT":
+			if f.proto == SMTPTypeName {
+				write("221 bye")
+			} else {
+				write("+OK bye")
+			}
+			return
+		case "LOGOUT":
+			write("* BYE logging out", tag+" OK done")
+			return
+		default:
+			write("500 unknown command")
+		}
+	}
+}
+
+var greetings = map[string]string{
+	SMTPTypeName: "220 mail.example.com ESMTP Postfix",
+	IMAPTypeName: "* OK [CAPABILITY IMAP4rev1] Dovecot ready.",
+	POP3TypeName: "+OK Dovecot ready.",
+}
+
+func mustTarget(t *testing.T, typeName, address, serverName string) targetConfig {
+	t.Helper()
+	target, err := certs.NewTarget(address, serverName, protocols[typeName].defaultPort)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return newTargetConfig(0, target)
+}
+
+// --- New() tests ---
+
+func TestNew_Defaults(t *testing.T) {
+	c, err := New(SMTPTypeName, []string{"mail.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Type() != SMTPTypeName {
+		t.Errorf("expected type %q, got %q", SMTPTypeName, c.Type())
+	}
+	if c.timeout != DefaultTimeout || c.ehloName != DefaultEHLOName || c.startTLS {
+		t.Errorf("unexpected defaults: %+v", c)
+	}
+	desc := c.Describe()
+	if len(desc.Metrics) != 1 || desc.Metrics[0].DSName != "addr0" || desc.Metrics[0].Unit != "ms" {
+		t.Errorf("unexpected metrics: %+v", desc.Metrics)
+	}
+}
+
+func TestNew_StartTLSMetrics(t *testing.T) {
+	c, err := New(IMAPTypeName, []string{"mail.example.com"}, WithStartTLS(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := c.Describe().Metrics
+	if len(m) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(m))
+	}
+	if m[1].ResultKey != "mail.example.com:143 cert" || m[1].DSName != "addr0_cert" || m[1].Unit != "days" || !m[1].Signed || m[1].Graph != "cert" {
+		t.Errorf("unexpected cert metric: %+v", m[1])
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tc := []string{"mail.example.com"}
+	tests := []struct {
+		name     string
+		typeName string
+		targets  []string
+		opts     []Option
+	}{
+		{"unknown protocol", "nntp", tc, nil},
+		{"no targets", POP3TypeName, nil, nil},
+		{"missing host", POP3TypeName, []string{":110"}, nil},
+		{"bad banner", POP3TypeName, tc, []Option{WithBanner("(")}},
+		{"ehlo for pop3", POP3TypeName, tc, []Option{WithEHLOName("probe.example.com")}},
+		{"warning below critical", POP3TypeName, tc, []Option{WithWarningDays(3), WithCriticalDays(10)}},
+		{"zero timeout", POP3TypeName, tc, []Option{WithTimeout(0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.typeName, tt.targets, tt.opts...); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestNewTargetConfig_DefaultPorts(t *testing.T) {
+	tests := []struct {
+		typeName, address, want string
+	}{
+		{SMTPTypeName, "mail.example.com", "mail.example.com:25"},
+		{SMTPTypeName, "mail.example.com:587", "mail.example.com:587"},
+		{IMAPTypeName, "mail.example.com", "mail.example.com:143"},
+		{POP3TypeName, "mail.example.com", "mail.example.com:110"},
+		{POP3TypeName, "[2001:db8::1]", "[2001:db8::1]:110"},
+	}
+	for _, tt := range tests {
+		tc := mustTarget(t, tt.typeName, tt.address, "")
+		if tc.address != tt.want {
+			t.Errorf("%s %s: expected %q, got %q", tt.typeName, tt.address, tt.want, tc.address)
+		}
+	}
+	if tc := mustTarget(t, IMAPTypeName, "10.0.0.5", "mail.example.com"); tc.serverName != "mail.example.com" || tc.label != "10.0.0.5:143 (mail.example.com)" {
+		t.Errorf("unexpected server name override: %+v", tc)
+	}
+}
+
+// --- Run() tests ---
+
+func TestRun_Handshake(t *testing.T) {
+	for _, proto := range []string{SMTPTypeName, IMAPTypeName, POP3TypeName} {
+		t.Run(proto, func(t *testing.T) {
+			addr := (&fakeServer{proto: proto, greeting: greetings[proto]}).start(t)
+			c, err := New(proto, []string{addr}, WithBanner("(?i)(postfix|dovecot)"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result := c.Run(context.Background())
+			if !result.Success {
+				t.Fatalf("expected success, got %v", result.Err)
+			}
+			if v := result.Metrics[addr]; v == nil || *v < 0 {
+				t.Errorf("expected handshake time, got %v", v)
+			}
+		})
+	}
+}
+
+func TestRun_StartTLS(t *testing.T) {
+	cert, pool := newCert(t, 90*day)
+	for _, proto := range []string{SMTPTypeName, IMAPTypeName, POP3TypeName} {
+		t.Run(proto, func(t *testing.T) {
+			addr := (&fakeServer{proto: proto, greeting: greetings[proto], cert: &cert}).start(t)
+			c, _ := New(proto, []string{addr}, WithStartTLS(true), WithRootCAs(pool))
+			result := c.Run(context.Background())
+			if !result.Success {
+				t.Fatalf("expected success, got %v", result.Err)
+			}
+			if result.Degraded {
+				t.Error("expected not degraded")
+			}
+			if result.Metrics[addr] == nil {
+				t.Error("expected handshake time")
+			}
+			if v := result.Metrics[addr+" cert"]; v == nil || *v != 89 {
+				t.Errorf("expected 89 days, got %v", v)
+			}
+		})
+	}
+}
+
+func TestRun_CertificateThresholds(t *testing.T) {
+	tests := []struct {
+		name         string
+		validFor     time.Duration
+		wantSuccess  bool
+		wantDegraded bool
+	}{
+		{"healthy", 60 * day, true, false},
+		{"warning", 10 * day, true, true},
+		{"critical", 3 * day, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, pool := newCert(t, tt.validFor)
+			addr := (&fakeServer{proto: SMTPTypeName, greeting: greetings[SMTPTypeName], cert: &cert}).start(t)
+			c, _ := New(SMTPTypeName, []string{addr}, WithStartTLS(true), WithRootCAs(pool))
+			result := c.Run(context.Background())
+			if result.Success != tt.wantSuccess || result.Degraded != tt.wantDegraded {
+				t.Errorf("expected success=%v degraded=%v, got success=%v degraded=%v (%v)",
+					tt.wantSuccess, tt.wantDegraded, result.Success, result.Degraded, result.Err)
+			}
+			if result.Metrics[addr+" cert"] == nil {
+				t.Error("expected certificate days to be recorded")
+			}
+		})
+	}
+}
+
+func TestRun_UntrustedCertificate(t *testing.T) {
+	cert, _ := newCert(t, 90*day)
+	addr := (&fakeServer{proto: SMTPTypeName, greeting: greetings[SMTPTypeName], cert: &cert}).start(t)
+
+	c, _ := New(SMTPTypeName, []string{addr}, WithStartTLS(true))
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Fatal("expected failure for untrusted certificate")
+	}
+	if !strings.Contains(result.Err.Error(), "verification failed") {
+		t.Errorf("unexpected error: %v", result.Err)
+	}
+	if result.Metrics[addr] == nil || result.Metrics[addr+" cert"] == nil {
+		t.Error("expected metrics to be recorded despite verification failure")
+	}
+
+	c, _ = New(SMTPTypeName, []string{addr}, WithStartTLS(true), WithSkipVerify(true))
+	if result := c.Run(context.Background()); !result.Success {
+		t.Errorf("expected success with skip_verify, got %v", result.Err)
+	}
+}
+
+func TestRun_HostnameMismatch(t *testing.T) {
+	cert, pool := newCert(t, 90*day)
+	addr := (&fakeServer{proto: IMAPTypeName, greeting: greetings[IMAPTypeName], cert: &cert}).start(t)
+
+	c, _ := newCheck(IMAPTypeName, []targetConfig{mustTarget(t, IMAPTypeName, addr, "imap.other.example")}, WithStartTLS(true), WithRootCAs(pool))
+	if result := c.Run(context.Background()); result.Success {
+		t.Error("expected failure for hostname mismatch")
+	}
+
+	c, _ = newCheck(IMAPTypeName, []targetConfig{mustTarget(t, IMAPTypeName, addr, "mail.example.com")}, WithStartTLS(true), WithRootCAs(pool))
+	if result := c.Run(context.Background()); !result.Success {
+		t.Errorf("expected success with matching server_name, got %v", result.Err)
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	cert, pool := newCert(t, 90*day)
+	tests := []struct {
+		name     string
+		server   fakeServer
+		opts     []Option
+		errMsg   string
+		recorded bool // handshake time recorded despite the failure
+	}{
+		{"banner mismatch", fakeServer{proto: SMTPTypeName, greeting: greetings[SMTPTypeName]},
+			[]Option{WithBanner("Exim")}, "does not match", true},
+		{"smtp service unavailable", fakeServer{proto: SMTPTypeName, greeting: "554 no service"},
+			nil, "greeting", false},
+		{"imap bye", fakeServer{proto: IMAPTypeName, greeting: "* BYE too many connections"},
+			nil, "greeting", false},
+		{"pop3 err", fakeServer{proto: POP3TypeName, greeting: "-ERR go away"},
+			nil, "greeting", false},
+		{"hung server", fakeServer{proto: SMTPTypeName},
+			[]Option{WithTimeout(300 * time.Millisecond)}, "greeting", false},
+		{"starttls not offered", fakeServer{proto: POP3TypeName, greeting: greetings[POP3TypeName]},
+			[]Option{WithStartTLS(true)}, "does not advertise STLS", false},
+		{"starttls refused", fakeServer{proto: IMAPTypeName, greeting: greetings[IMAPTypeName], cert: &cert, rejectTLS: true},
+			[]Option{WithStartTLS(true), WithRootCAs(pool)}, "TLS not available", false},
+		{"plaintext injection", fakeServer{proto: SMTPTypeName, greeting: greetings[SMTPTypeName], cert: &cert, inject: true},
+			[]Option{WithStartTLS(true), WithRootCAs(pool)}, "before the TLS handshake", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := tt.server.start(t)
+			c, err := New(tt.server.proto, []string{addr}, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result := c.Run(context.Background())
+			if result.Success {
+				t.Fatal("expected failure")
+			}
+			if !strings.Contains(result.Err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, result.Err)
+			}
+			if got := result.Metrics[addr] != nil; got != tt.recorded {
+				t.Errorf("expected handshake time recorded=%v, got %v", tt.recorded, got)
+			}
+		})
+	}
+}
+
+func TestRun_ConnectionRefused(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	ln.Close()
+
+	c, _ := New(SMTPTypeName, []string{addr}, WithStartTLS(true))
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Fatal("expected failure for refused connection")
+	}
+	for _, key := range []string{addr, addr + " cert"} {
+		if v, ok := result.Metrics[key]; !ok || v != nil {
+			t.Errorf("expected nil metric %q, got %v (present=%v)", key, v, ok)
+		}
+	}
+}
+
+// --- Factory tests ---
+
+func TestFactory_AllOptions(t *testing.T) {
+	cert, _ := newCert(t, day)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Leaf.Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	chk, err := SMTPFactory(map[string]any{
+		"targets": []any{
+			"mx1.example.com",
+			map[string]any{"address": "10.0.0.5:587", "server_name": "mx.example.com"},
+		},
+		"timeout":       "3s",
+		"starttls":      true,
+		"banner":        "^220 .*ESMTP",
+		"ehlo_name":     "monitor.example.com",
+		"warning_days":  float64(30),
+		"critical_days": float64(10),
+		"ca_file":       caFile,
+		"skip_verify":   false,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := chk.(*Check)
+	if c.timeout != 3*time.Second || !c.startTLS || c.banner == nil || c.ehloName != "monitor.example.com" ||
+		c.policy.WarningDays != 30 || c.policy.CriticalDays != 10 || c.policy.Roots == nil || c.skipVerify {
+		t.Errorf("options not applied: %+v", c)
+	}
+	if len(c.Describe().Metrics) != 4 {
+		t.Errorf("expected 4 metrics, got %d", len(c.Describe().Metrics))
+	}
+	if c.targets[1].address != "10.0.0.5:587" || c.targets[1].serverName != "mx.example.com" {
+		t.Errorf("unexpected target: %+v", c.targets[1])
+	}
+}
+
+func TestFactory_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		factory check.Factory
+		config  map[string]any
+	}{
+		{"missing targets", SMTPFactory, map[string]any{}},
+		{"targets not list", SMTPFactory, map[string]any{"targets": "mx.example.com"}},
+		{"empty targets", IMAPFactory, map[string]any{"targets": []any{}}},
+		{"target wrong type", IMAPFactory, map[string]any{"targets": []any{float64(1)}}},
+		{"object without address", POP3Factory, map[string]any{"targets": []any{map[string]any{}}}},
+		{"empty server_name", POP3Factory, map[string]any{"targets": []any{map[string]any{"address": "a", "server_name": ""}}}},
+		{"bad timeout", SMTPFactory, map[string]any{"targets": []any{"a"}, "timeout": "soon"}},
+		{"starttls not bool", SMTPFactory, map[string]any{"targets": []any{"a"}, "starttls": "yes"}},
+		{"bad banner", SMTPFactory, map[string]any{"targets": []any{"a"}, "banner": "["}},
+		{"ehlo_name for imap", IMAPFactory, map[string]any{"targets": []any{"a"}, "ehlo_name": "x"}},
+		{"warning_days not number", SMTPFactory, map[string]any{"targets": []any{"a"}, "warning_days": "30"}},
+		{"missing ca_file", SMTPFactory, map[string]any{"targets": []any{"a"}, "ca_file": "/nonexistent/ca.pem"}},
+		{"skip_verify not bool", SMTPFactory, map[string]any{"targets": []any{"a"}, "skip_verify": "true"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.factory(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestRegistryIntegration(t *testing.T) {
+	reg := check.NewRegistry()
+	for name, f := range map[string]check.Factory{
+		SMTPTypeName: SMTPFactory,
+		IMAPTypeName: IMAPFactory,
+		POP3TypeName: POP3Factory,
+	} {
+		if err := reg.Register(name, f); err != nil {
+			t.Fatalf("register %s: %v", name, err)
+		}
+		chk, err := reg.Create(name, map[string]any{"targets": []any{"mail.example.com"}})
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if chk.Type() != name {
+			t.Errorf("expected type %q, got %q", name, chk.Type())
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// maxLineBytes bounds a single protocol line; RFC 5321 allows 512 for SMTP
+// replies, IMAP capability lines can be longer.
+const maxLineBytes = 8192
+
+// session is an open connection speaking a line-based mail protocol.
+type session struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int // last IMAP command tag issued
+}
+
+func newSession(conn net.Conn) *session {
+	return &session{conn: conn, r: bufio.NewReaderSize(conn, maxLineBytes)}
+}
+
+// readLine reads one line, without its line ending.
+func (s *session) readLine() (string, error) {
+	line, err := s.r.ReadSlice('\n')
+	if err != nil {
+		switch {
+		case errors.Is(err, bufio.ErrBufferFull):
+			return "", fmt.Errorf("line longer than %d bytes", maxLineBytes)
+		case errors.Is(err, io.EOF):
+			return "", fmt.Errorf("connection closed by server")
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// writeLine sends one CRLF-terminated command line.
+func (s *session) writeLine(line string) error {
+	_, err := io.WriteString(s.conn, line+"\r\n")
+	return err
+}
+
+// upgrade performs the TLS handshake after the server has accepted
+// STARTTLS and switches the session to the encrypted connection.
+func (s *session) upgrade(ctx context.Context, config *tls.Config) (tls.ConnectionState, error) {
+	// Anything already buffered was sent in plaintext before the handshake
+	// and would be mistaken for a reply over TLS (CVE-2011-0411).
+	if s.r.Buffered() > 0 {
+		return tls.ConnectionState{}, fmt.Errorf("server sent data before the TLS handshake")
+	}
+	tc := tls.Client(s.conn, config)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, fmt.Errorf("TLS handshake: %w", err)
+	}
+	s.conn = tc
+	s.r = bufio.NewReaderSize(tc, maxLineBytes)
+	return tc.ConnectionState(), nil
+}
+
+// dialect implements the handshake commands of one mail protocol.
+type dialect interface {
+	// greet reads the server greeting and returns it as sent, minus line
+	// endings (multi-line greetings are joined with "\n").
+	greet(s *session) (string, error)
+
+	// capabilities asks for and returns the server's capabilities, one
+	// per entry, each starting with its keyword.
+	capabilities(s *session) ([]string, error)
+
+	// startTLS issues the STARTTLS command and reads the go-ahead.
+	startTLS(s *session) error
+
+	// quit ends the session politely.
+	quit(s *session)
+}
+
+// hasCapability reports whether caps advertises keyword, compared case
+// insensitively.
+func hasCapability(caps []string, keyword string) bool {
+	for _, c := range caps {
+		if f := strings.Fields(c); len(f) > 0 && strings.EqualFold(f[0], keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// smtpDialect speaks ESMTP (RFC 5321, STARTTLS per RFC 3207).
+type smtpDialect struct {
+	ehloName string
+}
+
+// smtpReply reads a possibly multi-line reply and returns its code and
+// raw lines.
+func smtpReply(s *session) (int, []string, error) {
+	var lines []string
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return 0, lines, err
+		}
+		if len(line) < 3 || (len(line) > 3 && line[3] != ' ' && line[3] != '-') {
+			return 0, lines, fmt.Errorf("malformed reply %q", line)
+		}
+		code, err := strconv.Atoi(line[:3])
+		if err != nil {
+			return 0, lines, fmt.Errorf("malformed reply %q", line)
+		}
+		lines = append(lines, line)
+		if len(line) == 3 || line[3] == ' ' {
+			return code, lines, nil
+		}
+	}
+}
+
+// expect reads a reply and fails unless it has the wanted code.
+func (smtpDialect) expect(s *session, want int) ([]string, error) {
+	code, lines, err := smtpReply(s)
+	if err != nil {
+		return nil, err
+	}
+	if code != want {
+		return nil, fmt.Errorf("unexpected reply %q", strings.Join(lines, "\n"))
+	}
+	return lines, nil
+}
+
+func (d smtpDialect) greet(s *session) (string, error) {
+	lines, err := d.expect(s, 220)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (d smtpDialect) capabilities(s *session) ([]string, error) {
+	if err := s.writeLine("EHLO " + d.ehloName); err != nil {
+		return nil, err
+	}
+	lines, err := d.expect(s, 250)
+	if err != nil {
+		return nil, fmt.Errorf("EHLO: %w", err)
+	}
+	// The first line is the server's hello; extensions follow.
+	caps := make([]string, 0, len(lines)-1)
+	for _, l := range lines[1:] {
+		if len(l) > 4 {
+			caps = append(caps, l[4:])
+		}
+	}
+	return caps, nil
+}
+
+func (d smtpDialect) startTLS(s *session) error {
+	if err := s.writeLine("STARTTLS"); err != nil {
+		return err
+	}
+	_, err := d.expect(s, 220)
+	return err
+}
+
+func (smtpDialect) quit(s *session) {
+	if s.writeLine("QUIT") == nil {
+		_, _, _ = smtpReply(s)
+	}
+}
+
+// imapDialect speaks IMAP4rev1 (RFC 3501).
+type imapDialect struct{}
+
+// command sends a tagged command and returns its untagged responses. A
+// tagged NO or BAD is an error.
+func (imapDialect) command(s *session, cmd string) ([]string, error) {
+	s.tag++
+	tag := "a" + strconv.Itoa(s.tag)
+	if err := s.writeLine(tag + " " + cmd); err != nil {
+		return nil, err
+	}
+	var untagged []string
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if rest, ok := strings.CutPrefix(line, tag+" "); ok {
+			if status, _, _ := strings.Cut(rest, " "); !strings.EqualFold(status, "OK") {
+				return nil, fmt.Errorf("%s: %s", cmd, rest)
+			}
+			return untagged, nil
+		}
+		if !strings.HasPrefix(line, "* ") {
+			return nil, fmt.Errorf("%s: unexpected response %q", cmd, line)
+		}
+		untagged = append(untagged, line[2:])
+	}
+}
+
+func (imapDialect) greet(s *session) (string, error) {
+	line, err := s.readLine()
+	if err != nil {
+		return "", err
+	}
+	status, _, _ := strings.Cut(strings.TrimPrefix(line, "* "), " ")
+	if !strings.HasPrefix(line, "* ") || (!strings.EqualFold(status, "OK") && !strings.EqualFold(status, "PREAUTH")) {
+		return "", fmt.Errorf("unexpected greeting %q", line)
+	}
+	return line, nil
+}
+
+func (d imapDialect) capabilities(s *session) ([]string, error) {
+	untagged, err := d.command(s, "CAPABILITY")
+	if err != nil {
+		return nil, err
+	}
+	var caps []string
+	for _, u := range untagged {
+		f := strings.Fields(u)
+		if len(f) > 0 && strings.EqualFold(f[0], "CAPABILITY") {
+			caps = append(caps, f[1:]...)
+		}
+	}
+	return caps, nil
+}
+
+func (d imapDialect) startTLS(s *session) error {
+	_, err := d.command(s, "STARTTLS")
+	return err
+}
+
+func (d imapDialect) quit(s *session) {
+	_, _ = d.command(s, "LOGOUT")
+}
+
+// pop3Dialect speaks POP3 (RFC 1939, CAPA and STLS per RFC 2449/2595).
+type pop3Dialect struct{}
+
+// status reads a single-line +OK/-ERR response.
+func (pop3Dialect) status(s *session) (string, error) {
+	line, err := s.readLine()
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return "", fmt.Errorf("unexpected response %q", line)
+	}
+	return line, nil
+}
+
+func (d pop3Dialect) greet(s *session) (string, error) {
+	return d.status(s)
+}
+
+func (d pop3Dialect) capabilities(s *session) ([]string, error) {
+	if err := s.writeLine("CAPA"); err != nil {
+		return nil, err
+	}
+	line, err := s.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(line, "-ERR") {
+		// A server predating RFC 2449 advertises nothing.
+		return nil, nil
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return nil, fmt.Errorf("CAPA: unexpected response %q", line)
+	}
+	var caps []string
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "." {
+			return caps, nil
+		}
+		caps = append(caps, strings.TrimPrefix(line, "."))
+	}
+}
+
+func (d pop3Dialect) startTLS(s *session) error {
+	if err := s.writeLine("STLS"); err != nil {
+		return err
+	}
+	_, err := d.status(s)
+	return err
+}
+
+func (pop3Dialect) quit(s *session) {
+	if s.writeLine("QUIT") == nil {
+		_, _ = s.readLine()
+	}
+}
@@ -0,0 +1,96 @@
+package mail
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+// scriptedSession returns a session over a pipe whose server end sends
+// output, discarding the client's commands, and then hangs up.
+func scriptedSession(t *testing.T, output string) *session {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close(); server.Close() })
+	go func() {
+		_, _ = server.Write([]byte(output))
+		server.Close()
+	}()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			if _, err := server.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	return newSession(client)
+}
+
+func TestSMTPReply(t *testing.T) {
+	s := scriptedSession(t, "250-mx.example.com\r\n250-SIZE 1000\r\n250 STARTTLS\r\n")
+	code, lines, err := smtpReply(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code != 250 || len(lines) != 3 {
+		t.Errorf("expected 250 with 3 lines, got %d %v", code, lines)
+	}
+
+	for _, bad := range []string{"25\r\n", "abc hello\r\n", "250Xhello\r\n", "250-unterminated\r\n"} {
+		if _, _, err := smtpReply(scriptedSession(t, bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestSMTPCapabilities(t *testing.T) {
+	s := scriptedSession(t, "250-mx.example.com Hello\r\n250-starttls\r\n250 SIZE 1000\r\n")
+	caps, err := smtpDialect{ehloName: "localhost"}.capabilities(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hasCapability(caps, "STARTTLS") || !hasCapability(caps, "size") || hasCapability(caps, "mx.example.com") {
+		t.Errorf("unexpected capabilities %q", caps)
+	}
+}
+
+func TestIMAPCommand(t *testing.T) {
+	s := scriptedSession(t, "* CAPABILITY IMAP4rev1 STARTTLS\r\n* CAPABILITY AUTH=PLAIN\r\na1 OK done\r\n")
+	caps, err := imapDialect{}.capabilities(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(caps) != 3 || !hasCapability(caps, "STARTTLS") {
+		t.Errorf("unexpected capabilities %q", caps)
+	}
+
+	s = scriptedSession(t, "a1 BAD unknown command\r\n")
+	if _, err := (imapDialect{}).command(s, "STARTTLS"); err == nil || !strings.Contains(err.Error(), "BAD") {
+		t.Errorf("expected BAD error, got %v", err)
+	}
+}
+
+func TestPOP3Capabilities(t *testing.T) {
+	s := scriptedSession(t, "+OK\r\nUSER\r\nSTLS\r\n..dotted\r\n.\r\n")
+	caps, err := pop3Dialect{}.capabilities(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(caps) != 3 || caps[2] != ".dotted" || !hasCapability(caps, "STLS") {
+		t.Errorf("unexpected capabilities %q", caps)
+	}
+
+	// Servers without CAPA advertise nothing.
+	caps, err = pop3Dialect{}.capabilities(scriptedSession(t, "-ERR unknown command\r\n"))
+	if err != nil || len(caps) != 0 {
+		t.Errorf("expected no capabilities, got %q, %v", caps, err)
+	}
+}
+
+func TestReadLine_TooLong(t *testing.T) {
+	s := scriptedSession(t, strings.Repeat("x", maxLineBytes+1)+"\r\n")
+	if _, err := s.readLine(); err == nil {
+		t.Error("expected error for overlong line")
+	}
+}
@@ -0,0 +1,344 @@
+// Package ntp implements an NTP check that sends SNTP queries to one or
+// more servers and reports the local clock's offset from each, the round
+// trip delay and the server's stratum.
+//
+// The offset is signed: positive when the local clock is behind the
+// server. An offset beyond the critical threshold fails the check and one
+// beyond the warning threshold marks it degraded, so drift is caught
+// before it breaks Kerberos or TLS. Servers that are unsynchronized or
+// answer with a kiss-of-death packet fail the check.
+package ntp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+)
+
+const (
+	// TypeName is the registered name for this check type.
+	TypeName = "ntp"
+
+	// DefaultTimeout is the default per-server query timeout.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultPort is used for server addresses without a port.
+	DefaultPort = "123"
+
+	// DefaultWarningOffset is the default absolute offset above which the
+	// check is marked degraded.
+	DefaultWarningOffset = 100 * time.Millisecond
+
+	// DefaultCriticalOffset is the default absolute offset above which the
+	// check fails.
+	DefaultCriticalOffset = time.Second
+)
+
+// serverConfig maps a single NTP server to its RRD data sources.
+type serverConfig struct {
+	address string // host:port to query
+	prefix  string // prefix for RRD DS names (e.g. "s0")
+}
+
+// offsetResultKey returns the Result.Metrics key for a server's offset.
+func offsetResultKey(s serverConfig) string {
+	return s.address + " offset"
+}
+
+// delayResultKey returns the Result.Metrics key for a server's round-trip
+// delay.
+func delayResultKey(s serverConfig) string {
+	return s.address + " delay"
+}
+
+// stratumResultKey returns the Result.Metrics key for a server's stratum.
+func stratumResultKey(s serverConfig) string {
+	return s.address + " stratum"
+}
+
+// Check implements check.Check by querying NTP servers.
+type Check struct {
+	servers        []serverConfig
+	timeout        time.Duration
+	warningOffset  time.Duration
+	criticalOffset time.Duration
+	dialer         *net.Dialer
+	desc           check.Descriptor
+}
+
+// Option is a functional option for configuring an NTP Check.
+type Option func(*Check) error
+
+// WithTimeout sets the per-server query timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Check) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout must be positive, got %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithWarningOffset sets the absolute offset above which the check is
+// marked degraded.
+func WithWarningOffset(d time.Duration) Option {
+	return func(c *Check) error {
+		if d <= 0 {
+			return fmt.Errorf("warning_offset must be positive, got %v", d)
+		}
+		c.warningOffset = d
+		return nil
+	}
+}
+
+// WithCriticalOffset sets the absolute offset above which the check fails.
+func WithCriticalOffset(d time.Duration) Option {
+	return func(c *Check) error {
+		if d <= 0 {
+			return fmt.Errorf("critical_offset must be positive, got %v", d)
+		}
+		c.criticalOffset = d
+		return nil
+	}
+}
+
+// New creates an NTP Check for the given servers. An address without a
+// port uses port 123.
+func New(servers []string, opts ...Option) (*Check, error) {
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("ntp: at least one server is required")
+	}
+
+	cfgs := make([]serverConfig, len(servers))
+	for i, s := range servers {
+		if s == "" {
+			return nil, fmt.Errorf("ntp: server at index %d must not be empty", i)
+		}
+		if _, _, err := net.SplitHostPort(s); err != nil {
+			s = net.JoinHostPort(s, DefaultPort)
+		}
+		cfgs[i] = serverConfig{
+			address: s,
+			prefix:  fmt.Sprintf("s%d", i),
+		}
+	}
+
+	c := &Check{
+		servers:        cfgs,
+		timeout:        DefaultTimeout,
+		warningOffset:  DefaultWarningOffset,
+		criticalOffset: DefaultCriticalOffset,
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("ntp: %w", err)
+		}
+	}
+
+	if c.warningOffset > c.criticalOffset {
+		return nil, fmt.Errorf("ntp: warning_offset (%v) must not exceed critical_offset (%v)", c.warningOffset, c.criticalOffset)
+	}
+
+	c.dialer = &net.Dialer{}
+
+	var metrics []check.MetricDef
+	for _, s := range cfgs {
+		metrics = append(metrics,
+			check.MetricDef{
+				ResultKey: offsetResultKey(s),
+				DSName:    s.prefix + "_offset",
+				Label:     s.address + " offset",
+				Unit:      "ms",
+				Scale:     1000,
+				Signed:    true,
+			},
+			check.MetricDef{
+				ResultKey: delayResultKey(s),
+				DSName:    s.prefix + "_delay",
+				Label:     s.address + " delay",
+				Unit:      "ms",
+				Scale:     1000,
+			},
+			check.MetricDef{
+				ResultKey: stratumResultKey(s),
+				DSName:    s.prefix + "_stratum",
+				Label:     s.address + " stratum",
+				Unit:      "stratum",
+				Scale:     1,
+			},
+		)
+	}
+	c.desc = check.Descriptor{
+		Label:   "ntp",
+		Metrics: metrics,
+	}
+
+	return c, nil
+}
+
+// Type returns the check type name.
+func (c *Check) Type() string {
+	return TypeName
+}
+
+// Describe returns the Descriptor for this check instance.
+// Three metrics are produced per configured server.
+func (c *Check) Describe() check.Descriptor {
+	return c.desc
+}
+
+// Run queries all configured servers and returns a Result. Each server's
+// offset and delay are stored in microseconds, and its stratum as is; all
+// three are nil if the server could not be queried. Offsets are recorded
+// even when they exceed a threshold. Success requires every server to
+// answer with a synchronized clock and an offset within critical_offset;
+// the result is degraded if any offset exceeds warning_offset.
+func (c *Check) Run(ctx context.Context) check.Result {
+	metrics := make(map[string]*float64, len(c.desc.Metrics))
+	var lastErr error
+	degraded := false
+
+	for _, s := range c.servers {
+		offset, delay, stratum, err := c.query(ctx, s.address)
+		if err != nil {
+			lastErr = fmt.Errorf("ntp %s: %w", s.address, err)
+			metrics[offsetResultKey(s)] = nil
+			metrics[delayResultKey(s)] = nil
+			metrics[stratumResultKey(s)] = nil
+			continue
+		}
+
+		o, d, st := microseconds(offset), microseconds(delay), float64(stratum)
+		metrics[offsetResultKey(s)] = &o
+		metrics[delayResultKey(s)] = &d
+		metrics[stratumResultKey(s)] = &st
+
+		abs := offset.Abs()
+		switch {
+		case abs > c.criticalOffset:
+			lastErr = fmt.Errorf("ntp %s: offset %v exceeds critical threshold %v", s.address, offset, c.criticalOffset)
+		case abs > c.warningOffset:
+			degraded = true
+		}
+	}
+
+	return check.Result{
+		Timestamp: time.Now(),
+		Success:   lastErr == nil,
+		Degraded:  degraded,
+		Err:       lastErr,
+		Metrics:   metrics,
+	}
+}
+
+// microseconds returns d in microseconds, keeping sub-microsecond
+// precision.
+func microseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
+
+// query sends one SNTP request to address and returns the clock offset,
+// round-trip delay and server stratum.
+func (c *Check) query(ctx context.Context, address string) (time.Duration, time.Duration, uint8, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	conn, err := c.dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	t1 := time.Now()
+	transmit := toNTP(t1)
+	if _, err := conn.Write(newRequest(transmit)); err != nil {
+		return 0, 0, 0, err
+	}
+
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	// t1 carries a monotonic clock reading, so the measured round trip is
+	// unaffected by the local clock being stepped during the exchange.
+	t4 := time.Now()
+
+	p, err := parsePacket(buf[:n])
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if err := p.validate(transmit); err != nil {
+		return 0, 0, 0, err
+	}
+
+	offset, delay := offsetAndDelay(t1, fromNTP(p.receive), fromNTP(p.transmit), t4)
+	return offset, delay, p.stratum, nil
+}
+
+// Factory creates an NTP Check from a config map.
+// Required keys:
+//   - "servers" (list of strings) — servers as host or host:port (port 123 by default)
+//
+// Optional keys:
+//   - "timeout" (string) — per-server duration string, default "5s"
+//   - "warning_offset" (string) — degrade above this absolute offset, default "100ms"
+//   - "critical_offset" (string) — fail above this absolute offset, default "1s"
+func Factory(config map[string]any) (check.Check, error) {
+	raw, ok := config["servers"]
+	if !ok {
+		return nil, fmt.Errorf("ntp: config missing required key 'servers'")
+	}
+
+	var servers []string
+	switch v := raw.(type) {
+	case []string:
+		servers = v
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("ntp: 'servers' items must be strings, got %T", item)
+			}
+			servers = append(servers, s)
+		}
+	default:
+		return nil, fmt.Errorf("ntp: 'servers' must be a list of strings, got %T", raw)
+	}
+
+	var opts []Option
+
+	durations := []struct {
+		key string
+		opt func(time.Duration) Option
+	}{
+		{"timeout", WithTimeout},
+		{"warning_offset", WithWarningOffset},
+		{"critical_offset", WithCriticalOffset},
+	}
+	for _, d := range durations {
+		v, ok := config[d.key]
+		if !ok {
+			continue
+		}
+		ds, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("ntp: '%s' must be a duration string, got %T", d.key, v)
+		}
+		parsed, err := time.ParseDuration(ds)
+		if err != nil {
+			return nil, fmt.Errorf("ntp: invalid %s %q: %w", d.key, ds, err)
+		}
+		opts = append(opts, d.opt(parsed))
+	}
+
+	return New(servers, opts...)
+}
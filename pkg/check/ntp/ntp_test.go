@@ -0,0 +1,273 @@
+package ntp
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+)
+
+// fakeServer is a minimal SNTP server whose clock runs at offset from the
+// local clock.
+type fakeServer struct {
+	offset  time.Duration
+	stratum uint8
+	leap    uint8
+	kiss    string // reply with a kiss-of-death carrying this code
+	silent  bool   // never reply
+	badEcho bool   // echo the wrong origin timestamp
+}
+
+// start runs the server on a localhost UDP socket and returns its address.
+func (f fakeServer) start(t *testing.T) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if f.silent || n < packetLen {
+				continue
+			}
+			received := time.Now().Add(f.offset)
+
+			resp := make([]byte, packetLen)
+			resp[0] = f.leap<<6 | version<<3 | modeServer
+			resp[1] = f.stratum
+			if f.kiss != "" {
+				resp[1] = 0
+				copy(resp[12:16], f.kiss)
+			}
+			origin := binary.BigEndian.Uint64(buf[40:48])
+			if f.badEcho {
+				origin++
+			}
+			binary.BigEndian.PutUint64(resp[24:], origin)
+			binary.BigEndian.PutUint64(resp[32:], toNTP(received))
+			binary.BigEndian.PutUint64(resp[40:], toNTP(time.Now().Add(f.offset)))
+			_, _ = pc.WriteTo(resp, addr)
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+// --- New() tests ---
+
+func TestNew_Defaults(t *testing.T) {
+	c, err := New([]string{"pool.ntp.org", "10.0.0.1:1123"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Type() != TypeName {
+		t.Errorf("expected type %q, got %q", TypeName, c.Type())
+	}
+	if c.timeout != DefaultTimeout || c.warningOffset != DefaultWarningOffset || c.criticalOffset != DefaultCriticalOffset {
+		t.Errorf("unexpected defaults: %+v", c)
+	}
+	if c.servers[0].address != "pool.ntp.org:123" || c.servers[1].address != "10.0.0.1:1123" {
+		t.Errorf("unexpected addresses: %+v", c.servers)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	c, _ := New([]string{"a.example.com"})
+	m := c.Describe().Metrics
+	if len(m) != 3 {
+		t.Fatalf("expected 3 metrics, got %d", len(m))
+	}
+	want := []struct {
+		key, ds, unit string
+		signed        bool
+	}{
+		{"a.example.com:123 offset", "s0_offset", "ms", true},
+		{"a.example.com:123 delay", "s0_delay", "ms", false},
+		{"a.example.com:123 stratum", "s0_stratum", "stratum", false},
+	}
+	for i, w := range want {
+		if m[i].ResultKey != w.key || m[i].DSName != w.ds || m[i].Unit != w.unit || m[i].Signed != w.signed {
+			t.Errorf("metric %d: unexpected %+v", i, m[i])
+		}
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []string
+		opts    []Option
+	}{
+		{"no servers", nil, nil},
+		{"empty server", []string{""}, nil},
+		{"zero timeout", []string{"a"}, []Option{WithTimeout(0)}},
+		{"negative warning", []string{"a"}, []Option{WithWarningOffset(-time.Second)}},
+		{"warning above critical", []string{"a"}, []Option{WithWarningOffset(2 * time.Second), WithCriticalOffset(time.Second)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.servers, tt.opts...); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+// --- Run() tests ---
+
+func TestRun_Offsets(t *testing.T) {
+	tests := []struct {
+		name         string
+		offset       time.Duration
+		wantSuccess  bool
+		wantDegraded bool
+	}{
+		{"in sync", 0, true, false},
+		{"ahead warning", 300 * time.Millisecond, true, true},
+		{"behind warning", -300 * time.Millisecond, true, true},
+		{"behind critical", -5 * time.Second, false, false},
+		{"ahead critical", 5 * time.Minute, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := fakeServer{offset: tt.offset, stratum: 2}.start(t)
+			c, err := New([]string{addr})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result := c.Run(context.Background())
+			if result.Success != tt.wantSuccess || result.Degraded != tt.wantDegraded {
+				t.Errorf("expected success=%v degraded=%v, got success=%v degraded=%v (%v)",
+					tt.wantSuccess, tt.wantDegraded, result.Success, result.Degraded, result.Err)
+			}
+
+			o := result.Metrics[addr+" offset"]
+			if o == nil {
+				t.Fatal("expected offset to be recorded")
+			}
+			if got := time.Duration(*o) * time.Microsecond; (got - tt.offset).Abs() > 20*time.Millisecond {
+				t.Errorf("expected offset near %v, got %v", tt.offset, got)
+			}
+			if d := result.Metrics[addr+" delay"]; d == nil || *d < 0 {
+				t.Errorf("expected non-negative delay, got %v", d)
+			}
+			if s := result.Metrics[addr+" stratum"]; s == nil || *s != 2 {
+				t.Errorf("expected stratum 2, got %v", s)
+			}
+		})
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	tests := []struct {
+		name   string
+		server fakeServer
+		errMsg string
+	}{
+		{"no reply", fakeServer{silent: true}, "timeout"},
+		{"kiss of death", fakeServer{kiss: "RATE"}, "kiss-of-death"},
+		{"unsynchronized", fakeServer{stratum: 3, leap: leapUnsynchronized}, "not synchronized"},
+		{"stratum 16", fakeServer{stratum: 16}, "not synchronized"},
+		{"spoofed reply", fakeServer{stratum: 2, badEcho: true}, "does not match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := tt.server.start(t)
+			c, _ := New([]string{addr}, WithTimeout(300*time.Millisecond))
+			result := c.Run(context.Background())
+			if result.Success {
+				t.Fatal("expected failure")
+			}
+			if !strings.Contains(result.Err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, result.Err)
+			}
+			for _, key := range []string{" offset", " delay", " stratum"} {
+				if v, ok := result.Metrics[addr+key]; !ok || v != nil {
+					t.Errorf("expected nil %s metric, got %v (present=%v)", key, v, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_MultipleServers(t *testing.T) {
+	good := fakeServer{stratum: 1}.start(t)
+	bad := fakeServer{silent: true}.start(t)
+	c, _ := New([]string{good, bad}, WithTimeout(300*time.Millisecond))
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Error("expected failure when one server does not answer")
+	}
+	if result.Metrics[good+" offset"] == nil {
+		t.Error("expected the answering server to be recorded")
+	}
+	if !strings.Contains(result.Err.Error(), bad) {
+		t.Errorf("expected error to name %s, got %v", bad, result.Err)
+	}
+}
+
+// --- Factory tests ---
+
+func TestFactory_AllOptions(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"servers":         []any{"0.pool.ntp.org", "1.pool.ntp.org"},
+		"timeout":         "2s",
+		"warning_offset":  "50ms",
+		"critical_offset": "500ms",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := chk.(*Check)
+	if c.timeout != 2*time.Second || c.warningOffset != 50*time.Millisecond || c.criticalOffset != 500*time.Millisecond {
+		t.Errorf("options not applied: %+v", c)
+	}
+	if len(c.Describe().Metrics) != 6 {
+		t.Errorf("expected 6 metrics, got %d", len(c.Describe().Metrics))
+	}
+}
+
+func TestFactory_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{"missing servers", map[string]any{}},
+		{"servers wrong type", map[string]any{"servers": "pool.ntp.org"}},
+		{"server not string", map[string]any{"servers": []any{float64(1)}}},
+		{"empty servers", map[string]any{"servers": []any{}}},
+		{"timeout not string", map[string]any{"servers": []any{"a"}, "timeout": float64(5)}},
+		{"bad warning_offset", map[string]any{"servers": []any{"a"}, "warning_offset": "soon"}},
+		{"warning above critical", map[string]any{"servers": []any{"a"}, "warning_offset": "2s", "critical_offset": "1s"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Factory(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestRegistryIntegration(t *testing.T) {
+	reg := check.NewRegistry()
+	if err := reg.Register(TypeName, Factory); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	chk, err := reg.Create(TypeName, map[string]any{"servers": []any{"pool.ntp.org"}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if chk.Type() != TypeName {
+		t.Errorf("expected type %q, got %q", TypeName, chk.Type())
+	}
+}
@@ -0,0 +1,115 @@
+package ntp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// packetLen is the length of an NTP packet without extensions or MAC.
+const packetLen = 48
+
+// Header field values (RFC 5905 section 7.3).
+const (
+	version    = 4
+	modeClient = 3
+	modeServer = 4
+
+	leapUnsynchronized = 3
+	stratumUnsynced    = 16
+)
+
+// ntpEpoch is the NTP prime epoch, 1900-01-01 00:00:00 UTC.
+var ntpEpoch = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// eraSeconds is the length of one NTP era (2^32 seconds, about 136 years).
+const eraSeconds = 1 << 32
+
+// toNTP converts t to a 64-bit NTP timestamp (32.32 fixed point seconds).
+func toNTP(t time.Time) uint64 {
+	d := t.Sub(ntpEpoch)
+	secs := uint64(d / time.Second)
+	frac := (uint64(d%time.Second) << 32) / uint64(time.Second)
+	return secs<<32 | frac
+}
+
+// fromNTP converts a 64-bit NTP timestamp to a time. Per RFC 4330 section
+// 3, a timestamp with the high bit clear is taken to be in era 1, which
+// starts in 2036.
+func fromNTP(ts uint64) time.Time {
+	secs := int64(ts >> 32)
+	if secs&(1<<31) == 0 {
+		secs += eraSeconds
+	}
+	nanos := int64((ts & 0xffffffff) * uint64(time.Second) >> 32)
+	return ntpEpoch.Add(time.Duration(secs) * time.Second).Add(time.Duration(nanos))
+}
+
+// packet holds the fields of an NTP packet used by the check.
+type packet struct {
+	leap     uint8
+	version  uint8
+	mode     uint8
+	stratum  uint8
+	refID    [4]byte
+	origin   uint64 // T1 as echoed by the server
+	receive  uint64 // T2, server receive time
+	transmit uint64 // T3, server transmit time
+}
+
+// newRequest returns a client request carrying transmit as its transmit
+// timestamp, which the server echoes back as the origin timestamp.
+func newRequest(transmit uint64) []byte {
+	b := make([]byte, packetLen)
+	b[0] = version<<3 | modeClient
+	binary.BigEndian.PutUint64(b[40:], transmit)
+	return b
+}
+
+// parsePacket decodes the fixed header of an NTP packet.
+func parsePacket(b []byte) (packet, error) {
+	if len(b) < packetLen {
+		return packet{}, fmt.Errorf("short response: %d bytes", len(b))
+	}
+	p := packet{
+		leap:     b[0] >> 6,
+		version:  b[0] >> 3 & 0x7,
+		mode:     b[0] & 0x7,
+		stratum:  b[1],
+		origin:   binary.BigEndian.Uint64(b[24:]),
+		receive:  binary.BigEndian.Uint64(b[32:]),
+		transmit: binary.BigEndian.Uint64(b[40:]),
+	}
+	copy(p.refID[:], b[12:16])
+	return p, nil
+}
+
+// validate checks a server response to the request carrying transmit.
+func (p packet) validate(transmit uint64) error {
+	if p.mode != modeServer {
+		return fmt.Errorf("unexpected mode %d in response", p.mode)
+	}
+	if p.origin != transmit {
+		return fmt.Errorf("response does not match request")
+	}
+	if p.stratum == 0 {
+		// A kiss-of-death packet; the reference ID holds the ASCII code.
+		return fmt.Errorf("kiss-of-death %q", string(p.refID[:]))
+	}
+	if p.leap == leapUnsynchronized || p.stratum >= stratumUnsynced {
+		return fmt.Errorf("server clock is not synchronized")
+	}
+	if p.transmit == 0 {
+		return fmt.Errorf("response has no transmit timestamp")
+	}
+	return nil
+}
+
+// offsetAndDelay computes the clock offset and round-trip delay from the
+// four timestamps of an exchange (RFC 5905 section 8): t1 request sent,
+// t2 request received, t3 response sent, t4 response received.
+func offsetAndDelay(t1, t2, t3, t4 time.Time) (offset, delay time.Duration) {
+	offset = (t2.Sub(t1) + t3.Sub(t4)) / 2
+	delay = t4.Sub(t1) - t3.Sub(t2)
+	return offset, max(delay, 0)
+}
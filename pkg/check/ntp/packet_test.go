@@ -0,0 +1,95 @@
+package ntp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNTPTimestampRoundTrip(t *testing.T) {
+	for _, want := range []time.Time{
+		time.Date(2026, 10, 16, 12, 30, 45, 123456789, time.UTC),
+		time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		// After the era rollover on 2036-02-07.
+		time.Date(2040, 6, 1, 8, 0, 0, 500000000, time.UTC),
+	} {
+		got := fromNTP(toNTP(want))
+		if d := got.Sub(want).Abs(); d > time.Microsecond {
+			t.Errorf("round trip of %v gave %v (off by %v)", want, got, d)
+		}
+	}
+}
+
+func TestToNTP_Epoch(t *testing.T) {
+	// 1970-01-01 is 2208988800 seconds after the NTP epoch.
+	if got := toNTP(time.Unix(0, 0)) >> 32; got != 2208988800 {
+		t.Errorf("expected 2208988800, got %d", got)
+	}
+	// Half a second is 2^31 in the fraction.
+	if got := toNTP(time.Unix(0, 500000000)) & 0xffffffff; got != 1<<31 {
+		t.Errorf("expected fraction %d, got %d", uint64(1<<31), got)
+	}
+}
+
+func TestParsePacket(t *testing.T) {
+	b := make([]byte, packetLen)
+	b[0] = 0<<6 | 4<<3 | modeServer
+	b[1] = 2
+	copy(b[12:], "GPS\x00")
+	req := newRequest(42)
+	copy(b[24:32], req[40:48])
+
+	p, err := parsePacket(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.leap != 0 || p.version != 4 || p.mode != modeServer || p.stratum != 2 || p.origin != 42 {
+		t.Errorf("unexpected packet %+v", p)
+	}
+
+	if _, err := parsePacket(b[:47]); err == nil {
+		t.Error("expected error for short packet")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	good := packet{mode: modeServer, stratum: 2, origin: 7, transmit: 9}
+	if err := good.validate(7); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		p    packet
+	}{
+		{"client mode", packet{mode: modeClient, stratum: 2, origin: 7, transmit: 9}},
+		{"wrong origin", packet{mode: modeServer, stratum: 2, origin: 8, transmit: 9}},
+		{"kiss of death", packet{mode: modeServer, stratum: 0, origin: 7, transmit: 9, refID: [4]byte{'R', 'A', 'T', 'E'}}},
+		{"leap unsynchronized", packet{leap: leapUnsynchronized, mode: modeServer, stratum: 2, origin: 7, transmit: 9}},
+		{"stratum 16", packet{mode: modeServer, stratum: 16, origin: 7, transmit: 9}},
+		{"no transmit", packet{mode: modeServer, stratum: 2, origin: 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.p.validate(7); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestOffsetAndDelay(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ms := time.Millisecond
+
+	// Server 50ms ahead, 10ms each way, 2ms processing.
+	offset, delay := offsetAndDelay(base, base.Add(60*ms), base.Add(62*ms), base.Add(22*ms))
+	if offset != 50*ms || delay != 20*ms {
+		t.Errorf("expected offset 50ms delay 20ms, got %v %v", offset, delay)
+	}
+
+	// Server 30ms behind.
+	offset, _ = offsetAndDelay(base, base.Add(-20*ms), base.Add(-20*ms), base.Add(20*ms))
+	if offset != -30*ms {
+		t.Errorf("expected offset -30ms, got %v", offset)
+	}
+}
@@ -0,0 +1,96 @@
+package ping
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// probeExec shells out to the system ping command and parses its summary.
+// It is the fallback when no ICMP socket can be opened.
+func probeExec(ctx context.Context, address string, count int, timeout time.Duration) (probeResult, error) {
+	timeoutSec := fmt.Sprintf("%.0f", timeout.Seconds())
+	cmd := exec.CommandContext(ctx, "ping", "-c", strconv.Itoa(count), "-W", timeoutSec, address)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	// ping exits non-zero when no replies arrive, but its summary still
+	// reports the loss.
+	runErr := cmd.Run()
+	pr, err := parseOutput(out.String())
+	if err != nil {
+		if runErr != nil {
+			return probeResult{}, runErr
+		}
+		return probeResult{}, err
+	}
+	return pr, nil
+}
+
+// parseOutput extracts packet counts and round-trip statistics from ping
+// command output. It understands the iputils ("rtt min/avg/max/mdev"),
+// BSD and busybox ("round-trip min/avg/max[/stddev]") summary formats.
+// The round-trip line is only required when replies were received.
+func parseOutput(output string) (probeResult, error) {
+	var pr probeResult
+	haveCounts, haveRTT := false, false
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, "packets transmitted") {
+			var sent, received int
+			fields := strings.Split(line, ",")
+			if len(fields) >= 2 {
+				_, err1 := fmt.Sscanf(strings.TrimSpace(fields[0]), "%d", &sent)
+				_, err2 := fmt.Sscanf(strings.TrimSpace(fields[1]), "%d", &received)
+				if err1 == nil && err2 == nil {
+					pr.sent, pr.received = sent, received
+					haveCounts = true
+				}
+			}
+			continue
+		}
+		if strings.Contains(line, "rtt min/avg/max") || strings.Contains(line, "round-trip min/avg/max") {
+			parts := strings.Split(line, "=")
+			if len(parts) < 2 {
+				continue
+			}
+			stats := strings.Fields(strings.TrimSpace(parts[1]))
+			if len(stats) == 0 {
+				continue
+			}
+			fields := strings.Split(stats[0], "/")
+			if len(fields) < 3 {
+				continue
+			}
+			vals := make([]time.Duration, len(fields))
+			ok := true
+			for i, f := range fields {
+				ms, err := strconv.ParseFloat(f, 64)
+				if err != nil {
+					ok = false
+					break
+				}
+				vals[i] = time.Duration(ms * float64(time.Millisecond))
+			}
+			if !ok {
+				continue
+			}
+			pr.min, pr.avg, pr.max = vals[0], vals[1], vals[2]
+			if len(vals) > 3 {
+				pr.mdev = vals[3]
+			}
+			haveRTT = true
+		}
+	}
+
+	if !haveCounts || (pr.received > 0 && !haveRTT) {
+		return probeResult{}, fmt.Errorf("could not parse ping output")
+	}
+	return pr, nil
+}
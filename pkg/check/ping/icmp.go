@@ -0,0 +1,202 @@
+package ping
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// IANA protocol numbers used to parse ICMP messages.
+const (
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+// packetInterval is the pause between echo requests to the same address,
+// matching the minimum interval the ping binary allows unprivileged users.
+const packetInterval = 200 * time.Millisecond
+
+// errNoSocket is returned by probeNative when neither a datagram nor a raw
+// ICMP socket could be opened, so the caller may fall back to exec.
+var errNoSocket = errors.New("no ICMP socket available")
+
+// icmpFamily holds the per-IP-version parameters for ICMP echo.
+type icmpFamily struct {
+	dgramNetwork string // unprivileged datagram socket network
+	rawNetwork   string // raw socket network
+	listenAddr   string
+	protocol     int
+	echoRequest  icmp.Type
+	echoReply    icmp.Type
+}
+
+var (
+	familyV4 = icmpFamily{
+		dgramNetwork: "udp4",
+		rawNetwork:   "ip4:icmp",
+		listenAddr:   "0.0.0.0",
+		protocol:     protocolICMP,
+		echoRequest:  ipv4.ICMPTypeEcho,
+		echoReply:    ipv4.ICMPTypeEchoReply,
+	}
+	familyV6 = icmpFamily{
+		dgramNetwork: "udp6",
+		rawNetwork:   "ip6:ipv6-icmp",
+		listenAddr:   "::",
+		protocol:     protocolIPv6ICMP,
+		echoRequest:  ipv6.ICMPTypeEchoRequest,
+		echoReply:    ipv6.ICMPTypeEchoReply,
+	}
+)
+
+// icmpConn is an open ICMP socket and the address form it expects.
+type icmpConn struct {
+	*icmp.PacketConn
+	dgram bool // datagram socket; peers are *net.UDPAddr
+}
+
+// listen opens an unprivileged datagram ICMP socket where the kernel allows
+// it (Linux net.ipv4.ping_group_range, macOS), falling back to a raw socket.
+func (f icmpFamily) listen() (*icmpConn, error) {
+	conn, dgramErr := icmp.ListenPacket(f.dgramNetwork, f.listenAddr)
+	if dgramErr == nil {
+		return &icmpConn{PacketConn: conn, dgram: true}, nil
+	}
+	conn, rawErr := icmp.ListenPacket(f.rawNetwork, f.listenAddr)
+	if rawErr == nil {
+		return &icmpConn{PacketConn: conn}, nil
+	}
+	return nil, fmt.Errorf("%w: datagram: %v; raw: %v", errNoSocket, dgramErr, rawErr)
+}
+
+// dst returns the socket address for ip.
+func (c *icmpConn) dst(ip *net.IPAddr) net.Addr {
+	if c.dgram {
+		return &net.UDPAddr{IP: ip.IP, Zone: ip.Zone}
+	}
+	return ip
+}
+
+// peerIP extracts the IP from an address returned by ReadFrom.
+func peerIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.IPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+// resolve returns the first IP address for host, which may be an IP literal.
+func resolve(ctx context.Context, host string) (*net.IPAddr, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses for %s", host)
+	}
+	return &addrs[0], nil
+}
+
+// probeNative sends count ICMP echo requests to address from Go and
+// collects the round-trip times of the replies. Each request waits up to
+// timeout for its reply.
+func probeNative(ctx context.Context, address string, count int, timeout time.Duration) (probeResult, error) {
+	ip, err := resolve(ctx, address)
+	if err != nil {
+		return probeResult{}, err
+	}
+
+	fam := familyV6
+	if ip.IP.To4() != nil {
+		fam = familyV4
+	}
+
+	conn, err := fam.listen()
+	if err != nil {
+		return probeResult{}, err
+	}
+	defer conn.Close()
+
+	// A random token in every payload identifies our replies. Datagram
+	// sockets have their echo ID rewritten by the kernel, and raw sockets
+	// see every echo reply arriving at the host.
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return probeResult{}, err
+	}
+
+	var rtts []time.Duration
+	id := os.Getpid() & 0xffff
+	dst := conn.dst(ip)
+	buf := make([]byte, 1500)
+
+	for seq := 0; seq < count; seq++ {
+		if seq > 0 {
+			select {
+			case <-ctx.Done():
+				return probeResult{}, ctx.Err()
+			case <-time.After(packetInterval):
+			}
+		}
+
+		msg := icmp.Message{
+			Type: fam.echoRequest,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: token},
+		}
+		b, err := msg.Marshal(nil)
+		if err != nil {
+			return probeResult{}, err
+		}
+
+		deadline := time.Now().Add(timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return probeResult{}, err
+		}
+
+		start := time.Now()
+		if _, err := conn.WriteTo(b, dst); err != nil {
+			return probeResult{}, fmt.Errorf("send: %w", err)
+		}
+
+		for {
+			n, peer, err := conn.ReadFrom(buf)
+			if err != nil {
+				var ne net.Error
+				if errors.As(err, &ne) && ne.Timeout() {
+					break // lost
+				}
+				return probeResult{}, fmt.Errorf("receive: %w", err)
+			}
+			if !ip.IP.Equal(peerIP(peer)) {
+				continue
+			}
+			reply, err := icmp.ParseMessage(fam.protocol, buf[:n])
+			if err != nil || reply.Type != fam.echoReply {
+				continue
+			}
+			echo, ok := reply.Body.(*icmp.Echo)
+			if !ok || echo.Seq != seq || !bytes.Equal(echo.Data, token) {
+				continue
+			}
+			rtts = append(rtts, time.Since(start))
+			break
+		}
+	}
+
+	return newProbeResult(count, rtts), nil
+}
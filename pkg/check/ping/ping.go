@@ -1,17 +1,23 @@
 // Package ping implements a ping (ICMP echo) check for the check framework.
 //
-// It shells out to the system ping command for each configured address,
-// reporting per-address latency as separate metrics. The addresses array
-// is explicit in the check config; the worker-injected "target" key is ignored.
+// It sends ICMP echo requests to each configured address from Go, using an
+// unprivileged datagram ICMP socket where the kernel allows it and a raw
+// socket otherwise, for both IPv4 and IPv6. If neither socket can be opened
+// it falls back to shelling out to the system ping command. Per-address
+// latency is reported as separate metrics, along with packet loss, min/max
+// round-trip time and jitter, which are stored and graphed by default with
+// loss on a graph of its own. An address is down when its packet loss
+// reaches the loss threshold; lower, partial loss marks the check degraded.
+// The addresses array is explicit in the check config; the worker-injected
+// "target" key is ignored.
 package ping
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"os/exec"
-	"strconv"
-	"strings"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/kylerisse/wasgeht/pkg/check"
@@ -26,8 +32,71 @@ const (
 
 	// DefaultCount is the default number of ping packets.
 	DefaultCount = 1
+
+	// DefaultLossThreshold is the default packet loss percentage at which
+	// an address is considered down.
+	DefaultLossThreshold = 100
+)
+
+// Modes select how echo requests are sent.
+const (
+	// ModeAuto sends echo requests natively and falls back to the ping
+	// command when no ICMP socket can be opened.
+	ModeAuto = "auto"
+
+	// ModeNative only sends echo requests natively.
+	ModeNative = "native"
+
+	// ModeExec only uses the system ping command.
+	ModeExec = "exec"
 )
 
+// probeResult summarizes the echo requests sent to one address.
+type probeResult struct {
+	sent     int
+	received int
+	min      time.Duration
+	avg      time.Duration
+	max      time.Duration
+	mdev     time.Duration // mean deviation, as reported by iputils ping
+}
+
+// newProbeResult computes round-trip statistics from the RTTs of the
+// replies received for sent requests.
+func newProbeResult(sent int, rtts []time.Duration) probeResult {
+	pr := probeResult{sent: sent, received: len(rtts)}
+	if len(rtts) == 0 {
+		return pr
+	}
+
+	var sum, sumSq float64
+	pr.min, pr.max = rtts[0], rtts[0]
+	for _, r := range rtts {
+		pr.min = min(pr.min, r)
+		pr.max = max(pr.max, r)
+		sum += float64(r)
+		sumSq += float64(r) * float64(r)
+	}
+	n := float64(len(rtts))
+	mean := sum / n
+	pr.avg = time.Duration(mean)
+	pr.mdev = time.Duration(math.Sqrt(math.Max(sumSq/n-mean*mean, 0)))
+	return pr
+}
+
+// probeFunc sends count echo requests to an address.
+type probeFunc func(ctx context.Context, address string, count int, timeout time.Duration) (probeResult, error)
+
+// probeAuto tries the native prober and falls back to exec when no ICMP
+// socket is available.
+func probeAuto(ctx context.Context, address string, count int, timeout time.Duration) (probeResult, error) {
+	pr, err := probeNative(ctx, address, count, timeout)
+	if errors.Is(err, errNoSocket) {
+		return probeExec(ctx, address, count, timeout)
+	}
+	return pr, err
+}
+
 // addressConfig maps a single ping target to its RRD data source.
 type addressConfig struct {
 	address   string // IP or hostname to ping
@@ -41,6 +110,9 @@ type Ping struct {
 	addresses []addressConfig
 	timeout   time.Duration
 	count     int
+	probe     probeFunc
+	lossLimit float64 // loss percentage at which an address is down
+	stats     bool    // store statistics as RRD data sources
 }
 
 // New creates a Ping check with the given addresses and options.
@@ -66,6 +138,9 @@ func New(addresses []string, opts ...Option) (*Ping, error) {
 		addresses: addrs,
 		timeout:   DefaultTimeout,
 		count:     DefaultCount,
+		probe:     probeAuto,
+		lossLimit: DefaultLossThreshold,
+		stats:     true,
 	}
 
 	for _, opt := range opts {
@@ -102,22 +177,77 @@ func WithCount(n int) Option {
 	}
 }
 
+// WithMode selects how echo requests are sent: ModeAuto (the default),
+// ModeNative or ModeExec.
+func WithMode(mode string) Option {
+	return func(p *Ping) error {
+		switch mode {
+		case ModeAuto:
+			p.probe = probeAuto
+		case ModeNative:
+			p.probe = probeNative
+		case ModeExec:
+			p.probe = probeExec
+		default:
+			return fmt.Errorf("mode must be %q, %q or %q, got %q", ModeAuto, ModeNative, ModeExec, mode)
+		}
+		return nil
+	}
+}
+
+// WithLossThreshold sets the packet loss percentage, in (0, 100], at which
+// an address is considered down. Any lower, non-zero loss marks the check
+// degraded.
+func WithLossThreshold(pct float64) Option {
+	return func(p *Ping) error {
+		if pct <= 0 || pct > 100 {
+			return fmt.Errorf("loss threshold must be in (0, 100], got %v", pct)
+		}
+		p.lossLimit = pct
+		return nil
+	}
+}
+
+// WithStatistics sets whether the per-address loss, min, max and jitter are
+// stored as RRD data sources, which is the default. They are always
+// reported in the Result.
+func WithStatistics(enabled bool) Option {
+	return func(p *Ping) error {
+		p.stats = enabled
+		return nil
+	}
+}
+
 // Type returns the check type name.
 func (p *Ping) Type() string {
 	return TypeName
 }
 
 // Describe returns the Descriptor for this ping check instance.
-// One metric is produced per configured address.
+// One metric is produced per configured address, followed by its
+// statistics when statistics are enabled.
 func (p *Ping) Describe() check.Descriptor {
-	metrics := make([]check.MetricDef, len(p.addresses))
-	for i, a := range p.addresses {
-		metrics[i] = check.MetricDef{
+	var metrics []check.MetricDef
+	for _, a := range p.addresses {
+		metrics = append(metrics, check.MetricDef{
 			ResultKey: a.resultKey,
 			DSName:    a.dsName,
 			Label:     a.label,
 			Unit:      "ms",
 			Scale:     1000,
+		})
+		if !p.stats {
+			continue
+		}
+		for _, st := range statistics {
+			metrics = append(metrics, check.MetricDef{
+				ResultKey: statResultKey(a, st),
+				DSName:    statDSName(a, st),
+				Label:     fmt.Sprintf("%s %s", a.label, st.label),
+				Unit:      st.unit,
+				Scale:     st.scale,
+				Graph:     st.graph,
+			})
 		}
 	}
 	return check.Descriptor{
@@ -126,46 +256,66 @@ func (p *Ping) Describe() check.Descriptor {
 	}
 }
 
-// Run pings all configured addresses and returns a Result.
-// Success requires all addresses to respond. Each address latency
-// is stored in microseconds keyed by address string.
+// Run pings all configured addresses concurrently and returns a Result.
+// Success requires every address's packet loss to stay below the loss
+// threshold; the result is degraded if any address lost packets. Each
+// address's average latency is stored in microseconds keyed by address
+// string, and is nil when no replies were received. Statistics are stored
+// under "<address> <statistic>" keys.
 func (p *Ping) Run(ctx context.Context) check.Result {
 	now := time.Now()
-	metrics := make(map[string]*int64, len(p.addresses))
+	metrics := make(map[string]*float64, len(p.addresses)*(1+len(statistics)))
 	var lastErr error
 	succeeded := 0
+	degraded := false
 
-	timeoutSec := fmt.Sprintf("%.0f", p.timeout.Seconds())
-	count := strconv.Itoa(p.count)
-
-	for _, a := range p.addresses {
-		cmd := exec.CommandContext(ctx, "ping", "-c", count, "-W", timeoutSec, a.address)
-
-		var out bytes.Buffer
-		cmd.Stdout = &out
-		cmd.Stderr = &out
+	// Probe every address at once; results are evaluated in address order.
+	probes := make([]probeResult, len(p.addresses))
+	errs := make([]error, len(p.addresses))
+	var wg sync.WaitGroup
+	for i, a := range p.addresses {
+		wg.Go(func() {
+			probes[i], errs[i] = p.probe(ctx, a.address, p.count, p.timeout)
+		})
+	}
+	wg.Wait()
 
-		if err := cmd.Run(); err != nil {
+	for i, a := range p.addresses {
+		pr, err := probes[i], errs[i]
+		if err != nil {
 			lastErr = fmt.Errorf("ping %s: %w", a.address, err)
 			metrics[a.resultKey] = nil
+			for _, st := range statistics {
+				metrics[statResultKey(a, st)] = nil
+			}
 			continue
 		}
 
-		latency, err := parseOutput(out.String())
-		if err != nil {
-			lastErr = fmt.Errorf("ping %s: %w", a.address, err)
+		for _, st := range statistics {
+			metrics[statResultKey(a, st)] = st.value(pr)
+		}
+		if pr.received == 0 {
 			metrics[a.resultKey] = nil
-			continue
+		} else {
+			v := microseconds(pr.avg)
+			metrics[a.resultKey] = &v
 		}
 
-		v := int64(latency.Microseconds())
-		metrics[a.resultKey] = &v
+		loss := pr.lossPercent()
+		if loss >= p.lossLimit {
+			lastErr = fmt.Errorf("ping %s: %d of %d packets lost (%.0f%%, threshold %.0f%%)", a.address, pr.sent-pr.received, pr.sent, loss, p.lossLimit)
+			continue
+		}
+		if loss > 0 {
+			degraded = true
+		}
 		succeeded++
 	}
 
 	return check.Result{
 		Timestamp: now,
 		Success:   succeeded == len(p.addresses),
+		Degraded:  degraded,
 		Err:       lastErr,
 		Metrics:   metrics,
 	}
@@ -173,7 +323,9 @@ func (p *Ping) Run(ctx context.Context) check.Result {
 
 // Factory creates a Ping check from a config map.
 // Required keys: "addresses" (list of strings).
-// Optional keys: "timeout" (duration string), "count" (float64).
+// Optional keys: "timeout" (duration string), "count" (float64),
+// "mode" (string: "auto", "native" or "exec"), "loss_threshold" (float64
+// percentage), "statistics" (bool, default true).
 // The "target" key injected by the worker is ignored.
 func Factory(config map[string]any) (check.Check, error) {
 	addresses, err := extractAddresses(config)
@@ -205,6 +357,31 @@ func Factory(config map[string]any) (check.Check, error) {
 		}
 	}
 
+	if v, ok := config["loss_threshold"]; ok {
+		switch t := v.(type) {
+		case float64:
+			opts = append(opts, WithLossThreshold(t))
+		default:
+			return nil, fmt.Errorf("ping: 'loss_threshold' must be a number, got %T", v)
+		}
+	}
+
+	if v, ok := config["statistics"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("ping: 'statistics' must be a bool, got %T", v)
+		}
+		opts = append(opts, WithStatistics(b))
+	}
+
+	if v, ok := config["mode"]; ok {
+		m, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("ping: 'mode' must be a string, got %T", v)
+		}
+		opts = append(opts, WithMode(m))
+	}
+
 	return New(addresses, opts...)
 }
 
@@ -238,27 +415,3 @@ func extractAddresses(config map[string]any) ([]string, error) {
 		return nil, fmt.Errorf("ping: 'addresses' must be a list, got %T", raw)
 	}
 }
-
-// parseOutput extracts the round-trip time from ping command output.
-func parseOutput(output string) (time.Duration, error) {
-	for _, line := range strings.Split(output, "\n") {
-		if strings.Contains(line, "rtt min/avg/max") || strings.Contains(line, "round-trip min/avg/max") {
-			parts := strings.Split(line, "=")
-			if len(parts) < 2 {
-				continue
-			}
-			stats := strings.TrimSpace(parts[1])
-			fields := strings.Split(stats, "/")
-			if len(fields) < 2 {
-				continue
-			}
-			avgStr := strings.TrimSpace(fields[1])
-			avgMs, err := strconv.ParseFloat(avgStr, 64)
-			if err != nil {
-				continue
-			}
-			return time.Duration(avgMs * float64(time.Millisecond)), nil
-		}
-	}
-	return 0, fmt.Errorf("could not parse ping output")
-}
@@ -2,7 +2,9 @@ package ping
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -103,7 +105,7 @@ func TestType(t *testing.T) {
 }
 
 func TestDescribe_SingleAddress(t *testing.T) {
-	p, _ := New([]string{"127.0.0.1"})
+	p, _ := New([]string{"127.0.0.1"}, WithStatistics(false))
 	desc := p.Describe()
 
 	if desc.Label != "ping" {
@@ -131,7 +133,7 @@ func TestDescribe_SingleAddress(t *testing.T) {
 }
 
 func TestDescribe_MultipleAddresses(t *testing.T) {
-	p, _ := New([]string{"1.1.1.1", "8.8.8.8", "9.9.9.9"})
+	p, _ := New([]string{"1.1.1.1", "8.8.8.8", "9.9.9.9"}, WithStatistics(false))
 	desc := p.Describe()
 
 	if desc.Label != "ping" {
@@ -152,8 +154,8 @@ func TestDescribe_MultipleAddresses(t *testing.T) {
 }
 
 func TestDescribe_IsInstanceSpecific(t *testing.T) {
-	p1, _ := New([]string{"1.1.1.1"})
-	p2, _ := New([]string{"1.1.1.1", "8.8.8.8"})
+	p1, _ := New([]string{"1.1.1.1"}, WithStatistics(false))
+	p2, _ := New([]string{"1.1.1.1", "8.8.8.8"}, WithStatistics(false))
 
 	if len(p1.Describe().Metrics) != 1 {
 		t.Errorf("expected 1 metric for p1, got %d", len(p1.Describe().Metrics))
@@ -318,7 +320,8 @@ func TestRegistryIntegration(t *testing.T) {
 	}
 
 	chk, err := reg.Create("ping", map[string]any{
-		"addresses": []any{"1.1.1.1", "8.8.8.8"},
+		"addresses":  []any{"1.1.1.1", "8.8.8.8"},
+		"statistics": false,
 	})
 	if err != nil {
 		t.Fatalf("failed to create ping check: %v", err)
@@ -345,7 +348,8 @@ func TestRegistryIntegration(t *testing.T) {
 	}
 }
 
-// TestRun_Localhost pings localhost. Requires ping binary on PATH.
+// TestRun_Localhost pings localhost. Requires an ICMP socket or the ping
+// binary on PATH.
 func TestRun_Localhost(t *testing.T) {
 	p, err := New([]string{"127.0.0.1"})
 	if err != nil {
@@ -360,3 +364,354 @@ func TestRun_Localhost(t *testing.T) {
 		t.Errorf("expected positive latency, got %v", result.Metrics["127.0.0.1"])
 	}
 }
+
+// --- mode tests ---
+
+func TestWithMode(t *testing.T) {
+	for _, m := range []string{ModeAuto, ModeNative, ModeExec} {
+		if _, err := New([]string{"127.0.0.1"}, WithMode(m)); err != nil {
+			t.Errorf("mode %q: unexpected error: %v", m, err)
+		}
+	}
+	if _, err := New([]string{"127.0.0.1"}, WithMode("fping")); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestFactory_Mode(t *testing.T) {
+	if _, err := Factory(map[string]any{"addresses": []any{"127.0.0.1"}, "mode": "exec"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Factory(map[string]any{"addresses": []any{"127.0.0.1"}, "mode": true}); err == nil {
+		t.Error("expected error for non-string mode")
+	}
+	if _, err := Factory(map[string]any{"addresses": []any{"127.0.0.1"}, "mode": "bogus"}); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+// --- Run with a fake prober ---
+
+// fakeProbe returns canned results keyed by address.
+func fakeProbe(results map[string]probeResult) probeFunc {
+	return func(_ context.Context, address string, _ int, _ time.Duration) (probeResult, error) {
+		pr, ok := results[address]
+		if !ok {
+			return probeResult{}, fmt.Errorf("unreachable")
+		}
+		return pr, nil
+	}
+}
+
+func TestRun_FakeProbe(t *testing.T) {
+	p, err := New([]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, WithCount(4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.probe = fakeProbe(map[string]probeResult{
+		"10.0.0.1": {sent: 4, received: 4, avg: 1500 * time.Microsecond},
+		"10.0.0.2": {sent: 4, received: 0},
+	})
+
+	result := p.Run(context.Background())
+	if result.Success {
+		t.Error("expected failure with total loss and an unreachable address")
+	}
+	if v := result.Metrics["10.0.0.1"]; v == nil || *v != 1500 {
+		t.Errorf("expected 1500us for 10.0.0.1, got %v", v)
+	}
+	for _, addr := range []string{"10.0.0.2", "10.0.0.3"} {
+		if v, ok := result.Metrics[addr]; !ok || v != nil {
+			t.Errorf("expected nil metric for %s, got %v (present=%v)", addr, v, ok)
+		}
+	}
+	if v := result.Metrics["10.0.0.2 loss"]; v == nil || *v != 100 {
+		t.Errorf("expected 100%% loss for 10.0.0.2, got %v", v)
+	}
+	if v, ok := result.Metrics["10.0.0.3 loss"]; !ok || v != nil {
+		t.Errorf("expected nil loss for unreachable address, got %v (present=%v)", v, ok)
+	}
+}
+
+func TestRun_ProbesConcurrently(t *testing.T) {
+	addrs := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	p, err := New(addrs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Each probe waits until all of them have started, which only happens
+	// when they run at the same time.
+	var started sync.WaitGroup
+	started.Add(len(addrs))
+	all := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(all)
+	}()
+	p.probe = func(_ context.Context, _ string, _ int, _ time.Duration) (probeResult, error) {
+		started.Done()
+		select {
+		case <-all:
+			return probeResult{sent: 1, received: 1, avg: time.Millisecond}, nil
+		case <-time.After(2 * time.Second):
+			return probeResult{}, errors.New("probes did not run concurrently")
+		}
+	}
+
+	result := p.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+}
+
+func TestRun_PartialLossDegraded(t *testing.T) {
+	ms := time.Millisecond
+	p, err := New([]string{"10.0.0.1"}, WithCount(4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.probe = fakeProbe(map[string]probeResult{
+		"10.0.0.1": {sent: 4, received: 3, min: 1 * ms, avg: 2 * ms, max: 4 * ms, mdev: 1 * ms},
+	})
+
+	result := p.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success with partial loss, got %v", result.Err)
+	}
+	if !result.Degraded {
+		t.Error("expected degraded with partial loss")
+	}
+	want := map[string]float64{
+		"10.0.0.1":      2000,
+		"10.0.0.1 min":  1000,
+		"10.0.0.1 max":  4000,
+		"10.0.0.1 mdev": 1000,
+		"10.0.0.1 loss": 25,
+	}
+	for k, w := range want {
+		if v := result.Metrics[k]; v == nil || *v != w {
+			t.Errorf("expected %s = %v, got %v", k, w, v)
+		}
+	}
+}
+
+func TestRun_LossThreshold(t *testing.T) {
+	p, err := New([]string{"10.0.0.1"}, WithCount(4), WithLossThreshold(50))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p.probe = fakeProbe(map[string]probeResult{"10.0.0.1": {sent: 4, received: 3, avg: time.Millisecond}})
+	if r := p.Run(context.Background()); !r.Success || !r.Degraded {
+		t.Errorf("expected degraded success at 25%% loss, got success=%v degraded=%v", r.Success, r.Degraded)
+	}
+
+	p.probe = fakeProbe(map[string]probeResult{"10.0.0.1": {sent: 4, received: 2, avg: time.Millisecond}})
+	r := p.Run(context.Background())
+	if r.Success {
+		t.Error("expected failure at 50% loss with a 50% threshold")
+	}
+	if v := r.Metrics["10.0.0.1"]; v == nil {
+		t.Error("expected latency to still be recorded when replies were received")
+	}
+}
+
+func TestRun_NoLossNotDegraded(t *testing.T) {
+	p, _ := New([]string{"10.0.0.1"})
+	p.probe = fakeProbe(map[string]probeResult{"10.0.0.1": {sent: 1, received: 1, avg: time.Millisecond}})
+	r := p.Run(context.Background())
+	if !r.Success || r.Degraded {
+		t.Errorf("expected clean success, got success=%v degraded=%v", r.Success, r.Degraded)
+	}
+}
+
+// --- statistics tests ---
+
+func TestWithLossThreshold_Invalid(t *testing.T) {
+	for _, v := range []float64{0, -5, 101} {
+		if _, err := New([]string{"127.0.0.1"}, WithLossThreshold(v)); err == nil {
+			t.Errorf("expected error for loss threshold %v", v)
+		}
+	}
+}
+
+func TestFactory_LossThresholdAndStatistics(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"addresses":      []any{"127.0.0.1"},
+		"loss_threshold": float64(20),
+		"statistics":     false,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := chk.(*Ping)
+	if p.lossLimit != 20 || p.stats {
+		t.Errorf("expected threshold 20 without statistics, got %v/%v", p.lossLimit, p.stats)
+	}
+
+	chk, err = Factory(map[string]any{"addresses": []any{"127.0.0.1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !chk.(*Ping).stats {
+		t.Error("expected statistics to be enabled by default")
+	}
+
+	if _, err := Factory(map[string]any{"addresses": []any{"127.0.0.1"}, "loss_threshold": "20%"}); err == nil {
+		t.Error("expected error for non-numeric loss_threshold")
+	}
+	if _, err := Factory(map[string]any{"addresses": []any{"127.0.0.1"}, "statistics": "yes"}); err == nil {
+		t.Error("expected error for non-bool statistics")
+	}
+}
+
+func TestDescribe_Statistics(t *testing.T) {
+	p, _ := New([]string{"1.1.1.1", "8.8.8.8"})
+	desc := p.Describe()
+	want := []struct {
+		ds, key, unit, graph string
+	}{
+		{"addr0", "1.1.1.1", "ms", ""},
+		{"addr0_min", "1.1.1.1 min", "ms", ""},
+		{"addr0_max", "1.1.1.1 max", "ms", ""},
+		{"addr0_mdev", "1.1.1.1 mdev", "ms", ""},
+		{"addr0_loss", "1.1.1.1 loss", "%", "loss"},
+		{"addr1", "8.8.8.8", "ms", ""},
+		{"addr1_min", "8.8.8.8 min", "ms", ""},
+		{"addr1_max", "8.8.8.8 max", "ms", ""},
+		{"addr1_mdev", "8.8.8.8 mdev", "ms", ""},
+		{"addr1_loss", "8.8.8.8 loss", "%", "loss"},
+	}
+	if len(desc.Metrics) != len(want) {
+		t.Fatalf("expected %d metrics, got %d", len(want), len(desc.Metrics))
+	}
+	for i, w := range want {
+		m := desc.Metrics[i]
+		if m.DSName != w.ds || m.ResultKey != w.key || m.Unit != w.unit || m.Graph != w.graph {
+			t.Errorf("metric %d: got {%s %q %s %q}, want {%s %q %s %q}", i, m.DSName, m.ResultKey, m.Unit, m.Graph, w.ds, w.key, w.unit, w.graph)
+		}
+	}
+	if graphs := desc.Graphs(); len(graphs) != 1 || graphs[0] != "loss" {
+		t.Errorf("expected a separate loss graph, got %v", graphs)
+	}
+}
+
+// --- newProbeResult tests ---
+
+func TestNewProbeResult(t *testing.T) {
+	ms := time.Millisecond
+	pr := newProbeResult(4, []time.Duration{1 * ms, 2 * ms, 3 * ms})
+	if pr.sent != 4 || pr.received != 3 {
+		t.Errorf("expected 3/4 received, got %d/%d", pr.received, pr.sent)
+	}
+	if pr.min != 1*ms || pr.avg != 2*ms || pr.max != 3*ms {
+		t.Errorf("expected min/avg/max 1/2/3ms, got %v/%v/%v", pr.min, pr.avg, pr.max)
+	}
+	// sqrt(((1+4+9)/3) - 4) ms = sqrt(2/3) ms
+	if pr.mdev < 816*time.Microsecond || pr.mdev > 817*time.Microsecond {
+		t.Errorf("expected mdev ~0.8165ms, got %v", pr.mdev)
+	}
+
+	empty := newProbeResult(2, nil)
+	if empty.received != 0 || empty.avg != 0 {
+		t.Errorf("expected no statistics without replies, got %+v", empty)
+	}
+}
+
+// --- parseOutput tests ---
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		sent     int
+		received int
+		avg      time.Duration
+		mdev     time.Duration
+	}{
+		{
+			name: "iputils",
+			output: `PING 127.0.0.1 (127.0.0.1) 56(84) bytes of data.
+64 bytes from 127.0.0.1: icmp_seq=1 ttl=64 time=0.041 ms
+
+--- 127.0.0.1 ping statistics ---
+3 packets transmitted, 2 received, 33.3333% packet loss, time 2002ms
+rtt min/avg/max/mdev = 0.030/0.041/0.052/0.011 ms`,
+			sent: 3, received: 2, avg: 41 * time.Microsecond, mdev: 11 * time.Microsecond,
+		},
+		{
+			name: "busybox",
+			output: `PING 10.0.0.1 (10.0.0.1): 56 data bytes
+64 bytes from 10.0.0.1: seq=0 ttl=64 time=1.250 ms
+
+--- 10.0.0.1 ping statistics ---
+1 packets transmitted, 1 packets received, 0% packet loss
+round-trip min/avg/max = 1.250/1.250/1.250 ms`,
+			sent: 1, received: 1, avg: 1250 * time.Microsecond,
+		},
+		{
+			name: "bsd",
+			output: `--- 10.0.0.1 ping statistics ---
+2 packets transmitted, 2 packets received, 0.0% packet loss
+round-trip min/avg/max/stddev = 1.000/2.000/3.000/1.000 ms`,
+			sent: 2, received: 2, avg: 2 * time.Millisecond, mdev: time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr, err := parseOutput(tt.output)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pr.sent != tt.sent || pr.received != tt.received {
+				t.Errorf("expected %d/%d, got %d/%d", tt.received, tt.sent, pr.received, pr.sent)
+			}
+			if pr.avg != tt.avg || pr.mdev != tt.mdev {
+				t.Errorf("expected avg %v mdev %v, got %v %v", tt.avg, tt.mdev, pr.avg, pr.mdev)
+			}
+		})
+	}
+}
+
+func TestParseOutput_TotalLoss(t *testing.T) {
+	pr, err := parseOutput(`PING 10.0.0.9 (10.0.0.9) 56(84) bytes of data.
+
+--- 10.0.0.9 ping statistics ---
+3 packets transmitted, 0 received, 100% packet loss, time 2043ms
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr.sent != 3 || pr.received != 0 {
+		t.Errorf("expected 0/3, got %d/%d", pr.received, pr.sent)
+	}
+}
+
+func TestParseOutput_Unparseable(t *testing.T) {
+	if _, err := parseOutput("ping: unknown host nowhere.invalid"); err == nil {
+		t.Error("expected error for unparseable output")
+	}
+}
+
+// TestProbeNative_Localhost pings loopback with the native prober over IPv4
+// and IPv6. Skipped where no ICMP socket can be opened.
+func TestProbeNative_Localhost(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "::1"} {
+		t.Run(addr, func(t *testing.T) {
+			pr, err := probeNative(context.Background(), addr, 2, time.Second)
+			if errors.Is(err, errNoSocket) {
+				t.Skipf("no ICMP socket: %v", err)
+			}
+			if err != nil {
+				t.Skipf("native ping failed (loopback may be unavailable): %v", err)
+			}
+			if pr.sent != 2 || pr.received != 2 {
+				t.Fatalf("expected 2/2 replies, got %d/%d", pr.received, pr.sent)
+			}
+			if pr.avg <= 0 || pr.min > pr.max {
+				t.Errorf("unexpected statistics: %+v", pr)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package ping
+
+import (
+	"fmt"
+	"time"
+)
+
+// statistic is a per-address measurement reported alongside the average
+// round-trip time.
+type statistic struct {
+	key   string // suffix for the result key and DS name
+	label string // human-readable label for graphs
+	unit  string
+	scale int
+	graph string                        // separate graph name, empty for the latency graph
+	value func(pr probeResult) *float64 // nil when not measurable
+}
+
+// statistics lists the per-address statistics in graph order. Loss is a
+// percentage rather than a time, so it is drawn on a graph of its own.
+var statistics = []statistic{
+	{key: "min", label: "min", unit: "ms", scale: 1000, value: rttValue(func(pr probeResult) time.Duration { return pr.min })},
+	{key: "max", label: "max", unit: "ms", scale: 1000, value: rttValue(func(pr probeResult) time.Duration { return pr.max })},
+	{key: "mdev", label: "jitter", unit: "ms", scale: 1000, value: rttValue(func(pr probeResult) time.Duration { return pr.mdev })},
+	{key: "loss", label: "loss", unit: "%", scale: 1, graph: "loss", value: lossValue},
+}
+
+// rttValue returns a statistic value func for a round-trip time in
+// microseconds, which is nil when no replies were received.
+func rttValue(get func(pr probeResult) time.Duration) func(pr probeResult) *float64 {
+	return func(pr probeResult) *float64 {
+		if pr.received == 0 {
+			return nil
+		}
+		v := microseconds(get(pr))
+		return &v
+	}
+}
+
+// lossValue returns the packet loss as a percentage.
+func lossValue(pr probeResult) *float64 {
+	v := pr.lossPercent()
+	return &v
+}
+
+// microseconds returns d in microseconds, keeping sub-microsecond
+// precision.
+func microseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
+
+// lossPercent returns the percentage of echo requests left unanswered.
+func (pr probeResult) lossPercent() float64 {
+	if pr.sent == 0 {
+		return 100
+	}
+	return float64(pr.sent-pr.received) * 100 / float64(pr.sent)
+}
+
+// statResultKey returns the Result.Metrics key for a statistic of an address.
+func statResultKey(a addressConfig, s statistic) string {
+	return fmt.Sprintf("%s %s", a.resultKey, s.key)
+}
+
+// statDSName returns the RRD DS name for a statistic of an address
+// (e.g. "addr0_loss").
+func statDSName(a addressConfig, s statistic) string {
+	return fmt.Sprintf("%s_%s", a.dsName, s.key)
+}
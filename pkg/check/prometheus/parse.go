@@ -0,0 +1,275 @@
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Sample is a single series value from a scrape.
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Parse reads metrics in the Prometheus text exposition format (or
+// OpenMetrics text) from r and calls fn for each sample. Comment lines
+// (HELP, TYPE, EOF) are skipped; timestamps and exemplars are validated
+// and discarded. A malformed line aborts the parse, as it does for a
+// Prometheus scrape.
+func Parse(r io.Reader, fn func(Sample)) error {
+	_, err := parse(r, fn, false)
+	return err
+}
+
+// ParseLenient is like Parse but skips malformed lines instead of aborting,
+// for callers that only want a few series from an exposition that may
+// contain unrelated lines they cannot parse. It returns the number of lines
+// skipped.
+func ParseLenient(r io.Reader, fn func(Sample)) (int, error) {
+	return parse(r, fn, true)
+}
+
+// parse implements Parse and ParseLenient.
+func parse(r io.Reader, fn func(Sample), lenient bool) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo, skipped := 0, 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s, err := ParseSample(line)
+		if err != nil {
+			if lenient {
+				skipped++
+				continue
+			}
+			return skipped, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		fn(s)
+	}
+	if err := scanner.Err(); err != nil {
+		return skipped, fmt.Errorf("error reading metrics: %w", err)
+	}
+	return skipped, nil
+}
+
+// ParseSample parses a single sample line of the form
+//
+//	name{label="value",...} value [timestamp] [# {label="value"} value [timestamp]]
+//
+// Label values may contain the escapes \\, \" and \n. Values may be
+// floats, NaN, +Inf or -Inf.
+func ParseSample(line string) (Sample, error) {
+	p := &lineParser{s: line}
+
+	name := p.ident(true)
+	if name == "" {
+		return Sample{}, fmt.Errorf("missing metric name")
+	}
+	s := Sample{Name: name}
+
+	blank := p.space()
+	if p.peek() == '{' {
+		labels, err := p.labels()
+		if err != nil {
+			return Sample{}, fmt.Errorf("metric %s: %w", name, err)
+		}
+		s.Labels = labels
+		blank = p.space()
+	}
+
+	if !blank || p.done() {
+		return Sample{}, fmt.Errorf("metric %s: missing value", name)
+	}
+	v, err := parseValue(p.token())
+	if err != nil {
+		return Sample{}, fmt.Errorf("metric %s: %w", name, err)
+	}
+	s.Value = v
+
+	if err := p.tail(); err != nil {
+		return Sample{}, fmt.Errorf("metric %s: %w", name, err)
+	}
+	return s, nil
+}
+
+// parseValue parses a sample value, accepting the text format's
+// spellings of NaN and the infinities.
+func parseValue(tok string) (float64, error) {
+	if tok == "" {
+		return 0, fmt.Errorf("missing value")
+	}
+	v, err := strconv.ParseFloat(tok, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", tok)
+	}
+	return v, nil
+}
+
+// lineParser walks a single exposition line.
+type lineParser struct {
+	s   string
+	pos int
+}
+
+func (p *lineParser) peek() byte {
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *lineParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+// space skips blanks and reports whether any were skipped.
+func (p *lineParser) space() bool {
+	start := p.pos
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+// token returns the run of non-blank characters at the current position.
+func (p *lineParser) token() string {
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] != ' ' && p.s[p.pos] != '\t' {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// ident returns a metric name (colons allowed) or label name at the
+// current position, or "" if there is none.
+func (p *lineParser) ident(metric bool) string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		ok := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(metric && c == ':') || (p.pos > start && c >= '0' && c <= '9')
+		if !ok {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// labels parses a {name="value",...} label set. A trailing comma is
+// allowed; repeated label names are not.
+func (p *lineParser) labels() (map[string]string, error) {
+	p.pos++ // '{'
+	labels := make(map[string]string)
+	for {
+		p.space()
+		if p.peek() == '}' {
+			p.pos++
+			return labels, nil
+		}
+		name := p.ident(false)
+		if name == "" {
+			return nil, fmt.Errorf("invalid label name at column %d", p.pos+1)
+		}
+		p.space()
+		if p.peek() != '=' {
+			return nil, fmt.Errorf("label %s: expected '='", name)
+		}
+		p.pos++
+		p.space()
+		value, err := p.quoted()
+		if err != nil {
+			return nil, fmt.Errorf("label %s: %w", name, err)
+		}
+		if _, dup := labels[name]; dup {
+			return nil, fmt.Errorf("duplicate label %s", name)
+		}
+		labels[name] = value
+		p.space()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+		default:
+			return nil, fmt.Errorf("label %s: expected ',' or '}'", name)
+		}
+	}
+}
+
+// quoted parses a double-quoted label value, unescaping \\, \" and \n.
+// Any other escaped character is kept as written.
+func (p *lineParser) quoted() (string, error) {
+	if p.peek() != '"' {
+		return "", fmt.Errorf("value must be quoted")
+	}
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.s):
+			p.pos++
+			switch e := p.s[p.pos]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case '\\', '"':
+				b.WriteByte(e)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+		p.pos++
+	}
+	return "", fmt.Errorf("unterminated value")
+}
+
+// tail validates what follows a sample value: an optional timestamp and
+// an optional OpenMetrics exemplar.
+func (p *lineParser) tail() error {
+	p.space()
+	if !p.done() && p.peek() != '#' {
+		if _, err := strconv.ParseFloat(p.token(), 64); err != nil {
+			return fmt.Errorf("invalid timestamp")
+		}
+		p.space()
+	}
+	if p.peek() == '#' {
+		p.pos++
+		p.space()
+		if p.peek() != '{' {
+			return fmt.Errorf("exemplar: missing label set")
+		}
+		if _, err := p.labels(); err != nil {
+			return fmt.Errorf("exemplar: %w", err)
+		}
+		p.space()
+		if _, err := parseValue(p.token()); err != nil {
+			return fmt.Errorf("exemplar: %w", err)
+		}
+		p.space()
+		if !p.done() {
+			if _, err := strconv.ParseFloat(p.token(), 64); err != nil {
+				return fmt.Errorf("exemplar: invalid timestamp")
+			}
+			p.space()
+		}
+	}
+	if !p.done() {
+		return fmt.Errorf("unexpected %q after value", p.s[p.pos:])
+	}
+	return nil
+}
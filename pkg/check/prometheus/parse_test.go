@@ -0,0 +1,165 @@
+package prometheus
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseSample_Valid(t *testing.T) {
+	tests := []struct {
+		line   string
+		name   string
+		labels map[string]string
+		value  float64
+	}{
+		{`up 1`, "up", nil, 1},
+		{`node_load1 0.42`, "node_load1", nil, 0.42},
+		{`http_requests_total{method="post",code="200"} 1027 1395066363000`, "http_requests_total", map[string]string{"method": "post", "code": "200"}, 1027},
+		{`node_filesystem_avail_bytes{mountpoint="/"} 1.2e+10`, "node_filesystem_avail_bytes", map[string]string{"mountpoint": "/"}, 1.2e10},
+		{`trailing_comma{a="b",} 3`, "trailing_comma", map[string]string{"a": "b"}, 3},
+		{`spaced { a = "b" , c="d" }	5`, "spaced", map[string]string{"a": "b", "c": "d"}, 5},
+		{`escaped{path="C:\\Program Files\\",msg="say \"hi\"\n"} 1`, "escaped", map[string]string{"path": `C:\Program Files\`, "msg": "say \"hi\"\n"}, 1},
+		{`brace_in_value{a="}{,="} 2`, "brace_in_value", map[string]string{"a": "}{,="}, 2},
+		{`empty{} 7`, "empty", map[string]string{}, 7},
+		{`colon:rule:name 4`, "colon:rule:name", nil, 4},
+		{`neg -3.5`, "neg", nil, -3.5},
+		{`big +Inf`, "big", nil, math.Inf(1)},
+		{`small -Inf`, "small", nil, math.Inf(-1)},
+		{`foo_bucket{le="0.5"} 3 # {trace_id="KOO5S4vxi0o"} 0.67`, "foo_bucket", map[string]string{"le": "0.5"}, 3},
+		{`foo_total 17 1520879607.789 # {trace_id="oHg5SJYRHA0"} 9.8 1520879607.789`, "foo_total", nil, 17},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSample(tt.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s.Name != tt.name {
+				t.Errorf("expected name %q, got %q", tt.name, s.Name)
+			}
+			if s.Value != tt.value {
+				t.Errorf("expected value %v, got %v", tt.value, s.Value)
+			}
+			if len(s.Labels) != len(tt.labels) {
+				t.Fatalf("expected labels %v, got %v", tt.labels, s.Labels)
+			}
+			for k, v := range tt.labels {
+				if s.Labels[k] != v {
+					t.Errorf("label %s: expected %q, got %q", k, v, s.Labels[k])
+				}
+			}
+		})
+	}
+}
+
+func TestParseSample_NaN(t *testing.T) {
+	s, err := ParseSample(`gauge NaN`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !math.IsNaN(s.Value) {
+		t.Errorf("expected NaN, got %v", s.Value)
+	}
+}
+
+func TestParseSample_Invalid(t *testing.T) {
+	tests := []string{
+		`{a="b"} 1`,
+		`metric`,
+		`metric abc`,
+		`metric{a="b"}`,
+		`metric{a="b} 1`,
+		`metric{a=b} 1`,
+		`metric{a="b" c="d"} 1`,
+		`metric{a="b",a="c"} 1`,
+		`metric{1a="b"} 1`,
+		`metric 1 notatimestamp`,
+		`metric 1 2 3`,
+		`metric 1 # trace_id="x" 1`,
+		`metric 1 # {trace_id="x"}`,
+	}
+	for _, line := range tests {
+		if _, err := ParseSample(line); err == nil {
+			t.Errorf("expected error for %q", line)
+		}
+	}
+}
+
+func TestParse_SkipsCommentsAndBlankLines(t *testing.T) {
+	input := `# HELP node_load1 1m load average.
+# TYPE node_load1 gauge
+node_load1 0.42
+
+# HELP up Whether the target is up.
+up 1
+# EOF
+`
+	var names []string
+	if err := Parse(strings.NewReader(input), func(s Sample) {
+		names = append(names, s.Name)
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(names) != 2 || names[0] != "node_load1" || names[1] != "up" {
+		t.Errorf("expected [node_load1 up], got %v", names)
+	}
+}
+
+func TestParse_ReportsLineNumber(t *testing.T) {
+	input := "# TYPE up gauge\nup 1\nup{broken 1\n"
+	err := Parse(strings.NewReader(input), func(Sample) {})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected line number in error, got %v", err)
+	}
+}
+
+func TestParseLenient_SkipsMalformedLines(t *testing.T) {
+	input := "up 1\nup{broken 1\nnode_load1 abc\nnode_load1 0.42\n"
+	var names []string
+	skipped, err := ParseLenient(strings.NewReader(input), func(s Sample) {
+		names = append(names, s.Name)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if skipped != 2 {
+		t.Errorf("expected 2 skipped lines, got %d", skipped)
+	}
+	if len(names) != 2 || names[0] != "up" || names[1] != "node_load1" {
+		t.Errorf("expected [up node_load1], got %v", names)
+	}
+}
+
+func TestSelector_Matches(t *testing.T) {
+	s := Sample{Name: "node_hwmon_temp_celsius", Labels: map[string]string{"chip": "coretemp", "sensor": "temp1"}}
+	tests := []struct {
+		sel  Selector
+		want bool
+	}{
+		{Selector{Name: "node_hwmon_temp_celsius"}, true},
+		{Selector{Name: "node_hwmon_temp_celsius", Labels: map[string]string{"sensor": "temp1"}}, true},
+		{Selector{Name: "node_hwmon_temp_celsius", Labels: map[string]string{"sensor": "temp1", "chip": "coretemp"}}, true},
+		{Selector{Name: "node_hwmon_temp_celsius", Labels: map[string]string{"sensor": "temp2"}}, false},
+		{Selector{Name: "node_hwmon_temp_celsius", Labels: map[string]string{"missing": ""}}, false},
+		{Selector{Name: "node_hwmon_temp_max_celsius"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.sel.Matches(s); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.sel, tt.want, got)
+		}
+	}
+}
+
+func TestSelector_String(t *testing.T) {
+	sel := Selector{Name: "temp", Labels: map[string]string{"sensor": "temp1", "chip": `a"b`}}
+	if got, want := sel.String(), `temp{chip="a\"b",sensor="temp1"}`; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if got := (Selector{Name: "up"}).String(); got != "up" {
+		t.Errorf("expected up, got %s", got)
+	}
+}
@@ -0,0 +1,361 @@
+// Package prometheus implements a check that scrapes a Prometheus metrics
+// endpoint and records selected series as RRD data sources.
+//
+// Each configured metric is a selector: a metric name plus optional label
+// values that a series must carry. For example, with
+//
+//	node_hwmon_temp_celsius{chip="platform_coretemp_0",sensor="temp1"} 45.5
+//	node_filesystem_avail_bytes{device="/dev/sda1",mountpoint="/"} 1.2e+10
+//
+// the selectors {"name": "node_hwmon_temp_celsius", "labels": {"sensor":
+// "temp1"}} and {"name": "node_filesystem_avail_bytes", "labels":
+// {"mountpoint": "/"}} each become a data source. When several series
+// match a selector their values are summed.
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+)
+
+const (
+	// TypeName is the registered name for this check type.
+	TypeName = "prometheus"
+
+	// DefaultTimeout is the default HTTP scrape timeout.
+	DefaultTimeout = 5 * time.Second
+)
+
+// acceptHeader asks for the text formats this package parses, so that
+// exporters which support content negotiation do not answer in protobuf.
+const acceptHeader = "application/openmetrics-text;version=1.0.0;q=0.5,text/plain;version=0.0.4;q=0.4"
+
+// metricConfig maps a selector to an RRD data source.
+type metricConfig struct {
+	selector  Selector
+	resultKey string // key in Result.Metrics (= label)
+	dsName    string // RRD DS name (e.g. "m0")
+	label     string // human-readable label (defaults to the selector)
+	unit      string
+	divisor   int // graphs show the value ÷ divisor
+}
+
+// Check implements check.Check by scraping a Prometheus metrics endpoint.
+type Check struct {
+	url     string
+	metrics []metricConfig
+	timeout time.Duration
+	client  *http.Client
+	desc    check.Descriptor
+}
+
+// Option is a functional option for configuring a Prometheus Check.
+type Option func(*Check) error
+
+// WithTimeout sets the HTTP scrape timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Check) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout must be positive, got %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// newCheck creates a Prometheus Check that scrapes url for the given metrics.
+func newCheck(url string, metrics []metricConfig, opts ...Option) (*Check, error) {
+	if url == "" {
+		return nil, fmt.Errorf("prometheus: url must not be empty")
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("prometheus: at least one metric is required")
+	}
+
+	seen := make(map[string]bool, len(metrics))
+	for _, m := range metrics {
+		if seen[m.resultKey] {
+			return nil, fmt.Errorf("prometheus: duplicate metric %q", m.resultKey)
+		}
+		seen[m.resultKey] = true
+	}
+
+	c := &Check{
+		url:     url,
+		metrics: metrics,
+		timeout: DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("prometheus: %w", err)
+		}
+	}
+
+	c.client = &http.Client{Timeout: c.timeout}
+
+	defs := make([]check.MetricDef, len(metrics))
+	for i, m := range metrics {
+		defs[i] = check.MetricDef{
+			ResultKey: m.resultKey,
+			DSName:    m.dsName,
+			Label:     m.label,
+			Unit:      m.unit,
+			Scale:     m.divisor,
+		}
+	}
+	c.desc = check.Descriptor{
+		Label:   "metrics",
+		Metrics: defs,
+	}
+
+	return c, nil
+}
+
+// Type returns the check type name.
+func (c *Check) Type() string {
+	return TypeName
+}
+
+// Describe returns the Descriptor for this check instance.
+func (c *Check) Describe() check.Descriptor {
+	return c.desc
+}
+
+// Run scrapes the endpoint and returns a Result with one metric per
+// selector, the sum of the values of the series it matches. A selector
+// that matches no series, or whose value is NaN or infinite, is recorded
+// as nil and marks the check degraded. The check fails if the scrape
+// fails or no selector has a value. Malformed lines are skipped, and
+// their count is reported alongside any selectors left without a value.
+func (c *Check) Run(ctx context.Context) check.Result {
+	now := time.Now()
+
+	sums := make([]float64, len(c.metrics))
+	found := make([]bool, len(c.metrics))
+	// Exporters such as node_exporter may carry collectors whose lines the
+	// parser rejects; skip them and read the selectors that do parse.
+	skipped, err := ScrapeLenient(ctx, c.client, c.url, func(s Sample) {
+		for i, m := range c.metrics {
+			if m.selector.Matches(s) {
+				sums[i] += s.Value
+				found[i] = true
+			}
+		}
+	})
+
+	metrics := make(map[string]*float64, len(c.metrics))
+	if err != nil {
+		for _, m := range c.metrics {
+			metrics[m.resultKey] = nil
+		}
+		return check.Result{
+			Timestamp: now,
+			Success:   false,
+			Metrics:   metrics,
+			Err:       fmt.Errorf("prometheus: %w", err),
+		}
+	}
+
+	var missing []string
+	for i, m := range c.metrics {
+		v := sums[i]
+		if !found[i] || math.IsNaN(v) || math.IsInf(v, 0) {
+			metrics[m.resultKey] = nil
+			missing = append(missing, m.resultKey)
+			continue
+		}
+		metrics[m.resultKey] = &v
+	}
+
+	result := check.Result{
+		Timestamp: now,
+		Success:   true,
+		Metrics:   metrics,
+	}
+	if len(missing) > 0 {
+		result.Success = len(missing) < len(c.metrics)
+		result.Degraded = result.Success
+		result.Err = fmt.Errorf("prometheus: no values for %s", strings.Join(missing, ", "))
+		if skipped > 0 {
+			result.Err = fmt.Errorf("prometheus: no values for %s (%d malformed lines skipped)", strings.Join(missing, ", "), skipped)
+		}
+	}
+	return result
+}
+
+// Scrape fetches url with client and calls fn for each sample in the
+// response. It fails on transport errors, a non-200 status, or a
+// malformed exposition.
+func Scrape(ctx context.Context, client *http.Client, url string, fn func(Sample)) error {
+	body, err := fetch(ctx, client, url)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return Parse(body, fn)
+}
+
+// ScrapeLenient is like Scrape but skips malformed lines, as ParseLenient
+// does, and returns the number of lines skipped.
+func ScrapeLenient(ctx context.Context, client *http.Client, url string, fn func(Sample)) (int, error) {
+	body, err := fetch(ctx, client, url)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	return ParseLenient(body, fn)
+}
+
+// fetch requests url with client and returns the response body if the
+// status is 200.
+func fetch(ctx context.Context, client *http.Client, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", acceptHeader)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("scrape failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// Factory creates a Prometheus Check from a config map.
+// Required keys:
+//   - "url" (string) — metrics endpoint, e.g. "http://nas.example.com:9100/metrics"
+//   - "metrics" (list of objects) — selectors, see below
+//
+// Optional keys:
+//   - "timeout" (string) — duration string, default "5s"
+//
+// Each metric object has:
+//   - "name" (string, required) — metric name
+//   - "labels" (object of strings) — label values a series must carry
+//   - "label" (string) — display label and result key, default the selector
+//   - "unit" (string) — display unit
+//   - "divisor" (number) — integer graphs divide the value by, default 1
+func Factory(config map[string]any) (check.Check, error) {
+	url, ok := config["url"].(string)
+	if !ok || url == "" {
+		return nil, fmt.Errorf("prometheus: config missing required key 'url'")
+	}
+
+	metrics, err := extractMetrics(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []Option
+
+	if v, ok := config["timeout"]; ok {
+		ts, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("prometheus: 'timeout' must be a duration string, got %T", v)
+		}
+		d, err := time.ParseDuration(ts)
+		if err != nil {
+			return nil, fmt.Errorf("prometheus: invalid timeout %q: %w", ts, err)
+		}
+		opts = append(opts, WithTimeout(d))
+	}
+
+	return newCheck(url, metrics, opts...)
+}
+
+// extractMetrics parses the "metrics" config key into metricConfigs.
+func extractMetrics(config map[string]any) ([]metricConfig, error) {
+	raw, ok := config["metrics"]
+	if !ok {
+		return nil, fmt.Errorf("prometheus: config missing required key 'metrics'")
+	}
+
+	rawList, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("prometheus: 'metrics' must be a list, got %T", raw)
+	}
+	if len(rawList) == 0 {
+		return nil, fmt.Errorf("prometheus: 'metrics' must not be empty")
+	}
+
+	metrics := make([]metricConfig, 0, len(rawList))
+	for i, item := range rawList {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("prometheus: metric at index %d must be an object, got %T", i, item)
+		}
+
+		name, ok := m["name"].(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("prometheus: metric at index %d missing required 'name'", i)
+		}
+		sel := Selector{Name: name}
+
+		if v, ok := m["labels"]; ok {
+			rawLabels, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("prometheus: metric at index %d: 'labels' must be an object, got %T", i, v)
+			}
+			sel.Labels = make(map[string]string, len(rawLabels))
+			for k, lv := range rawLabels {
+				s, ok := lv.(string)
+				if !ok {
+					return nil, fmt.Errorf("prometheus: metric at index %d: label %q must be a string, got %T", i, k, lv)
+				}
+				sel.Labels[k] = s
+			}
+		}
+
+		label := sel.String()
+		if v, ok := m["label"]; ok {
+			s, ok := v.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("prometheus: metric at index %d: 'label' must be a non-empty string", i)
+			}
+			label = s
+		}
+
+		unit := ""
+		if v, ok := m["unit"]; ok {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("prometheus: metric at index %d: 'unit' must be a string, got %T", i, v)
+			}
+			unit = s
+		}
+
+		divisor := 0
+		if v, ok := m["divisor"]; ok {
+			f, ok := v.(float64)
+			if !ok || f < 1 || f != math.Trunc(f) {
+				return nil, fmt.Errorf("prometheus: metric at index %d: 'divisor' must be a positive integer", i)
+			}
+			divisor = int(f)
+		}
+
+		metrics = append(metrics, metricConfig{
+			selector:  sel,
+			resultKey: label,
+			dsName:    fmt.Sprintf("m%d", i),
+			label:     label,
+			unit:      unit,
+			divisor:   divisor,
+		})
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,285 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+)
+
+const sampleMetrics = `# HELP node_hwmon_temp_celsius Hardware monitor for temperature (input)
+# TYPE node_hwmon_temp_celsius gauge
+node_hwmon_temp_celsius{chip="platform_coretemp_0",sensor="temp1"} 45.5
+node_hwmon_temp_celsius{chip="platform_coretemp_0",sensor="temp2"} 43
+# HELP node_filesystem_avail_bytes Filesystem space available to non-root users in bytes.
+# TYPE node_filesystem_avail_bytes gauge
+node_filesystem_avail_bytes{device="/dev/sda1",fstype="ext4",mountpoint="/"} 1.2e+10
+node_filesystem_avail_bytes{device="/dev/sdb1",fstype="ext4",mountpoint="/srv"} 3e+09
+# HELP ups_load UPS load in percent.
+# TYPE ups_load gauge
+ups_load{ups="rack"} NaN
+`
+
+func testMetrics() []metricConfig {
+	return []metricConfig{
+		{
+			selector:  Selector{Name: "node_hwmon_temp_celsius", Labels: map[string]string{"sensor": "temp1"}},
+			resultKey: "cpu temp", dsName: "m0", label: "cpu temp", unit: "°C",
+		},
+		{
+			selector:  Selector{Name: "node_filesystem_avail_bytes"},
+			resultKey: "disk free", dsName: "m1", label: "disk free", unit: "GB", divisor: 1e9,
+		},
+	}
+}
+
+func newMetricsServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// --- newCheck() tests ---
+
+func TestNew_Valid(t *testing.T) {
+	c, err := newCheck("http://nas.local:9100/metrics", testMetrics())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.timeout != DefaultTimeout {
+		t.Errorf("expected default timeout, got %v", c.timeout)
+	}
+	if c.Type() != TypeName {
+		t.Errorf("expected type %q, got %q", TypeName, c.Type())
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := newCheck("", testMetrics()); err == nil {
+		t.Error("expected error for empty url")
+	}
+	if _, err := newCheck("http://nas.local/metrics", nil); err == nil {
+		t.Error("expected error for no metrics")
+	}
+	dup := append(testMetrics(), testMetrics()[0])
+	if _, err := newCheck("http://nas.local/metrics", dup); err == nil {
+		t.Error("expected error for duplicate metric")
+	}
+	if _, err := newCheck("http://nas.local/metrics", testMetrics(), WithTimeout(0)); err == nil {
+		t.Error("expected error for zero timeout")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	c, err := newCheck("http://nas.local/metrics", testMetrics())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	desc := c.Describe()
+	if len(desc.Metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(desc.Metrics))
+	}
+	want := check.MetricDef{ResultKey: "cpu temp", DSName: "m0", Label: "cpu temp", Unit: "°C"}
+	if desc.Metrics[0] != want {
+		t.Errorf("expected %+v, got %+v", want, desc.Metrics[0])
+	}
+}
+
+// --- Run() tests ---
+
+func TestRun_Success(t *testing.T) {
+	srv := newMetricsServer(t, sampleMetrics)
+	c, err := newCheck(srv.URL, testMetrics())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := c.Run(context.Background())
+	if !result.Success || result.Degraded {
+		t.Fatalf("expected success, got success=%v degraded=%v err=%v", result.Success, result.Degraded, result.Err)
+	}
+	if v := result.Metrics["cpu temp"]; v == nil || *v != 45.5 {
+		t.Errorf("expected cpu temp 45.5, got %v", v)
+	}
+	// Both filesystems match the unlabelled selector and are summed.
+	if v := result.Metrics["disk free"]; v == nil || *v != 15000000000 {
+		t.Errorf("expected summed disk free 15000000000, got %v", v)
+	}
+}
+
+func TestRun_SendsAcceptHeader(t *testing.T) {
+	var accept string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		_, _ = w.Write([]byte(sampleMetrics))
+	}))
+	defer srv.Close()
+
+	c, _ := newCheck(srv.URL, testMetrics())
+	c.Run(context.Background())
+	if !strings.Contains(accept, "text/plain") {
+		t.Errorf("expected text format in Accept header, got %q", accept)
+	}
+}
+
+func TestRun_MissingSeriesDegraded(t *testing.T) {
+	srv := newMetricsServer(t, sampleMetrics)
+	metrics := append(testMetrics(),
+		metricConfig{selector: Selector{Name: "ups_load"}, resultKey: "ups load", dsName: "m2", label: "ups load"},
+		metricConfig{selector: Selector{Name: "node_load1"}, resultKey: "load", dsName: "m3", label: "load"},
+	)
+	c, err := newCheck(srv.URL, metrics)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := c.Run(context.Background())
+	if !result.Success || !result.Degraded {
+		t.Fatalf("expected degraded success, got success=%v degraded=%v", result.Success, result.Degraded)
+	}
+	for _, key := range []string{"ups load", "load"} {
+		if v, ok := result.Metrics[key]; !ok || v != nil {
+			t.Errorf("expected nil metric for %q, got %v (present=%v)", key, v, ok)
+		}
+		if !strings.Contains(result.Err.Error(), key) {
+			t.Errorf("expected %q in error, got %v", key, result.Err)
+		}
+	}
+	if result.Metrics["cpu temp"] == nil {
+		t.Error("expected cpu temp to be recorded")
+	}
+}
+
+func TestRun_NoSeriesFails(t *testing.T) {
+	srv := newMetricsServer(t, "up 1\n")
+	c, _ := newCheck(srv.URL, testMetrics())
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Fatal("expected failure when no selector matches")
+	}
+}
+
+func TestRun_SkipsMalformedLines(t *testing.T) {
+	srv := newMetricsServer(t, "broken{ 1\n"+sampleMetrics+"node_textfile_mtime_seconds{file=\"x\"} 1 2 3\n")
+	c, _ := newCheck(srv.URL, testMetrics())
+	result := c.Run(context.Background())
+	if !result.Success || result.Degraded {
+		t.Fatalf("expected success despite malformed lines, got success=%v degraded=%v err=%v", result.Success, result.Degraded, result.Err)
+	}
+	if v := result.Metrics["cpu temp"]; v == nil || *v != 45.5 {
+		t.Errorf("expected cpu temp 45.5, got %v", v)
+	}
+}
+
+func TestRun_MissingSeriesReportsSkippedLines(t *testing.T) {
+	srv := newMetricsServer(t, sampleMetrics+"broken{ 1\n")
+	metrics := append(testMetrics(),
+		metricConfig{selector: Selector{Name: "broken"}, resultKey: "broken", dsName: "m2", label: "broken"},
+	)
+	c, _ := newCheck(srv.URL, metrics)
+	result := c.Run(context.Background())
+	if !result.Success || !result.Degraded {
+		t.Fatalf("expected degraded success, got success=%v degraded=%v", result.Success, result.Degraded)
+	}
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "1 malformed lines skipped") {
+		t.Errorf("expected skipped line count in error, got %v", result.Err)
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"500 response", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+			c, _ := newCheck(srv.URL, testMetrics())
+			result := c.Run(context.Background())
+			if result.Success {
+				t.Fatal("expected failure")
+			}
+			for k, v := range result.Metrics {
+				if v != nil {
+					t.Errorf("expected nil metric for %q on failure, got %v", k, *v)
+				}
+			}
+		})
+	}
+}
+
+func TestRun_ConnectionError(t *testing.T) {
+	c, _ := newCheck("http://127.0.0.1:1/metrics", testMetrics(), WithTimeout(time.Second))
+	if result := c.Run(context.Background()); result.Success {
+		t.Error("expected failure for unreachable endpoint")
+	}
+}
+
+// --- Factory tests ---
+
+func TestFactory_Valid(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"url":     "http://nas.local:9100/metrics",
+		"timeout": "10s",
+		"metrics": []any{
+			map[string]any{
+				"name":   "node_hwmon_temp_celsius",
+				"labels": map[string]any{"chip": "platform_coretemp_0", "sensor": "temp1"},
+				"label":  "cpu temp",
+				"unit":   "°C",
+			},
+			map[string]any{"name": "node_filesystem_avail_bytes", "labels": map[string]any{"mountpoint": "/"}, "unit": "GB", "divisor": float64(1e9)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := chk.(*Check)
+	if c.timeout != 10*time.Second {
+		t.Errorf("expected 10s timeout, got %v", c.timeout)
+	}
+	m := c.Describe().Metrics
+	if m[0].ResultKey != "cpu temp" || m[0].Scale != 0 || m[0].Unit != "°C" {
+		t.Errorf("unexpected first metric: %+v", m[0])
+	}
+	if m[1].ResultKey != `node_filesystem_avail_bytes{mountpoint="/"}` || m[1].DSName != "m1" || m[1].Scale != 1e9 {
+		t.Errorf("expected selector as default label, got %+v", m[1])
+	}
+}
+
+func TestFactory_Errors(t *testing.T) {
+	metric := map[string]any{"name": "up"}
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{"missing url", map[string]any{"metrics": []any{metric}}},
+		{"missing metrics", map[string]any{"url": "http://x/metrics"}},
+		{"empty metrics", map[string]any{"url": "http://x/metrics", "metrics": []any{}}},
+		{"metric not object", map[string]any{"url": "http://x/metrics", "metrics": []any{"up"}}},
+		{"missing name", map[string]any{"url": "http://x/metrics", "metrics": []any{map[string]any{"label": "up"}}}},
+		{"labels not object", map[string]any{"url": "http://x/metrics", "metrics": []any{map[string]any{"name": "up", "labels": "job=x"}}}},
+		{"label value not string", map[string]any{"url": "http://x/metrics", "metrics": []any{map[string]any{"name": "up", "labels": map[string]any{"port": float64(1)}}}}},
+		{"fractional divisor", map[string]any{"url": "http://x/metrics", "metrics": []any{map[string]any{"name": "up", "divisor": 0.5}}}},
+		{"duplicate label", map[string]any{"url": "http://x/metrics", "metrics": []any{metric, metric}}},
+		{"bad timeout", map[string]any{"url": "http://x/metrics", "metrics": []any{metric}, "timeout": "soon"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Factory(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
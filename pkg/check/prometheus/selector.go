@@ -0,0 +1,47 @@
+package prometheus
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Selector picks series from a scrape by metric name and label values.
+// A series matches when its name is Name and it carries every label in
+// Labels with exactly that value; other labels are ignored.
+type Selector struct {
+	Name   string
+	Labels map[string]string
+}
+
+// Matches reports whether s belongs to the selected series.
+func (sel Selector) Matches(s Sample) bool {
+	if s.Name != sel.Name {
+		return false
+	}
+	for k, v := range sel.Labels {
+		if got, ok := s.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the selector in PromQL form, e.g.
+// node_hwmon_temp_celsius{chip="coretemp",sensor="temp1"}, with labels
+// in name order.
+func (sel Selector) String() string {
+	if len(sel.Labels) == 0 {
+		return sel.Name
+	}
+	names := make([]string, 0, len(sel.Labels))
+	for k := range sel.Labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, k := range names {
+		parts[i] = k + "=" + strconv.Quote(sel.Labels[k])
+	}
+	return sel.Name + "{" + strings.Join(parts, ",") + "}"
+}
@@ -12,10 +12,25 @@ type Result struct {
 	// Success indicates whether the check passed.
 	Success bool
 
-	// Metrics holds named measurements from the check execution.
+	// Degraded indicates the check passed but reported a warning condition
+	// (e.g. a certificate nearing expiry). It is only meaningful when
+	// Success is true.
+	Degraded bool
+
+	// Unknown indicates the check could not determine the target's state
+	// (e.g. a plugin exiting with UNKNOWN or failing to start), as opposed
+	// to determining that it is down. It is only meaningful when Success
+	// is false.
+	Unknown bool
+
+	// Metrics holds named measurements from the check execution, in the
+	// units declared by the check's MetricDefs (before Scale is applied).
 	// A nil pointer value for a key means the target was attempted but failed.
 	// An absent key or nil map means no measurement was attempted.
-	Metrics map[string]*int64
+	// Keys not declared in the check's Descriptor are reported through the
+	// API but not stored in the RRD. NaN and infinite values are treated
+	// as nil.
+	Metrics map[string]*float64
 
 	// Err holds any error encountered during check execution.
 	// A non-nil Err generally corresponds to Success being false,
@@ -22,11 +22,11 @@ func TestResult_ZeroValue(t *testing.T) {
 }
 
 func TestResult_WithMetrics(t *testing.T) {
-	v := int64(12345)
+	v := float64(12345)
 	r := Result{
 		Timestamp: time.Now(),
 		Success:   true,
-		Metrics:   map[string]*int64{"latency_us": &v},
+		Metrics:   map[string]*float64{"latency_us": &v},
 	}
 	if !r.Success {
 		t.Error("expected success")
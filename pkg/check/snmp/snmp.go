@@ -0,0 +1,656 @@
+// Package snmp implements an SNMP polling check that GETs a configured
+// list of OIDs from a device over SNMP v2c or v3 and records each value as
+// its own data source.
+//
+// Values are gauges by default. Counters such as ifHCInOctets can instead
+// be declared as counter or derive metrics, which the RRD stores as
+// per-second rates; with a multiplier of 8 an octet counter becomes bits
+// per second.
+package snmp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/kylerisse/wasgeht/pkg/check"
+)
+
+const (
+	// TypeName is the registered name for this check type.
+	TypeName = "snmp"
+
+	// DefaultTimeout is the default timeout for each request attempt.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultRetries is the default number of retries after a timeout.
+	DefaultRetries = 1
+
+	// DefaultPort is used for targets without a port.
+	DefaultPort = 161
+
+	// DefaultCommunity is the default v2c community.
+	DefaultCommunity = "public"
+)
+
+// maxInt63 masks values that overflow int64 (Counter64 above 2^63).
+var maxInt63 = big.NewInt(math.MaxInt64)
+
+// authProtocols maps config names to USM authentication protocols.
+var authProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"MD5":    gosnmp.MD5,
+	"SHA":    gosnmp.SHA,
+	"SHA224": gosnmp.SHA224,
+	"SHA256": gosnmp.SHA256,
+	"SHA384": gosnmp.SHA384,
+	"SHA512": gosnmp.SHA512,
+}
+
+// privProtocols maps config names to USM privacy protocols.
+var privProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"DES":     gosnmp.DES,
+	"AES":     gosnmp.AES,
+	"AES192":  gosnmp.AES192,
+	"AES256":  gosnmp.AES256,
+	"AES192C": gosnmp.AES192C,
+	"AES256C": gosnmp.AES256C,
+}
+
+// metricConfig maps a single OID to its RRD data source.
+type metricConfig struct {
+	oid        string // numeric OID (e.g. "1.3.6.1.2.1.31.1.1.1.6.1")
+	resultKey  string // key in Result.Metrics (= label)
+	dsName     string // RRD DS name (e.g. "oid0")
+	label      string // human-readable label (defaults to oid)
+	unit       string
+	dsType     check.DSType
+	multiplier int64 // stored value = polled value × multiplier
+	divisor    int   // graphs show stored value ÷ divisor
+	max        *float64
+}
+
+// usmConfig holds SNMPv3 user-based security settings.
+type usmConfig struct {
+	user           string
+	authProtocol   string // "" for noAuthNoPriv
+	authPassphrase string
+	privProtocol   string // "" for no privacy
+	privPassphrase string
+	contextName    string
+}
+
+// Check implements check.Check by polling OIDs over SNMP.
+type Check struct {
+	target    string
+	port      uint16
+	community string
+	usm       *usmConfig // nil means v2c
+	metrics   []metricConfig
+	timeout   time.Duration
+	retries   int
+	desc      check.Descriptor
+}
+
+// Option is a functional option for configuring an SNMP Check.
+type Option func(*Check) error
+
+// WithTimeout sets the timeout for each request attempt.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Check) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout must be positive, got %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithRetries sets how many times a timed-out request is retried.
+func WithRetries(n int) Option {
+	return func(c *Check) error {
+		if n < 0 {
+			return fmt.Errorf("retries must not be negative, got %d", n)
+		}
+		c.retries = n
+		return nil
+	}
+}
+
+// WithCommunity sets the v2c community string.
+func WithCommunity(community string) Option {
+	return func(c *Check) error {
+		if community == "" {
+			return fmt.Errorf("community must not be empty")
+		}
+		c.community = community
+		return nil
+	}
+}
+
+// WithV3 switches the check to SNMPv3 with the given user. Without
+// WithAuth the user is noAuthNoPriv.
+func WithV3(user string) Option {
+	return func(c *Check) error {
+		if user == "" {
+			return fmt.Errorf("username must not be empty")
+		}
+		c.usm = &usmConfig{user: user}
+		return nil
+	}
+}
+
+// WithAuth sets the SNMPv3 authentication protocol (MD5, SHA, SHA224,
+// SHA256, SHA384 or SHA512) and passphrase. It must follow WithV3.
+func WithAuth(protocol, passphrase string) Option {
+	return func(c *Check) error {
+		if c.usm == nil {
+			return fmt.Errorf("authentication requires SNMPv3")
+		}
+		protocol = strings.ToUpper(protocol)
+		if _, ok := authProtocols[protocol]; !ok {
+			return fmt.Errorf("unknown auth protocol %q", protocol)
+		}
+		if len(passphrase) < 8 {
+			return fmt.Errorf("auth passphrase must be at least 8 characters")
+		}
+		c.usm.authProtocol, c.usm.authPassphrase = protocol, passphrase
+		return nil
+	}
+}
+
+// WithPrivacy sets the SNMPv3 privacy protocol (DES, AES, AES192, AES256,
+// AES192C or AES256C) and passphrase. It must follow WithAuth.
+func WithPrivacy(protocol, passphrase string) Option {
+	return func(c *Check) error {
+		if c.usm == nil || c.usm.authProtocol == "" {
+			return fmt.Errorf("privacy requires SNMPv3 authentication")
+		}
+		protocol = strings.ToUpper(protocol)
+		if _, ok := privProtocols[protocol]; !ok {
+			return fmt.Errorf("unknown priv protocol %q", protocol)
+		}
+		if len(passphrase) < 8 {
+			return fmt.Errorf("priv passphrase must be at least 8 characters")
+		}
+		c.usm.privProtocol, c.usm.privPassphrase = protocol, passphrase
+		return nil
+	}
+}
+
+// WithContextName sets the SNMPv3 context name. It must follow WithV3.
+func WithContextName(name string) Option {
+	return func(c *Check) error {
+		if c.usm == nil {
+			return fmt.Errorf("context name requires SNMPv3")
+		}
+		c.usm.contextName = name
+		return nil
+	}
+}
+
+// newCheck creates an SNMP Check that polls the given metrics from target
+// (host or host:port; port 161 by default).
+func newCheck(target string, metrics []metricConfig, opts ...Option) (*Check, error) {
+	if target == "" {
+		return nil, fmt.Errorf("snmp: target must not be empty")
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("snmp: at least one metric is required")
+	}
+
+	host, port := target, uint16(DefaultPort)
+	if h, p, err := net.SplitHostPort(target); err == nil {
+		n, err := strconv.ParseUint(p, 10, 16)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("snmp: invalid port in target %q", target)
+		}
+		host, port = h, uint16(n)
+	}
+
+	seen := make(map[string]bool, len(metrics))
+	for _, m := range metrics {
+		if seen[m.resultKey] {
+			return nil, fmt.Errorf("snmp: duplicate metric %q", m.resultKey)
+		}
+		seen[m.resultKey] = true
+	}
+
+	c := &Check{
+		target:    strings.Trim(host, "[]"),
+		port:      port,
+		community: DefaultCommunity,
+		metrics:   metrics,
+		timeout:   DefaultTimeout,
+		retries:   DefaultRetries,
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("snmp: %w", err)
+		}
+	}
+
+	defs := make([]check.MetricDef, len(metrics))
+	for i, m := range metrics {
+		defs[i] = check.MetricDef{
+			ResultKey: m.resultKey,
+			DSName:    m.dsName,
+			Label:     m.label,
+			Unit:      m.unit,
+			Scale:     m.divisor,
+			DSType:    m.dsType,
+			Max:       m.max,
+		}
+	}
+	c.desc = check.Descriptor{
+		Label:   "snmp",
+		Metrics: defs,
+	}
+
+	return c, nil
+}
+
+// Type returns the check type name.
+func (c *Check) Type() string {
+	return TypeName
+}
+
+// Describe returns the Descriptor for this check instance.
+func (c *Check) Describe() check.Descriptor {
+	return c.desc
+}
+
+// Run GETs every configured OID and returns a Result. Each value is
+// multiplied by its metric's multiplier and stored as is; for counter and
+// derive metrics that is the raw counter, which the RRD turns into a rate.
+// An OID the agent does not have, or whose value is not numeric, is
+// recorded as nil. Success requires every OID to be retrieved.
+func (c *Check) Run(ctx context.Context) check.Result {
+	metrics := make(map[string]*float64, len(c.metrics))
+	for _, m := range c.metrics {
+		metrics[m.resultKey] = nil
+	}
+
+	result := check.Result{Metrics: metrics}
+	fail := func(err error) check.Result {
+		result.Timestamp = time.Now()
+		result.Err = fmt.Errorf("snmp %s: %w", c.target, err)
+		return result
+	}
+
+	client := c.client(ctx)
+	if err := client.Connect(); err != nil {
+		return fail(err)
+	}
+	defer client.Conn.Close()
+
+	var lastErr error
+	for start := 0; start < len(c.metrics); start += client.MaxOids {
+		batch := c.metrics[start:min(start+client.MaxOids, len(c.metrics))]
+		oids := make([]string, len(batch))
+		for i, m := range batch {
+			oids[i] = m.oid
+		}
+
+		pkt, err := client.Get(oids)
+		if err != nil {
+			return fail(err)
+		}
+		if pkt.Error != gosnmp.NoError {
+			return fail(fmt.Errorf("agent returned %v (index %d)", pkt.Error, pkt.ErrorIndex))
+		}
+		if len(pkt.Variables) != len(batch) {
+			return fail(fmt.Errorf("agent returned %d values for %d OIDs", len(pkt.Variables), len(batch)))
+		}
+
+		for i, m := range batch {
+			v, err := value(pkt.Variables[i], m.multiplier)
+			if err != nil {
+				lastErr = fmt.Errorf("snmp %s: %s: %w", c.target, m.label, err)
+				continue
+			}
+			metrics[m.resultKey] = &v
+		}
+	}
+
+	result.Timestamp = time.Now()
+	result.Success = lastErr == nil
+	result.Err = lastErr
+	return result
+}
+
+// client returns a GoSNMP client configured for this check. A new client is
+// used for every run, so SNMPv3 engine discovery is repeated and an agent
+// reboot is handled transparently.
+func (c *Check) client(ctx context.Context) *gosnmp.GoSNMP {
+	g := &gosnmp.GoSNMP{
+		Target:    c.target,
+		Port:      c.port,
+		Transport: "udp",
+		Community: c.community,
+		Version:   gosnmp.Version2c,
+		Context:   ctx,
+		Timeout:   c.timeout,
+		Retries:   c.retries,
+		MaxOids:   gosnmp.MaxOids,
+	}
+	if c.usm == nil {
+		return g
+	}
+
+	params := &gosnmp.UsmSecurityParameters{
+		UserName:               c.usm.user,
+		AuthenticationProtocol: gosnmp.NoAuth,
+		PrivacyProtocol:        gosnmp.NoPriv,
+	}
+	g.MsgFlags = gosnmp.NoAuthNoPriv
+	if c.usm.authProtocol != "" {
+		params.AuthenticationProtocol = authProtocols[c.usm.authProtocol]
+		params.AuthenticationPassphrase = c.usm.authPassphrase
+		g.MsgFlags = gosnmp.AuthNoPriv
+	}
+	if c.usm.privProtocol != "" {
+		params.PrivacyProtocol = privProtocols[c.usm.privProtocol]
+		params.PrivacyPassphrase = c.usm.privPassphrase
+		g.MsgFlags = gosnmp.AuthPriv
+	}
+
+	g.Version = gosnmp.Version3
+	g.Community = ""
+	g.SecurityModel = gosnmp.UserSecurityModel
+	g.SecurityParameters = params
+	g.ContextName = c.usm.contextName
+	return g
+}
+
+// value converts a polled variable to the stored value, multiplied by
+// multiplier. Integer types are multiplied exactly, with Counter64 values
+// above 2^63 reduced modulo 2^63 so counters wrap within the range the RRD
+// accepts; numeric strings and opaque floats are used as floats.
+func value(pdu gosnmp.SnmpPDU, multiplier int64) (float64, error) {
+	switch pdu.Type {
+	case gosnmp.Integer, gosnmp.Counter32, gosnmp.Gauge32, gosnmp.TimeTicks,
+		gosnmp.Counter64, gosnmp.Uinteger32:
+		n := gosnmp.ToBigInt(pdu.Value)
+		n.Mul(n, big.NewInt(multiplier))
+		if !n.IsInt64() {
+			n.And(n, maxInt63)
+		}
+		return float64(n.Int64()), nil
+	case gosnmp.OctetString:
+		b, _ := pdu.Value.([]byte)
+		f, err := strconv.ParseFloat(strings.TrimSpace(string(b)), 64)
+		if err != nil {
+			return 0, fmt.Errorf("value %q is not numeric", b)
+		}
+		return f * float64(multiplier), nil
+	case gosnmp.OpaqueFloat:
+		f, _ := pdu.Value.(float32)
+		return float64(f) * float64(multiplier), nil
+	case gosnmp.OpaqueDouble:
+		f, _ := pdu.Value.(float64)
+		return f * float64(multiplier), nil
+	case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView, gosnmp.Null:
+		return 0, fmt.Errorf("agent has no value for %s (%v)", pdu.Name, pdu.Type)
+	default:
+		return 0, fmt.Errorf("value of type %v is not numeric", pdu.Type)
+	}
+}
+
+// Factory creates an SNMP Check from a config map.
+// Required keys:
+//   - "target" (string) — device as host or host:port (port 161 by default)
+//   - "metrics" (list of objects) — OIDs to poll, see below
+//
+// Optional keys:
+//   - "version" (string) — "2c" (default) or "3"
+//   - "community" (string) — v2c community, default "public"
+//   - "username" (string) — v3 user (required for version 3)
+//   - "auth_protocol", "auth_passphrase" (string) — v3 authentication
+//   - "priv_protocol", "priv_passphrase" (string) — v3 privacy
+//   - "context_name" (string) — v3 context
+//   - "timeout" (string) — per-attempt duration string, default "5s"
+//   - "request_retries" (number) — retries after a timeout, default 1
+//
+// Each metric object has:
+//   - "oid" (string, required) — numeric OID, e.g. "1.3.6.1.2.1.31.1.1.1.6.1"
+//   - "label" (string) — display label and result key, default the OID
+//   - "unit" (string) — display unit
+//   - "type" (string) — "gauge" (default), "counter" or "derive"
+//   - "multiplier" (number) — integer applied before storing, e.g. 8 to
+//     turn octets into bits. Default 1.
+//   - "divisor" (number) — integer graphs divide by, e.g. 10 for values
+//     reported in tenths. Default 1.
+//   - "max" (number) — largest plausible value, after the multiplier; for
+//     counter and derive metrics, the largest rate per second. Anything
+//     above is stored as unknown. Default unbounded.
+func Factory(config map[string]any) (check.Check, error) {
+	target, ok := config["target"].(string)
+	if !ok {
+		return nil, fmt.Errorf("snmp: config missing required string key 'target'")
+	}
+
+	metrics, err := extractMetrics(config)
+	if err != nil {
+		return nil, err
+	}
+
+	str := func(key string) (string, bool, error) {
+		v, ok := config[key]
+		if !ok {
+			return "", false, nil
+		}
+		s, ok := v.(string)
+		if !ok {
+			return "", false, fmt.Errorf("snmp: '%s' must be a string, got %T", key, v)
+		}
+		return s, true, nil
+	}
+
+	var opts []Option
+
+	version, _, err := str("version")
+	if err != nil {
+		return nil, err
+	}
+	switch version {
+	case "", "2c":
+		for _, key := range []string{"username", "auth_protocol", "auth_passphrase", "priv_protocol", "priv_passphrase", "context_name"} {
+			if _, ok := config[key]; ok {
+				return nil, fmt.Errorf("snmp: '%s' requires version \"3\"", key)
+			}
+		}
+		community, ok, err := str("community")
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			opts = append(opts, WithCommunity(community))
+		}
+	case "3":
+		if _, ok := config["community"]; ok {
+			return nil, fmt.Errorf("snmp: 'community' applies only to version \"2c\"")
+		}
+		user, _, err := str("username")
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithV3(user))
+
+		authProto, hasAuth, err := str("auth_protocol")
+		if err != nil {
+			return nil, err
+		}
+		authPass, _, err := str("auth_passphrase")
+		if err != nil {
+			return nil, err
+		}
+		if hasAuth {
+			opts = append(opts, WithAuth(authProto, authPass))
+		}
+
+		privProto, hasPriv, err := str("priv_protocol")
+		if err != nil {
+			return nil, err
+		}
+		privPass, _, err := str("priv_passphrase")
+		if err != nil {
+			return nil, err
+		}
+		if hasPriv {
+			opts = append(opts, WithPrivacy(privProto, privPass))
+		}
+
+		contextName, ok, err := str("context_name")
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			opts = append(opts, WithContextName(contextName))
+		}
+	default:
+		return nil, fmt.Errorf("snmp: unsupported version %q (want \"2c\" or \"3\")", version)
+	}
+
+	if ts, ok, err := str("timeout"); err != nil {
+		return nil, err
+	} else if ok {
+		d, err := time.ParseDuration(ts)
+		if err != nil {
+			return nil, fmt.Errorf("snmp: invalid timeout %q: %w", ts, err)
+		}
+		opts = append(opts, WithTimeout(d))
+	}
+
+	if v, ok := config["request_retries"]; ok {
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) {
+			return nil, fmt.Errorf("snmp: 'request_retries' must be an integer")
+		}
+		opts = append(opts, WithRetries(int(n)))
+	}
+
+	return newCheck(target, metrics, opts...)
+}
+
+// extractMetrics parses the "metrics" list from the config map.
+func extractMetrics(config map[string]any) ([]metricConfig, error) {
+	raw, ok := config["metrics"]
+	if !ok {
+		return nil, fmt.Errorf("snmp: config missing required key 'metrics'")
+	}
+
+	rawList, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("snmp: 'metrics' must be a list, got %T", raw)
+	}
+	if len(rawList) == 0 {
+		return nil, fmt.Errorf("snmp: 'metrics' must not be empty")
+	}
+
+	metrics := make([]metricConfig, 0, len(rawList))
+	for i, item := range rawList {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("snmp: metric at index %d must be an object, got %T", i, item)
+		}
+
+		oid, ok := m["oid"].(string)
+		oid = strings.TrimPrefix(oid, ".")
+		if !ok || !validOID(oid) {
+			return nil, fmt.Errorf("snmp: metric at index %d: 'oid' must be a numeric OID", i)
+		}
+
+		mc := metricConfig{
+			oid:        oid,
+			label:      oid,
+			dsName:     fmt.Sprintf("oid%d", i),
+			dsType:     check.DSGauge,
+			multiplier: 1,
+		}
+
+		if v, ok := m["label"]; ok {
+			s, ok := v.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("snmp: metric at index %d: 'label' must be a non-empty string", i)
+			}
+			mc.label = s
+		}
+		mc.resultKey = mc.label
+
+		if v, ok := m["unit"]; ok {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("snmp: metric at index %d: 'unit' must be a string, got %T", i, v)
+			}
+			mc.unit = s
+		}
+
+		if v, ok := m["type"]; ok {
+			s, _ := v.(string)
+			switch strings.ToLower(s) {
+			case "gauge":
+				mc.dsType = check.DSGauge
+			case "counter":
+				mc.dsType = check.DSCounter
+			case "derive":
+				mc.dsType = check.DSDerive
+			default:
+				return nil, fmt.Errorf("snmp: metric at index %d: 'type' must be \"gauge\", \"counter\" or \"derive\"", i)
+			}
+		}
+
+		if v, ok := m["multiplier"]; ok {
+			f, ok := v.(float64)
+			if !ok || f < 1 || f != math.Trunc(f) {
+				return nil, fmt.Errorf("snmp: metric at index %d: 'multiplier' must be a positive integer", i)
+			}
+			mc.multiplier = int64(f)
+		}
+		if mc.multiplier > 1 && mc.dsType == check.DSCounter {
+			// The RRD detects counter wraps at 2^32 and 2^64, which a
+			// multiplied value no longer wraps at.
+			return nil, fmt.Errorf("snmp: metric at index %d: 'multiplier' cannot be used with type \"counter\"; use \"derive\"", i)
+		}
+
+		if v, ok := m["divisor"]; ok {
+			f, ok := v.(float64)
+			if !ok || f < 1 || f != math.Trunc(f) {
+				return nil, fmt.Errorf("snmp: metric at index %d: 'divisor' must be a positive integer", i)
+			}
+			mc.divisor = int(f)
+		}
+
+		if v, ok := m["max"]; ok {
+			f, ok := v.(float64)
+			if !ok || f <= 0 {
+				return nil, fmt.Errorf("snmp: metric at index %d: 'max' must be a positive number", i)
+			}
+			mc.max = check.Bound(f)
+		}
+
+		metrics = append(metrics, mc)
+	}
+
+	return metrics, nil
+}
+
+// validOID reports whether s is a dotted numeric OID.
+func validOID(s string) bool {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	for _, p := range parts {
+		if _, err := strconv.ParseUint(p, 10, 32); err != nil {
+			return false
+		}
+	}
+	return true
+}
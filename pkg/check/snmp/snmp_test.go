@@ -0,0 +1,401 @@
+package snmp
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/kylerisse/wasgeht/pkg/check"
+)
+
+// fakeAgent is a minimal SNMPv2c agent answering GET requests from a
+// fixed table of values.
+type fakeAgent struct {
+	community string
+	values    map[string]gosnmp.SnmpPDU // keyed by OID without leading dot
+	errStatus gosnmp.SNMPError          // reply with this error status
+	silent    bool                      // never reply
+}
+
+// start runs the agent on a localhost UDP socket and returns its address.
+func (f fakeAgent) start(t *testing.T) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	go func() {
+		decoder := &gosnmp.GoSNMP{}
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			req, err := decoder.SnmpDecodePacket(buf[:n])
+			if err != nil || f.silent || req.Community != f.community {
+				continue
+			}
+
+			resp := &gosnmp.SnmpPacket{
+				Version:   gosnmp.Version2c,
+				Community: req.Community,
+				PDUType:   gosnmp.GetResponse,
+				RequestID: req.RequestID,
+				Error:     f.errStatus,
+			}
+			for _, v := range req.Variables {
+				pdu, ok := f.values[strings.TrimPrefix(v.Name, ".")]
+				if !ok {
+					pdu = gosnmp.SnmpPDU{Type: gosnmp.NoSuchObject}
+				}
+				pdu.Name = v.Name
+				resp.Variables = append(resp.Variables, pdu)
+			}
+			out, err := resp.MarshalMsg()
+			if err != nil {
+				continue
+			}
+			_, _ = pc.WriteTo(out, addr)
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+// testMetrics parses metric config items as the Factory would.
+func testMetrics(t *testing.T, items ...map[string]any) []metricConfig {
+	t.Helper()
+	raw := make([]any, len(items))
+	for i, item := range items {
+		raw[i] = item
+	}
+	metrics, err := extractMetrics(map[string]any{"metrics": raw})
+	if err != nil {
+		t.Fatalf("extractMetrics: %v", err)
+	}
+	return metrics
+}
+
+// --- newCheck() tests ---
+
+func TestNew_Defaults(t *testing.T) {
+	c, err := newCheck("switch.example.com", testMetrics(t, map[string]any{"oid": "1.3.6.1.2.1.1.3.0"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Type() != TypeName {
+		t.Errorf("expected type %q, got %q", TypeName, c.Type())
+	}
+	if c.target != "switch.example.com" || c.port != DefaultPort {
+		t.Errorf("unexpected target %s:%d", c.target, c.port)
+	}
+	if c.community != DefaultCommunity || c.usm != nil || c.timeout != DefaultTimeout || c.retries != DefaultRetries {
+		t.Errorf("unexpected defaults: %+v", c)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	m := testMetrics(t, map[string]any{"oid": "1.3.6.1.2.1.1.3.0"})
+	dup := testMetrics(t,
+		map[string]any{"oid": "1.3.6.1.2.1.1.3.0", "label": "x"},
+		map[string]any{"oid": "1.3.6.1.2.1.1.7.0", "label": "x"},
+	)
+	tests := []struct {
+		name    string
+		target  string
+		metrics []metricConfig
+		opts    []Option
+	}{
+		{"empty target", "", m, nil},
+		{"no metrics", "host", nil, nil},
+		{"bad port", "host:99999", m, nil},
+		{"duplicate label", "host", dup, nil},
+		{"zero timeout", "host", m, []Option{WithTimeout(0)}},
+		{"negative retries", "host", m, []Option{WithRetries(-1)}},
+		{"empty community", "host", m, []Option{WithCommunity("")}},
+		{"auth without v3", "host", m, []Option{WithAuth("SHA", "secret123")}},
+		{"unknown auth", "host", m, []Option{WithV3("u"), WithAuth("SHA1024", "secret123")}},
+		{"short auth passphrase", "host", m, []Option{WithV3("u"), WithAuth("SHA", "short")}},
+		{"privacy without auth", "host", m, []Option{WithV3("u"), WithPrivacy("AES", "secret123")}},
+		{"unknown priv", "host", m, []Option{WithV3("u"), WithAuth("SHA", "secret123"), WithPrivacy("ROT13", "secret123")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newCheck(tt.target, tt.metrics, tt.opts...); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	c, err := newCheck("router", testMetrics(t,
+		map[string]any{"oid": ".1.3.6.1.2.1.31.1.1.1.6.1", "label": "eth0 in", "unit": "bits", "type": "derive", "multiplier": float64(8), "max": 1e10},
+		map[string]any{"oid": "1.3.6.1.4.1.2021.10.1.5.1", "divisor": float64(100)},
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []check.MetricDef{
+		{ResultKey: "eth0 in", DSName: "oid0", Label: "eth0 in", Unit: "bits", DSType: check.DSDerive},
+		{ResultKey: "1.3.6.1.4.1.2021.10.1.5.1", DSName: "oid1", Label: "1.3.6.1.4.1.2021.10.1.5.1", Scale: 100, DSType: check.DSGauge},
+	}
+	desc := c.Describe()
+	if desc.Label != "snmp" || len(desc.Metrics) != len(want) {
+		t.Fatalf("unexpected descriptor %+v", desc)
+	}
+	for i, w := range want {
+		got := desc.Metrics[i]
+		got.Max = nil
+		if got != w {
+			t.Errorf("metric %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+	if m := desc.Metrics[0].Max; m == nil || *m != 1e10 {
+		t.Errorf("expected max 1e10, got %v", m)
+	}
+	if desc.Metrics[1].Max != nil {
+		t.Errorf("expected no max, got %v", *desc.Metrics[1].Max)
+	}
+}
+
+func TestClient_V3(t *testing.T) {
+	m := testMetrics(t, map[string]any{"oid": "1.3.6.1.2.1.1.3.0"})
+	tests := []struct {
+		name  string
+		opts  []Option
+		flags gosnmp.SnmpV3MsgFlags
+		auth  gosnmp.SnmpV3AuthProtocol
+		priv  gosnmp.SnmpV3PrivProtocol
+	}{
+		{"noAuthNoPriv", []Option{WithV3("monitor")}, gosnmp.NoAuthNoPriv, gosnmp.NoAuth, gosnmp.NoPriv},
+		{"authNoPriv", []Option{WithV3("monitor"), WithAuth("sha256", "authsecret")}, gosnmp.AuthNoPriv, gosnmp.SHA256, gosnmp.NoPriv},
+		{"authPriv", []Option{WithV3("monitor"), WithAuth("SHA", "authsecret"), WithPrivacy("aes", "privsecret"), WithContextName("vlan-10")}, gosnmp.AuthPriv, gosnmp.SHA, gosnmp.AES},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newCheck("[2001:db8::1]:1161", m, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			g := c.client(context.Background())
+			if g.Target != "2001:db8::1" || g.Port != 1161 {
+				t.Errorf("unexpected target %s:%d", g.Target, g.Port)
+			}
+			if g.Version != gosnmp.Version3 || g.SecurityModel != gosnmp.UserSecurityModel || g.MsgFlags != tt.flags {
+				t.Errorf("unexpected v3 settings: version %v, model %v, flags %v", g.Version, g.SecurityModel, g.MsgFlags)
+			}
+			params, ok := g.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+			if !ok {
+				t.Fatalf("unexpected security parameters %T", g.SecurityParameters)
+			}
+			if params.UserName != "monitor" || params.AuthenticationProtocol != tt.auth || params.PrivacyProtocol != tt.priv {
+				t.Errorf("unexpected USM parameters %+v", params)
+			}
+			if tt.flags == gosnmp.AuthPriv && (params.PrivacyPassphrase != "privsecret" || g.ContextName != "vlan-10") {
+				t.Errorf("unexpected privacy settings %+v, context %q", params, g.ContextName)
+			}
+		})
+	}
+}
+
+// --- Run() tests ---
+
+func TestRun_Success(t *testing.T) {
+	addr := fakeAgent{
+		community: "s3cret",
+		values: map[string]gosnmp.SnmpPDU{
+			"1.3.6.1.2.1.1.3.0":          {Type: gosnmp.TimeTicks, Value: uint32(123456)},
+			"1.3.6.1.2.1.31.1.1.1.6.1":   {Type: gosnmp.Counter64, Value: uint64(1 << 40)},
+			"1.3.6.1.2.1.2.2.1.10.1":     {Type: gosnmp.Counter32, Value: uint(4000000000)},
+			"1.3.6.1.4.1.2021.10.1.3.1":  {Type: gosnmp.OctetString, Value: []byte("0.42")},
+			"1.3.6.1.4.1.9.9.13.1.3.1.3": {Type: gosnmp.Gauge32, Value: uint(38)},
+		},
+	}.start(t)
+
+	c, err := newCheck(addr, testMetrics(t,
+		map[string]any{"oid": "1.3.6.1.2.1.1.3.0", "label": "uptime"},
+		map[string]any{"oid": "1.3.6.1.2.1.31.1.1.1.6.1", "label": "in", "type": "derive", "multiplier": float64(8)},
+		map[string]any{"oid": "1.3.6.1.2.1.2.2.1.10.1", "label": "in32", "type": "counter"},
+		map[string]any{"oid": "1.3.6.1.4.1.2021.10.1.3.1", "label": "load"},
+		map[string]any{"oid": "1.3.6.1.4.1.9.9.13.1.3.1.3", "label": "temp"},
+	), WithCommunity("s3cret"), WithTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if !result.Success || result.Err != nil {
+		t.Fatalf("expected success, got err %v", result.Err)
+	}
+	want := map[string]float64{
+		"uptime": 123456,
+		"in":     8 << 40,
+		"in32":   4000000000,
+		"load":   0.42,
+		"temp":   38,
+	}
+	for key, w := range want {
+		v := result.Metrics[key]
+		if v == nil || *v != w {
+			t.Errorf("%s: expected %v, got %v", key, w, v)
+		}
+	}
+}
+
+func TestRun_MissingOID(t *testing.T) {
+	addr := fakeAgent{
+		community: "public",
+		values: map[string]gosnmp.SnmpPDU{
+			"1.3.6.1.2.1.1.3.0": {Type: gosnmp.TimeTicks, Value: uint32(5)},
+		},
+	}.start(t)
+
+	c, err := newCheck(addr, testMetrics(t,
+		map[string]any{"oid": "1.3.6.1.2.1.1.3.0", "label": "uptime"},
+		map[string]any{"oid": "1.3.6.1.2.1.2.2.1.10.99", "label": "gone"},
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if result.Success || result.Err == nil || !strings.Contains(result.Err.Error(), "gone") {
+		t.Errorf("expected failure naming the missing metric, got %v", result.Err)
+	}
+	if v := result.Metrics["uptime"]; v == nil || *v != 5 {
+		t.Errorf("expected uptime 5, got %v", v)
+	}
+	if v, ok := result.Metrics["gone"]; !ok || v != nil {
+		t.Errorf("expected nil entry for missing OID, got %v (present %v)", v, ok)
+	}
+}
+
+func TestRun_ErrorStatus(t *testing.T) {
+	addr := fakeAgent{community: "public", errStatus: gosnmp.GenErr}.start(t)
+	c, err := newCheck(addr, testMetrics(t, map[string]any{"oid": "1.3.6.1.2.1.1.3.0"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := c.Run(context.Background())
+	if result.Success || result.Err == nil {
+		t.Error("expected failure for error status")
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	addr := fakeAgent{community: "public", silent: true}.start(t)
+	c, err := newCheck(addr, testMetrics(t, map[string]any{"oid": "1.3.6.1.2.1.1.3.0", "label": "uptime"}),
+		WithTimeout(100*time.Millisecond), WithRetries(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := c.Run(context.Background())
+	if result.Success || result.Err == nil {
+		t.Error("expected failure for silent agent")
+	}
+	if v, ok := result.Metrics["uptime"]; !ok || v != nil {
+		t.Errorf("expected nil entry, got %v (present %v)", v, ok)
+	}
+}
+
+// --- value() tests ---
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		name       string
+		pdu        gosnmp.SnmpPDU
+		multiplier int64
+		want       float64
+		wantErr    bool
+	}{
+		{"integer", gosnmp.SnmpPDU{Type: gosnmp.Integer, Value: -7}, 1, -7, false},
+		{"counter64 wraps to 63 bits", gosnmp.SnmpPDU{Type: gosnmp.Counter64, Value: uint64(1<<63 + 5)}, 1, 5, false},
+		{"multiplied", gosnmp.SnmpPDU{Type: gosnmp.Counter32, Value: uint(10)}, 8, 80, false},
+		{"numeric string", gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte(" 12.5 ")}, 10, 125, false},
+		{"fractional string", gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte("0.42")}, 1, 0.42, false},
+		{"opaque float", gosnmp.SnmpPDU{Type: gosnmp.OpaqueFloat, Value: float32(1.5)}, 2, 3, false},
+		{"text string", gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte("up")}, 1, 0, true},
+		{"no such instance", gosnmp.SnmpPDU{Type: gosnmp.NoSuchInstance}, 1, 0, true},
+		{"object identifier", gosnmp.SnmpPDU{Type: gosnmp.ObjectIdentifier, Value: ".1.3.6"}, 1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := value(tt.pdu, tt.multiplier)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// --- Factory tests ---
+
+func TestFactory_Valid(t *testing.T) {
+	c, err := Factory(map[string]any{
+		"target":          "router:1161",
+		"version":         "3",
+		"username":        "monitor",
+		"auth_protocol":   "SHA256",
+		"auth_passphrase": "authsecret",
+		"priv_protocol":   "AES",
+		"priv_passphrase": "privsecret",
+		"timeout":         "2s",
+		"request_retries": float64(3),
+		"metrics": []any{
+			map[string]any{"oid": "1.3.6.1.2.1.31.1.1.1.6.1", "label": "in", "type": "derive", "multiplier": float64(8), "unit": "bits"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sc := c.(*Check)
+	if sc.port != 1161 || sc.timeout != 2*time.Second || sc.retries != 3 {
+		t.Errorf("unexpected settings: %+v", sc)
+	}
+	if sc.usm == nil || sc.usm.privProtocol != "AES" {
+		t.Errorf("unexpected USM config %+v", sc.usm)
+	}
+}
+
+func TestFactory_Errors(t *testing.T) {
+	metrics := []any{map[string]any{"oid": "1.3.6.1.2.1.1.3.0"}}
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{"missing target", map[string]any{"metrics": metrics}},
+		{"missing metrics", map[string]any{"target": "h"}},
+		{"empty metrics", map[string]any{"target": "h", "metrics": []any{}}},
+		{"metrics not list", map[string]any{"target": "h", "metrics": "1.3.6"}},
+		{"metric not object", map[string]any{"target": "h", "metrics": []any{"1.3.6"}}},
+		{"missing oid", map[string]any{"target": "h", "metrics": []any{map[string]any{"label": "x"}}}},
+		{"symbolic oid", map[string]any{"target": "h", "metrics": []any{map[string]any{"oid": "sysUpTime.0"}}}},
+		{"bad type", map[string]any{"target": "h", "metrics": []any{map[string]any{"oid": "1.3.6", "type": "rate"}}}},
+		{"fractional multiplier", map[string]any{"target": "h", "metrics": []any{map[string]any{"oid": "1.3.6", "multiplier": 0.5}}}},
+		{"counter multiplier", map[string]any{"target": "h", "metrics": []any{map[string]any{"oid": "1.3.6", "type": "counter", "multiplier": float64(8)}}}},
+		{"zero divisor", map[string]any{"target": "h", "metrics": []any{map[string]any{"oid": "1.3.6", "divisor": float64(0)}}}},
+		{"negative max", map[string]any{"target": "h", "metrics": []any{map[string]any{"oid": "1.3.6", "max": float64(-1)}}}},
+		{"bad version", map[string]any{"target": "h", "metrics": metrics, "version": "1"}},
+		{"v3 key on v2c", map[string]any{"target": "h", "metrics": metrics, "username": "u"}},
+		{"community on v3", map[string]any{"target": "h", "metrics": metrics, "version": "3", "username": "u", "community": "c"}},
+		{"v3 without username", map[string]any{"target": "h", "metrics": metrics, "version": "3"}},
+		{"bad timeout", map[string]any{"target": "h", "metrics": metrics, "timeout": "soon"}},
+		{"fractional retries", map[string]any{"target": "h", "metrics": metrics, "request_retries": 1.5}},
+		{"community not string", map[string]any{"target": "h", "metrics": metrics, "community": 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Factory(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
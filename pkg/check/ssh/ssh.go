@@ -0,0 +1,398 @@
+// Package ssh implements an SSH check that connects to one or more servers,
+// waits for the SSH identification banner and reports how long it took.
+// A server that accepts the connection but never sends its banner (a hung
+// sshd) fails the check at the timeout.
+//
+// Host keys may be pinned by fingerprint. The check then performs the SSH
+// key exchange, which reveals the server's host key without sending any
+// credentials, and fails if the key matches none of the pins — catching a
+// reinstalled host or an unexpected machine answering at the address. The
+// connection is closed before authentication begins.
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// TypeName is the registered name for this check type.
+	TypeName = "ssh"
+
+	// DefaultTimeout is the default per-server timeout.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultPort is used for addresses without a port.
+	DefaultPort = "22"
+)
+
+// clientVersion identifies the check to servers during key exchange.
+const clientVersion = "SSH-2.0-wasgeht"
+
+// maxBannerLines bounds the lines a server may send before its
+// identification string (RFC 4253 section 4.2 allows a few).
+const maxBannerLines = 20
+
+// errHostKeySeen aborts the handshake once the host key has been received.
+var errHostKeySeen = errors.New("host key received")
+
+// targetConfig maps a single server to its RRD data source.
+type targetConfig struct {
+	address   string // host:port to connect to
+	resultKey string // key in Result.Metrics (= address)
+	dsName    string // RRD DS name (e.g. "addr0")
+}
+
+// hostKeyPin is an accepted host key fingerprint, optionally restricted to
+// a key type.
+type hostKeyPin struct {
+	keyType     string // e.g. "ssh-ed25519"; "" matches any type
+	fingerprint string // SHA256 fingerprint, as printed by ssh-keygen -l
+}
+
+// matches reports whether key is the pinned key.
+func (p hostKeyPin) matches(key ssh.PublicKey) bool {
+	return (p.keyType == "" || p.keyType == key.Type()) && p.fingerprint == ssh.FingerprintSHA256(key)
+}
+
+// parsePin parses "SHA256:<base64>" or "<key type> SHA256:<base64>".
+func parsePin(s string) (hostKeyPin, error) {
+	fields := strings.Fields(s)
+	var p hostKeyPin
+	switch len(fields) {
+	case 1:
+		p.fingerprint = fields[0]
+	case 2:
+		p.keyType, p.fingerprint = fields[0], fields[1]
+	default:
+		return p, fmt.Errorf("fingerprint %q must be \"SHA256:...\" or \"<key type> SHA256:...\"", s)
+	}
+	if !strings.HasPrefix(p.fingerprint, "SHA256:") || len(p.fingerprint) == len("SHA256:") {
+		return p, fmt.Errorf("fingerprint %q must be a SHA256 fingerprint (ssh-keygen -l -E sha256)", s)
+	}
+	return p, nil
+}
+
+// Check implements check.Check by connecting to SSH servers.
+type Check struct {
+	targets []targetConfig
+	pins    []hostKeyPin
+	timeout time.Duration
+	dialer  *net.Dialer
+	desc    check.Descriptor
+}
+
+// Option is a functional option for configuring an SSH Check.
+type Option func(*Check) error
+
+// WithTimeout sets the per-server timeout covering connect, banner and
+// key exchange.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Check) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout must be positive, got %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithFingerprints pins the accepted host keys. Each entry is a SHA256
+// fingerprint, optionally preceded by the key type (e.g. "ssh-ed25519
+// SHA256:..."); a typed pin makes the check ask the server for a key of
+// that type, so hosts with several host keys can be pinned reliably.
+func WithFingerprints(fingerprints []string) Option {
+	return func(c *Check) error {
+		for _, f := range fingerprints {
+			p, err := parsePin(f)
+			if err != nil {
+				return err
+			}
+			c.pins = append(c.pins, p)
+		}
+		return nil
+	}
+}
+
+// New creates an SSH Check for the given servers. An address without a
+// port uses port 22.
+func New(addresses []string, opts ...Option) (*Check, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("ssh: at least one address is required")
+	}
+
+	targets := make([]targetConfig, len(addresses))
+	for i, addr := range addresses {
+		if addr == "" {
+			return nil, fmt.Errorf("ssh: address at index %d must not be empty", i)
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, DefaultPort)
+		}
+		targets[i] = targetConfig{
+			address:   addr,
+			resultKey: addr,
+			dsName:    fmt.Sprintf("addr%d", i),
+		}
+	}
+
+	c := &Check{
+		targets: targets,
+		timeout: DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("ssh: %w", err)
+		}
+	}
+
+	c.dialer = &net.Dialer{}
+
+	metrics := make([]check.MetricDef, len(targets))
+	for i, t := range targets {
+		metrics[i] = check.MetricDef{
+			ResultKey: t.resultKey,
+			DSName:    t.dsName,
+			Label:     t.address,
+			Unit:      "ms",
+			Scale:     1000,
+		}
+	}
+	c.desc = check.Descriptor{
+		Label:   "banner latency",
+		Metrics: metrics,
+	}
+
+	return c, nil
+}
+
+// Type returns the check type name.
+func (c *Check) Type() string {
+	return TypeName
+}
+
+// Describe returns the Descriptor for this check instance.
+func (c *Check) Describe() check.Descriptor {
+	return c.desc
+}
+
+// Run probes every server and returns a Result. Each server's banner
+// latency (from connection established to identification string received)
+// is stored in microseconds keyed by its address. Success requires every
+// server to send a banner and, when fingerprints are pinned, to present a
+// pinned host key.
+func (c *Check) Run(ctx context.Context) check.Result {
+	metrics := make(map[string]*float64, len(c.targets))
+	var lastErr error
+
+	for _, t := range c.targets {
+		latency, err := c.probe(ctx, t.address)
+		if err != nil {
+			lastErr = fmt.Errorf("ssh %s: %w", t.address, err)
+			metrics[t.resultKey] = nil
+			continue
+		}
+		v := float64(latency.Microseconds())
+		metrics[t.resultKey] = &v
+	}
+
+	return check.Result{
+		Timestamp: time.Now(),
+		Success:   lastErr == nil,
+		Metrics:   metrics,
+		Err:       lastErr,
+	}
+}
+
+// probe connects to address, reads the banner and, with pins configured,
+// verifies the host key. It returns the banner latency.
+func (c *Check) probe(ctx context.Context, address string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	conn, err := c.dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	start := time.Now()
+	br := bufio.NewReader(conn)
+	consumed, err := readBanner(br)
+	if err != nil {
+		return 0, err
+	}
+	latency := time.Since(start)
+
+	if len(c.pins) == 0 {
+		return latency, nil
+	}
+
+	// Replay the banner for the SSH library, which reads it again.
+	replay := &replayConn{Conn: conn, r: io.MultiReader(bytes.NewReader(consumed), br)}
+	if err := c.verifyHostKey(replay, address); err != nil {
+		return 0, err
+	}
+	return latency, nil
+}
+
+// readBanner reads lines until the SSH identification string and returns
+// everything read. Servers may send other lines before it.
+func readBanner(br *bufio.Reader) ([]byte, error) {
+	var consumed []byte
+	for range maxBannerLines {
+		line, err := br.ReadSlice('\n')
+		consumed = append(consumed, line...)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("connection closed before banner")
+			}
+			return nil, fmt.Errorf("reading banner: %w", err)
+		}
+		if bytes.HasPrefix(line, []byte("SSH-")) {
+			if !bytes.HasPrefix(line, []byte("SSH-2.0-")) && !bytes.HasPrefix(line, []byte("SSH-1.99-")) {
+				return nil, fmt.Errorf("unsupported protocol in banner %q", strings.TrimSpace(string(line)))
+			}
+			return consumed, nil
+		}
+	}
+	return nil, fmt.Errorf("no SSH banner in first %d lines", maxBannerLines)
+}
+
+// verifyHostKey runs the key exchange over conn and checks the host key
+// against the pins. The handshake is aborted as soon as the key is known,
+// so no authentication is attempted.
+func (c *Check) verifyHostKey(conn net.Conn, address string) error {
+	var key ssh.PublicKey
+	config := &ssh.ClientConfig{
+		User:          "wasgeht",
+		ClientVersion: clientVersion,
+		HostKeyCallback: func(_ string, _ net.Addr, k ssh.PublicKey) error {
+			key = k
+			return errHostKeySeen
+		},
+		HostKeyAlgorithms: c.hostKeyAlgorithms(),
+	}
+
+	_, _, _, err := ssh.NewClientConn(conn, address, config)
+	if key == nil {
+		return fmt.Errorf("key exchange: %w", err)
+	}
+	for _, p := range c.pins {
+		if p.matches(key) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host key %s %s matches no pinned fingerprint", key.Type(), ssh.FingerprintSHA256(key))
+}
+
+// hostKeyAlgorithms returns the host key algorithms to offer: those for the
+// pinned key types, or nil (the library defaults) if any pin is untyped.
+func (c *Check) hostKeyAlgorithms() []string {
+	var algos []string
+	for _, p := range c.pins {
+		switch p.keyType {
+		case "":
+			return nil
+		case ssh.KeyAlgoRSA:
+			algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algos = append(algos, p.keyType)
+		}
+	}
+	return algos
+}
+
+// replayConn is a net.Conn whose reads come from r.
+type replayConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *replayConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// Factory creates an SSH Check from a config map.
+// Required keys:
+//   - "addresses" (list of strings) — servers as host or host:port (port 22 by default)
+//
+// Optional keys:
+//   - "timeout" (string) — per-server duration string, default "5s"
+//   - "fingerprints" (list of strings) — pinned host key fingerprints,
+//     "SHA256:..." or "<key type> SHA256:..."
+func Factory(config map[string]any) (check.Check, error) {
+	addresses, err := stringList(config, "addresses")
+	if err != nil {
+		return nil, err
+	}
+	if addresses == nil {
+		return nil, fmt.Errorf("ssh: config missing required key 'addresses'")
+	}
+
+	var opts []Option
+
+	if v, ok := config["timeout"]; ok {
+		ts, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("ssh: 'timeout' must be a duration string, got %T", v)
+		}
+		d, err := time.ParseDuration(ts)
+		if err != nil {
+			return nil, fmt.Errorf("ssh: invalid timeout %q: %w", ts, err)
+		}
+		opts = append(opts, WithTimeout(d))
+	}
+
+	fingerprints, err := stringList(config, "fingerprints")
+	if err != nil {
+		return nil, err
+	}
+	if fingerprints != nil {
+		if len(fingerprints) == 0 {
+			return nil, fmt.Errorf("ssh: 'fingerprints' must not be empty")
+		}
+		opts = append(opts, WithFingerprints(fingerprints))
+	}
+
+	return New(addresses, opts...)
+}
+
+// stringList reads an optional list of strings from config. It returns nil
+// if the key is absent.
+func stringList(config map[string]any, key string) ([]string, error) {
+	raw, ok := config[key]
+	if !ok {
+		return nil, nil
+	}
+	switch v := raw.(type) {
+	case []string:
+		return v, nil
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("ssh: '%s' items must be strings, got %T", key, item)
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("ssh: '%s' must be a list of strings, got %T", key, raw)
+	}
+}
@@ -0,0 +1,282 @@
+package ssh
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// startSSHServer runs an in-process SSH server presenting the given host
+// keys. It never authenticates anyone; the check does not need it to.
+func startSSHServer(t *testing.T, hostKeys ...ssh.Signer) string {
+	t.Helper()
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			t.Error("check attempted password authentication")
+			return nil, nil
+		},
+	}
+	for _, k := range hostKeys {
+		config.AddHostKey(k)
+	}
+	return startServer(t, func(conn net.Conn) {
+		_, _, _, _ = ssh.NewServerConn(conn, config)
+	})
+}
+
+// startServer runs handle for every connection on a localhost listener.
+func startServer(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func ed25519Signer(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	s, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	return s
+}
+
+func ecdsaSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	s, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	return s
+}
+
+// --- New() tests ---
+
+func TestNew_DefaultPort(t *testing.T) {
+	c, err := New([]string{"host.example.com", "10.0.0.1:2222", "::1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"host.example.com:22", "10.0.0.1:2222", "[::1]:22"}
+	for i, w := range want {
+		if c.targets[i].address != w {
+			t.Errorf("target %d: expected %q, got %q", i, w, c.targets[i].address)
+		}
+	}
+	if m := c.Describe().Metrics; len(m) != 3 || m[0].DSName != "addr0" || m[0].Unit != "ms" {
+		t.Errorf("unexpected metrics: %+v", m)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("expected error for no addresses")
+	}
+	if _, err := New([]string{""}); err == nil {
+		t.Error("expected error for empty address")
+	}
+	for _, fp := range []string{"abc", "MD5:aa:bb", "SHA256:", "a b c"} {
+		if _, err := New([]string{"host"}, WithFingerprints([]string{fp})); err == nil {
+			t.Errorf("expected error for fingerprint %q", fp)
+		}
+	}
+}
+
+// --- Run() tests ---
+
+func TestRun_BannerOnly(t *testing.T) {
+	addr := startSSHServer(t, ed25519Signer(t))
+	c, err := New([]string{addr})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if v := result.Metrics[addr]; v == nil || *v < 0 {
+		t.Errorf("expected banner latency, got %v", v)
+	}
+}
+
+func TestRun_PreBannerLines(t *testing.T) {
+	addr := startServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("Welcome\r\nAuthorized use only\r\nSSH-2.0-OpenSSH_9.6\r\n"))
+	})
+	c, _ := New([]string{addr})
+	if result := c.Run(context.Background()); !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(net.Conn)
+		errMsg string
+	}{
+		{"hung sshd", func(conn net.Conn) { time.Sleep(2 * time.Second) }, "banner"},
+		{"closed", func(conn net.Conn) {}, "connection closed before banner"},
+		{"not ssh", func(conn net.Conn) {
+			for range maxBannerLines + 1 {
+				_, _ = conn.Write([]byte("220 smtp.example.com ESMTP\r\n"))
+			}
+		}, "no SSH banner"},
+		{"ssh1", func(conn net.Conn) { _, _ = conn.Write([]byte("SSH-1.5-ancient\r\n")) }, "unsupported protocol"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := startServer(t, tt.handle)
+			c, _ := New([]string{addr}, WithTimeout(300*time.Millisecond))
+			result := c.Run(context.Background())
+			if result.Success {
+				t.Fatal("expected failure")
+			}
+			if !strings.Contains(result.Err.Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errMsg, result.Err)
+			}
+			if v, ok := result.Metrics[addr]; !ok || v != nil {
+				t.Errorf("expected nil metric, got %v (present=%v)", v, ok)
+			}
+		})
+	}
+}
+
+func TestRun_ConnectionRefused(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	ln.Close()
+	c, _ := New([]string{addr})
+	if result := c.Run(context.Background()); result.Success {
+		t.Error("expected failure for refused connection")
+	}
+}
+
+func TestRun_FingerprintMatch(t *testing.T) {
+	key := ed25519Signer(t)
+	addr := startSSHServer(t, key)
+	fp := ssh.FingerprintSHA256(key.PublicKey())
+
+	for _, pin := range []string{fp, "ssh-ed25519 " + fp} {
+		c, err := New([]string{addr}, WithFingerprints([]string{"SHA256:somethingelse", pin}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		result := c.Run(context.Background())
+		if !result.Success {
+			t.Errorf("pin %q: expected success, got %v", pin, result.Err)
+		}
+		if result.Metrics[addr] == nil {
+			t.Errorf("pin %q: expected banner latency", pin)
+		}
+	}
+}
+
+func TestRun_FingerprintMismatch(t *testing.T) {
+	addr := startSSHServer(t, ed25519Signer(t))
+	old := ssh.FingerprintSHA256(ed25519Signer(t).PublicKey())
+
+	c, _ := New([]string{addr}, WithFingerprints([]string{old}))
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Fatal("expected failure for reinstalled host key")
+	}
+	if !strings.Contains(result.Err.Error(), "matches no pinned fingerprint") ||
+		!strings.Contains(result.Err.Error(), "ssh-ed25519") {
+		t.Errorf("unexpected error: %v", result.Err)
+	}
+}
+
+func TestRun_TypedPinSelectsKey(t *testing.T) {
+	ed, ec := ed25519Signer(t), ecdsaSigner(t)
+	addr := startSSHServer(t, ed, ec)
+
+	// Pinning only the ECDSA key must make the server present it, even
+	// though the client would otherwise prefer ed25519.
+	pin := ec.PublicKey().Type() + " " + ssh.FingerprintSHA256(ec.PublicKey())
+	c, _ := New([]string{addr}, WithFingerprints([]string{pin}))
+	if result := c.Run(context.Background()); !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+
+	// A typed pin for a key type the server lacks fails the exchange.
+	c, _ = New([]string{addr}, WithFingerprints([]string{"ssh-rsa SHA256:abc"}))
+	if result := c.Run(context.Background()); result.Success {
+		t.Error("expected failure when the server has no key of the pinned type")
+	}
+}
+
+// --- Factory tests ---
+
+func TestFactory_Valid(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"addresses":    []any{"host.example.com", "10.0.0.1:2222"},
+		"timeout":      "2s",
+		"fingerprints": []any{"ssh-ed25519 SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := chk.(*Check)
+	if c.timeout != 2*time.Second {
+		t.Errorf("expected 2s timeout, got %v", c.timeout)
+	}
+	if len(c.pins) != 1 || c.pins[0].keyType != "ssh-ed25519" {
+		t.Errorf("unexpected pins %+v", c.pins)
+	}
+	if got := c.hostKeyAlgorithms(); len(got) != 1 || got[0] != "ssh-ed25519" {
+		t.Errorf("expected ed25519 host key algorithm, got %v", got)
+	}
+}
+
+func TestFactory_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{"missing addresses", map[string]any{}},
+		{"addresses wrong type", map[string]any{"addresses": "host"}},
+		{"address not string", map[string]any{"addresses": []any{float64(1)}}},
+		{"empty fingerprints", map[string]any{"addresses": []any{"host"}, "fingerprints": []any{}}},
+		{"bad fingerprint", map[string]any{"addresses": []any{"host"}, "fingerprints": []any{"md5"}}},
+		{"bad timeout", map[string]any{"addresses": []any{"host"}, "timeout": "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Factory(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
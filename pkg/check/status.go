@@ -1,16 +1,43 @@
 package check
 
 import (
+	"math"
+	"slices"
 	"sync"
+	"time"
+)
+
+// StateType tells whether a check's state is confirmed or still being
+// retried.
+type StateType string
+
+const (
+	// StateHard is a confirmed state: the last attempt succeeded, or it
+	// failed and no retries are left.
+	StateHard StateType = "hard"
+
+	// StateSoft is a failure that has not been confirmed yet. The check is
+	// retried and keeps reporting its previous hard state meanwhile.
+	StateSoft StateType = "soft"
 )
 
 // Status tracks the latest result of a check execution.
 // It is safe for concurrent reads via the exported accessor methods,
 // but writes should be done through SetResult.
+//
+// A failure only becomes hard after the configured number of retries
+// have failed as well. Until then the failure is soft: Alive, Degraded
+// and Unknown keep reporting the last hard result, while metrics come
+// from the latest attempt.
 type Status struct {
 	mu         sync.RWMutex
-	lastResult Result
+	lastResult Result // latest attempt
+	hardResult Result // result that set the current hard state
+	attempt    int    // consecutive failed attempts
+	retries    int    // failed attempts retried before a failure is hard
 	lastUpdate int64
+	interval   time.Duration
+	graphs     []string // extra graph names from the check's descriptor
 }
 
 // NewStatus creates a Status with zero values (not alive, no metrics).
@@ -18,23 +45,72 @@ func NewStatus() *Status {
 	return &Status{}
 }
 
-// Alive returns whether the check's last execution was successful.
+// Alive returns whether the check's hard state is successful.
 func (s *Status) Alive() bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.lastResult.Success
+	return s.hardResult.Success
+}
+
+// Degraded returns whether the check's hard state succeeded with a
+// warning condition.
+func (s *Status) Degraded() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.hardResult.Success && s.hardResult.Degraded
+}
+
+// Unknown returns whether the check's hard state failed without
+// determining the target's state.
+func (s *Status) Unknown() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.hardResult.Success && s.hardResult.Unknown
+}
+
+// State returns whether the latest attempt confirmed the check's state
+// (StateHard) or failed with retries left (StateSoft).
+func (s *Status) State() StateType {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state()
+}
+
+// state returns the state type. The caller must hold the lock.
+func (s *Status) state() StateType {
+	if s.attempt > 0 && s.attempt <= s.retries {
+		return StateSoft
+	}
+	return StateHard
+}
+
+// Attempt returns the number of consecutive failed attempts, or 0 if the
+// latest attempt succeeded.
+func (s *Status) Attempt() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.attempt
+}
+
+// SetRetries sets how many times a failed check is retried before the
+// failure becomes hard. The default of 0 makes every failure hard.
+func (s *Status) SetRetries(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retries = n
 }
 
 // Metric returns the value of a named metric from the last result.
-// Returns the value and true if found and non-nil, or 0 and false otherwise.
-func (s *Status) Metric(key string) (int64, bool) {
+// Returns the value and true if found, non-nil and finite, or 0 and false
+// otherwise.
+func (s *Status) Metric(key string) (float64, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	if !s.lastResult.Success || s.lastResult.Metrics == nil {
 		return 0, false
 	}
 	v, ok := s.lastResult.Metrics[key]
-	if !ok || v == nil {
+	if !ok || !finite(v) {
 		return 0, false
 	}
 	return *v, true
@@ -47,11 +123,51 @@ func (s *Status) LastUpdate() int64 {
 	return s.lastUpdate
 }
 
-// SetResult stores the latest check result.
+// Interval returns how often the check is scheduled to run, or 0 if it
+// has not been set.
+func (s *Status) Interval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.interval
+}
+
+// SetInterval records how often the check is scheduled to run.
+func (s *Status) SetInterval(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interval = d
+}
+
+// Graphs returns the names of the check's graphs drawn besides its main
+// graph, or nil if it has none.
+func (s *Status) Graphs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.graphs)
+}
+
+// SetGraphs records the names of the check's graphs drawn besides its main
+// graph, as returned by Descriptor.Graphs.
+func (s *Status) SetGraphs(graphs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.graphs = slices.Clone(graphs)
+}
+
+// SetResult stores the latest check result. A success is always hard; a
+// failure becomes hard once it has been retried as many times as allowed.
 func (s *Status) SetResult(result Result) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.lastResult = result
+	if result.Success {
+		s.attempt = 0
+	} else {
+		s.attempt++
+	}
+	if s.state() == StateHard {
+		s.hardResult = result
+	}
 }
 
 // SetLastUpdate records the unix timestamp of the last successful RRD update.
@@ -67,12 +183,13 @@ func (s *Status) Snapshot() StatusSnapshot {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Deep copy the metrics map so the snapshot is independent
-	var metrics map[string]*int64
+	// Deep copy the metrics map so the snapshot is independent. Values
+	// that are not finite cannot be encoded as JSON and are reported as nil.
+	var metrics map[string]*float64
 	if s.lastResult.Metrics != nil {
-		metrics = make(map[string]*int64, len(s.lastResult.Metrics))
+		metrics = make(map[string]*float64, len(s.lastResult.Metrics))
 		for k, v := range s.lastResult.Metrics {
-			if v != nil {
+			if finite(v) {
 				cv := *v
 				metrics[k] = &cv
 			} else {
@@ -82,15 +199,32 @@ func (s *Status) Snapshot() StatusSnapshot {
 	}
 
 	return StatusSnapshot{
-		Alive:      s.lastResult.Success,
+		Alive:      s.hardResult.Success,
+		Degraded:   s.hardResult.Success && s.hardResult.Degraded,
+		Unknown:    !s.hardResult.Success && s.hardResult.Unknown,
+		State:      s.state(),
+		Attempt:    s.attempt,
 		Metrics:    metrics,
 		LastUpdate: s.lastUpdate,
+		Interval:   s.interval,
+		Graphs:     slices.Clone(s.graphs),
 	}
 }
 
 // StatusSnapshot is a point-in-time copy of Status fields.
 type StatusSnapshot struct {
 	Alive      bool
-	Metrics    map[string]*int64
+	Degraded   bool
+	Unknown    bool
+	State      StateType
+	Attempt    int // consecutive failed attempts
+	Metrics    map[string]*float64
 	LastUpdate int64
+	Interval   time.Duration
+	Graphs     []string // extra graph names, see Status.Graphs
+}
+
+// finite reports whether v is a non-nil, finite metric value.
+func finite(v *float64) bool {
+	return v != nil && !math.IsNaN(*v) && !math.IsInf(*v, 0)
 }
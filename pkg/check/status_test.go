@@ -1,11 +1,13 @@
 package check
 
 import (
+	"math"
 	"sync"
 	"testing"
+	"time"
 )
 
-func p64(v int64) *int64 { return &v }
+func p64(v float64) *float64 { return &v }
 
 func TestNewStatus_ZeroValues(t *testing.T) {
 	s := NewStatus()
@@ -13,7 +15,7 @@ func TestNewStatus_ZeroValues(t *testing.T) {
 		t.Error("new status should not be alive")
 	}
 	if v, ok := s.Metric("latency_us"); ok {
-		t.Errorf("new status should have no metrics, got latency_us=%d", v)
+		t.Errorf("new status should have no metrics, got latency_us=%v", v)
 	}
 	if s.LastUpdate() != 0 {
 		t.Errorf("new status should have zero last update, got %d", s.LastUpdate())
@@ -24,7 +26,7 @@ func TestStatus_SetResult_Success(t *testing.T) {
 	s := NewStatus()
 	s.SetResult(Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(1234)},
+		Metrics: map[string]*float64{"latency_us": p64(1234)},
 	})
 
 	if !s.Alive() {
@@ -35,7 +37,7 @@ func TestStatus_SetResult_Success(t *testing.T) {
 		t.Fatal("expected latency_us metric to be present")
 	}
 	if v != 1234 {
-		t.Errorf("expected latency_us=1234, got %d", v)
+		t.Errorf("expected latency_us=1234, got %v", v)
 	}
 }
 
@@ -44,7 +46,7 @@ func TestStatus_SetResult_Failure(t *testing.T) {
 	// First set it alive
 	s.SetResult(Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(1000)},
+		Metrics: map[string]*float64{"latency_us": p64(1000)},
 	})
 	// Then fail
 	s.SetResult(Result{
@@ -63,7 +65,7 @@ func TestStatus_SetResult_SuccessWithoutMetrics(t *testing.T) {
 	s := NewStatus()
 	s.SetResult(Result{
 		Success: true,
-		Metrics: map[string]*int64{},
+		Metrics: map[string]*float64{},
 	})
 
 	if !s.Alive() {
@@ -78,7 +80,7 @@ func TestStatus_Metric_MultipleMetrics(t *testing.T) {
 	s := NewStatus()
 	s.SetResult(Result{
 		Success: true,
-		Metrics: map[string]*int64{
+		Metrics: map[string]*float64{
 			"latency_us":    p64(12340),
 			"response_code": p64(2000),
 		},
@@ -86,17 +88,147 @@ func TestStatus_Metric_MultipleMetrics(t *testing.T) {
 
 	v, ok := s.Metric("latency_us")
 	if !ok || v != 12340 {
-		t.Errorf("expected latency_us=12340, got %d (ok=%v)", v, ok)
+		t.Errorf("expected latency_us=12340, got %v (ok=%v)", v, ok)
 	}
 	v, ok = s.Metric("response_code")
 	if !ok || v != 2000 {
-		t.Errorf("expected response_code=2000, got %d (ok=%v)", v, ok)
+		t.Errorf("expected response_code=2000, got %v (ok=%v)", v, ok)
 	}
 	if _, ok := s.Metric("nonexistent"); ok {
 		t.Error("expected nonexistent metric to not be found")
 	}
 }
 
+func TestStatus_Metric_Fractional(t *testing.T) {
+	s := NewStatus()
+	s.SetResult(Result{
+		Success: true,
+		Metrics: map[string]*float64{
+			"temp": p64(21.5),
+			"nan":  p64(math.NaN()),
+		},
+	})
+
+	if v, ok := s.Metric("temp"); !ok || v != 21.5 {
+		t.Errorf("expected temp=21.5, got %v (ok=%v)", v, ok)
+	}
+	if v, ok := s.Metric("nan"); ok {
+		t.Errorf("expected NaN to be reported as missing, got %v", v)
+	}
+
+	snap := s.Snapshot()
+	if v, ok := snap.Metrics["nan"]; !ok || v != nil {
+		t.Errorf("expected nil snapshot entry for NaN, got %v (present %v)", v, ok)
+	}
+	if v := snap.Metrics["temp"]; v == nil || *v != 21.5 {
+		t.Errorf("expected snapshot temp=21.5, got %v", v)
+	}
+}
+
+func TestStatus_Degraded(t *testing.T) {
+	s := NewStatus()
+	s.SetResult(Result{Success: true, Degraded: true})
+	if !s.Degraded() {
+		t.Error("expected Degraded true for successful warned result")
+	}
+	if !s.Snapshot().Degraded {
+		t.Error("expected snapshot Degraded true")
+	}
+
+	s.SetResult(Result{Success: false, Degraded: true})
+	if s.Degraded() {
+		t.Error("expected Degraded false when the result failed")
+	}
+	if s.Snapshot().Degraded {
+		t.Error("expected snapshot Degraded false when the result failed")
+	}
+}
+
+func TestStatus_Unknown(t *testing.T) {
+	s := NewStatus()
+	s.SetResult(Result{Success: false, Unknown: true})
+	if !s.Unknown() {
+		t.Error("expected Unknown true for failed unknown result")
+	}
+	if !s.Snapshot().Unknown {
+		t.Error("expected snapshot Unknown true")
+	}
+
+	s.SetResult(Result{Success: true, Unknown: true})
+	if s.Unknown() {
+		t.Error("expected Unknown false when the result succeeded")
+	}
+	if s.Snapshot().Unknown {
+		t.Error("expected snapshot Unknown false when the result succeeded")
+	}
+}
+
+func TestStatus_FailureWithoutRetriesIsHard(t *testing.T) {
+	s := NewStatus()
+	s.SetResult(Result{Success: true})
+	s.SetResult(Result{Success: false})
+
+	if s.Alive() {
+		t.Error("expected not alive after failure without retries")
+	}
+	if s.State() != StateHard {
+		t.Errorf("expected hard state, got %q", s.State())
+	}
+	if s.Attempt() != 1 {
+		t.Errorf("expected attempt 1, got %d", s.Attempt())
+	}
+}
+
+func TestStatus_SoftFailure(t *testing.T) {
+	s := NewStatus()
+	s.SetRetries(2)
+	s.SetResult(Result{Success: true, Degraded: true})
+
+	// Two failures are retried and keep the previous hard state.
+	for attempt := 1; attempt <= 2; attempt++ {
+		s.SetResult(Result{Success: false, Unknown: true, Metrics: map[string]*float64{"latency_us": nil}})
+		if !s.Alive() || !s.Degraded() || s.Unknown() {
+			t.Errorf("attempt %d: expected previous hard state (alive, degraded), got alive=%v degraded=%v unknown=%v",
+				attempt, s.Alive(), s.Degraded(), s.Unknown())
+		}
+		snap := s.Snapshot()
+		if snap.State != StateSoft || snap.Attempt != attempt {
+			t.Errorf("attempt %d: expected soft state, got %q (attempt %d)", attempt, snap.State, snap.Attempt)
+		}
+		if v, ok := snap.Metrics["latency_us"]; !ok || v != nil {
+			t.Errorf("attempt %d: expected metrics from the latest attempt, got %v (present %v)", attempt, v, ok)
+		}
+	}
+
+	// The third consecutive failure is hard.
+	s.SetResult(Result{Success: false, Unknown: true})
+	if s.Alive() || !s.Unknown() {
+		t.Errorf("expected hard unknown failure, got alive=%v unknown=%v", s.Alive(), s.Unknown())
+	}
+	if s.State() != StateHard || s.Attempt() != 3 {
+		t.Errorf("expected hard state at attempt 3, got %q at %d", s.State(), s.Attempt())
+	}
+
+	// Recovery is immediate and hard.
+	s.SetResult(Result{Success: true})
+	if !s.Alive() || s.State() != StateHard || s.Attempt() != 0 {
+		t.Errorf("expected hard recovery, got alive=%v state=%q attempt=%d", s.Alive(), s.State(), s.Attempt())
+	}
+}
+
+func TestStatus_SoftFailureBeforeFirstHardState(t *testing.T) {
+	s := NewStatus()
+	s.SetRetries(1)
+	s.SetResult(Result{Success: false})
+
+	if s.Alive() {
+		t.Error("expected not alive when no hard success was recorded yet")
+	}
+	if s.State() != StateSoft {
+		t.Errorf("expected soft state, got %q", s.State())
+	}
+}
+
 func TestStatus_SetLastUpdate(t *testing.T) {
 	s := NewStatus()
 	s.SetLastUpdate(1700000000)
@@ -106,11 +238,43 @@ func TestStatus_SetLastUpdate(t *testing.T) {
 	}
 }
 
+func TestStatus_SetInterval(t *testing.T) {
+	s := NewStatus()
+	if s.Interval() != 0 {
+		t.Errorf("new status should have zero interval, got %v", s.Interval())
+	}
+
+	s.SetInterval(time.Hour)
+	if s.Interval() != time.Hour {
+		t.Errorf("expected interval 1h, got %v", s.Interval())
+	}
+	if snap := s.Snapshot(); snap.Interval != time.Hour {
+		t.Errorf("snapshot interval: expected 1h, got %v", snap.Interval)
+	}
+}
+
+func TestStatus_SetGraphs(t *testing.T) {
+	s := NewStatus()
+	if s.Graphs() != nil {
+		t.Errorf("new status should have no graphs, got %v", s.Graphs())
+	}
+
+	graphs := []string{"loss"}
+	s.SetGraphs(graphs)
+	graphs[0] = "changed"
+	if got := s.Graphs(); len(got) != 1 || got[0] != "loss" {
+		t.Errorf("expected graphs [loss], got %v", got)
+	}
+	if snap := s.Snapshot(); len(snap.Graphs) != 1 || snap.Graphs[0] != "loss" {
+		t.Errorf("snapshot graphs: expected [loss], got %v", snap.Graphs)
+	}
+}
+
 func TestStatus_Snapshot(t *testing.T) {
 	s := NewStatus()
 	s.SetResult(Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(5678)},
+		Metrics: map[string]*float64{"latency_us": p64(5678)},
 	})
 	s.SetLastUpdate(1700000000)
 
@@ -132,7 +296,7 @@ func TestStatus_Snapshot_Independent(t *testing.T) {
 	s := NewStatus()
 	s.SetResult(Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(1000)},
+		Metrics: map[string]*float64{"latency_us": p64(1000)},
 	})
 
 	snap := s.Snapshot()
@@ -153,7 +317,7 @@ func TestStatus_Snapshot_MetricsMapIndependent(t *testing.T) {
 	s := NewStatus()
 	s.SetResult(Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(1000)},
+		Metrics: map[string]*float64{"latency_us": p64(1000)},
 	})
 
 	snap := s.Snapshot()
@@ -164,7 +328,7 @@ func TestStatus_Snapshot_MetricsMapIndependent(t *testing.T) {
 	// Status should be unaffected
 	v, ok := s.Metric("latency_us")
 	if !ok || v != 1000 {
-		t.Errorf("mutating snapshot should not affect status, got %d", v)
+		t.Errorf("mutating snapshot should not affect status, got %v", v)
 	}
 }
 
@@ -179,7 +343,7 @@ func TestStatus_ConcurrentAccess(t *testing.T) {
 			defer wg.Done()
 			s.SetResult(Result{
 				Success: true,
-				Metrics: map[string]*int64{"latency_us": p64(int64(n))},
+				Metrics: map[string]*float64{"latency_us": p64(float64(n))},
 			})
 			s.SetLastUpdate(int64(n))
 		}(i)
@@ -0,0 +1,212 @@
+// Package tcp implements a TCP connect check that dials one or more
+// host:port targets and reports per-target connect time. The check
+// succeeds only when every configured target accepts the connection;
+// a refused or timed-out connect fails the check. No data is sent or
+// read once the connection is established.
+package tcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+)
+
+const (
+	// TypeName is the registered name for this check type.
+	TypeName = "tcp"
+
+	// DefaultTimeout is the default per-target connect timeout.
+	DefaultTimeout = 3 * time.Second
+)
+
+// targetConfig maps a single host:port target to its RRD data source.
+type targetConfig struct {
+	address   string // host:port to dial
+	resultKey string // key in Result.Metrics (same as address)
+	dsName    string // RRD DS name (e.g. "addr0")
+	label     string // human-readable label (same as address)
+}
+
+// Check implements check.Check using TCP connects to one or more targets.
+type Check struct {
+	targets []targetConfig
+	timeout time.Duration
+	dialer  *net.Dialer
+	desc    check.Descriptor
+}
+
+// Option is a functional option for configuring a TCP Check.
+type Option func(*Check) error
+
+// WithTimeout sets the per-target connect timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Check) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout must be positive, got %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// New creates a TCP Check for the given host:port targets.
+func New(targets []string, opts ...Option) (*Check, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("tcp: at least one target is required")
+	}
+
+	tgts := make([]targetConfig, len(targets))
+	for i, t := range targets {
+		if t == "" {
+			return nil, fmt.Errorf("tcp: target at index %d must not be empty", i)
+		}
+		host, port, err := net.SplitHostPort(t)
+		if err != nil {
+			return nil, fmt.Errorf("tcp: invalid target %q: %w", t, err)
+		}
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("tcp: target %q must be in host:port form", t)
+		}
+		tgts[i] = targetConfig{
+			address:   t,
+			resultKey: t,
+			dsName:    fmt.Sprintf("addr%d", i),
+			label:     t,
+		}
+	}
+
+	c := &Check{
+		targets: tgts,
+		timeout: DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("tcp: %w", err)
+		}
+	}
+
+	c.dialer = &net.Dialer{Timeout: c.timeout}
+
+	metrics := make([]check.MetricDef, len(tgts))
+	for i, t := range tgts {
+		metrics[i] = check.MetricDef{
+			ResultKey: t.resultKey,
+			DSName:    t.dsName,
+			Label:     t.label,
+			Unit:      "ms",
+			Scale:     1000,
+		}
+	}
+	c.desc = check.Descriptor{
+		Label:   "tcp connect",
+		Metrics: metrics,
+	}
+
+	return c, nil
+}
+
+// Type returns the check type name.
+func (c *Check) Type() string {
+	return TypeName
+}
+
+// Describe returns the Descriptor for this check instance.
+// One metric is produced per configured target.
+func (c *Check) Describe() check.Descriptor {
+	return c.desc
+}
+
+// Run dials all configured targets and returns a Result.
+// Success requires every target to accept the connection. Each target's
+// connect time is stored in microseconds keyed by the host:port string.
+func (c *Check) Run(ctx context.Context) check.Result {
+	metrics := make(map[string]*float64, len(c.targets))
+	var lastErr error
+	succeeded := 0
+
+	for _, t := range c.targets {
+		start := time.Now()
+		conn, err := c.dialer.DialContext(ctx, "tcp", t.address)
+		elapsed := time.Since(start)
+
+		if err != nil {
+			lastErr = fmt.Errorf("tcp %s: %w", t.address, err)
+			metrics[t.resultKey] = nil
+			continue
+		}
+		conn.Close()
+
+		v := float64(elapsed.Microseconds())
+		metrics[t.resultKey] = &v
+		succeeded++
+	}
+
+	return check.Result{
+		Timestamp: time.Now(),
+		Success:   succeeded == len(c.targets),
+		Err:       lastErr,
+		Metrics:   metrics,
+	}
+}
+
+// Factory creates a TCP Check from a config map.
+// Required keys: "targets" (list of host:port strings).
+// Optional keys:
+//   - "timeout" (string) — duration string (e.g. "5s"), default "3s"
+func Factory(config map[string]any) (check.Check, error) {
+	targets, err := extractTargets(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []Option
+
+	if v, ok := config["timeout"]; ok {
+		ts, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("tcp: 'timeout' must be a string, got %T", v)
+		}
+		d, err := time.ParseDuration(ts)
+		if err != nil {
+			return nil, fmt.Errorf("tcp: invalid timeout %q: %w", ts, err)
+		}
+		opts = append(opts, WithTimeout(d))
+	}
+
+	return New(targets, opts...)
+}
+
+// extractTargets pulls the targets list from the config map.
+func extractTargets(config map[string]any) ([]string, error) {
+	raw, ok := config["targets"]
+	if !ok {
+		return nil, fmt.Errorf("tcp: config missing required key 'targets'")
+	}
+
+	switch v := raw.(type) {
+	case []string:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("tcp: 'targets' must not be empty")
+		}
+		return v, nil
+	case []any:
+		targets := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("tcp: 'targets' items must be strings, got %T", item)
+			}
+			targets = append(targets, s)
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("tcp: 'targets' must not be empty")
+		}
+		return targets, nil
+	default:
+		return nil, fmt.Errorf("tcp: 'targets' must be a list, got %T", raw)
+	}
+}
@@ -0,0 +1,293 @@
+package tcp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+)
+
+// startListener opens a TCP listener on a random localhost port that
+// accepts and immediately closes connections. It is closed when the test ends.
+func startListener(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return ln.Addr().String()
+}
+
+// closedAddr returns a localhost address that is not accepting connections.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+// --- New() tests ---
+
+func TestNew_Valid(t *testing.T) {
+	c, err := New([]string{"127.0.0.1:22"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Type() != TypeName {
+		t.Errorf("expected type %q, got %q", TypeName, c.Type())
+	}
+	if c.timeout != DefaultTimeout {
+		t.Errorf("expected default timeout, got %v", c.timeout)
+	}
+}
+
+func TestNew_EmptyTargets(t *testing.T) {
+	_, err := New([]string{})
+	if err == nil {
+		t.Error("expected error for empty targets")
+	}
+}
+
+func TestNew_EmptyTargetInList(t *testing.T) {
+	_, err := New([]string{"127.0.0.1:22", ""})
+	if err == nil {
+		t.Error("expected error for empty target in list")
+	}
+}
+
+func TestNew_MissingPort(t *testing.T) {
+	_, err := New([]string{"127.0.0.1"})
+	if err == nil {
+		t.Error("expected error for target without port")
+	}
+}
+
+func TestNew_EmptyHost(t *testing.T) {
+	_, err := New([]string{":22"})
+	if err == nil {
+		t.Error("expected error for target without host")
+	}
+}
+
+func TestNew_WithTimeout(t *testing.T) {
+	c, err := New([]string{"127.0.0.1:22"}, WithTimeout(7*time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.timeout != 7*time.Second {
+		t.Errorf("expected timeout 7s, got %v", c.timeout)
+	}
+}
+
+func TestNew_WithTimeoutZero(t *testing.T) {
+	_, err := New([]string{"127.0.0.1:22"}, WithTimeout(0))
+	if err == nil {
+		t.Error("expected error for zero timeout")
+	}
+}
+
+// --- Describe tests ---
+
+func TestDescribe_MultipleTargets(t *testing.T) {
+	targets := []string{"db.example.com:5432", "[2001:db8::1]:1883"}
+	c, err := New(targets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	desc := c.Describe()
+	if desc.Label != "tcp connect" {
+		t.Errorf("expected Descriptor.Label 'tcp connect', got %q", desc.Label)
+	}
+	if len(desc.Metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(desc.Metrics))
+	}
+	for i, target := range targets {
+		m := desc.Metrics[i]
+		if m.ResultKey != target {
+			t.Errorf("metric %d: expected ResultKey %q, got %q", i, target, m.ResultKey)
+		}
+		if want := []string{"addr0", "addr1"}[i]; m.DSName != want {
+			t.Errorf("metric %d: expected DSName %q, got %q", i, want, m.DSName)
+		}
+		if m.Unit != "ms" {
+			t.Errorf("metric %d: expected Unit 'ms', got %q", i, m.Unit)
+		}
+		if m.Scale != 1000 {
+			t.Errorf("metric %d: expected Scale 1000, got %d", i, m.Scale)
+		}
+	}
+}
+
+// --- Factory tests ---
+
+func TestFactory_MinimalConfig(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"targets": []any{"127.0.0.1:22"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tcpChk := chk.(*Check)
+	if tcpChk.timeout != DefaultTimeout {
+		t.Errorf("expected default timeout, got %v", tcpChk.timeout)
+	}
+}
+
+func TestFactory_WithTimeout(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"targets": []any{"127.0.0.1:22"},
+		"timeout": "5s",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chk.(*Check).timeout != 5*time.Second {
+		t.Errorf("expected timeout 5s, got %v", chk.(*Check).timeout)
+	}
+}
+
+func TestFactory_StringSliceTargets(t *testing.T) {
+	_, err := Factory(map[string]any{
+		"targets": []string{"127.0.0.1:22"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestFactory_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{"missing targets", map[string]any{}},
+		{"empty targets", map[string]any{"targets": []any{}}},
+		{"wrong targets type", map[string]any{"targets": "127.0.0.1:22"}},
+		{"non-string target", map[string]any{"targets": []any{22}}},
+		{"invalid timeout", map[string]any{"targets": []any{"127.0.0.1:22"}, "timeout": "soon"}},
+		{"wrong timeout type", map[string]any{"targets": []any{"127.0.0.1:22"}, "timeout": 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Factory(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestRegistryIntegration(t *testing.T) {
+	reg := check.NewRegistry()
+	if err := reg.Register(TypeName, Factory); err != nil {
+		t.Fatalf("failed to register tcp: %v", err)
+	}
+	chk, err := reg.Create(TypeName, map[string]any{
+		"targets": []any{"127.0.0.1:22", "127.0.0.1:5432"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create tcp check: %v", err)
+	}
+	if len(chk.Describe().Metrics) != 2 {
+		t.Errorf("expected 2 metrics, got %d", len(chk.Describe().Metrics))
+	}
+}
+
+func TestCheckInterface(t *testing.T) {
+	var _ check.Check = &Check{}
+}
+
+// --- Run tests ---
+
+func TestRun_Success(t *testing.T) {
+	addr := startListener(t)
+
+	c, err := New([]string{addr})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Errorf("expected success, got failure: %v", result.Err)
+	}
+	if p := result.Metrics[addr]; p == nil || *p < 0 {
+		t.Errorf("expected non-negative connect time, got %v", result.Metrics[addr])
+	}
+	if result.Timestamp.IsZero() {
+		t.Error("expected non-zero timestamp")
+	}
+}
+
+func TestRun_Refused(t *testing.T) {
+	addr := closedAddr(t)
+
+	c, err := New([]string{addr}, WithTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Error("expected failure for refused connection")
+	}
+	if result.Err == nil {
+		t.Error("expected non-nil error")
+	}
+	if v, ok := result.Metrics[addr]; !ok || v != nil {
+		t.Errorf("expected nil metric for refused target, got %v (present=%v)", v, ok)
+	}
+}
+
+func TestRun_PartialFailure(t *testing.T) {
+	good := startListener(t)
+	bad := closedAddr(t)
+
+	c, err := New([]string{good, bad}, WithTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Error("expected failure when any target is refused")
+	}
+	if result.Metrics[good] == nil {
+		t.Error("expected metric for reachable target")
+	}
+	if result.Metrics[bad] != nil {
+		t.Error("expected nil metric for refused target")
+	}
+}
+
+func TestRun_ContextCancelled(t *testing.T) {
+	addr := startListener(t)
+
+	c, err := New([]string{addr})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := c.Run(ctx)
+	if result.Success {
+		t.Error("expected failure with cancelled context")
+	}
+}
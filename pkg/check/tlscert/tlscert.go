@@ -0,0 +1,319 @@
+// Package tlscert implements a TLS certificate check that handshakes with
+// one or more host:port endpoints and reports the number of days until each
+// leaf certificate expires.
+//
+// The presented chain is always verified against the system roots (or a
+// configured CA bundle) and the expected hostname, so a misissued or
+// untrusted certificate fails the check even when it has not expired.
+// Certificates expiring within the critical threshold fail the check;
+// certificates expiring within the warning threshold mark it degraded.
+package tlscert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+	"github.com/kylerisse/wasgeht/pkg/check/internal/certs"
+)
+
+const (
+	// TypeName is the registered name for this check type.
+	TypeName = "tls_cert"
+
+	// DefaultTimeout is the default per-endpoint handshake timeout.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultWarningDays is the default number of remaining days below
+	// which the check is marked degraded.
+	DefaultWarningDays = certs.DefaultWarningDays
+
+	// DefaultCriticalDays is the default number of remaining days below
+	// which the check fails.
+	DefaultCriticalDays = certs.DefaultCriticalDays
+)
+
+// targetConfig holds the parsed configuration for a single endpoint.
+type targetConfig struct {
+	address    string // host:port to connect to
+	serverName string // SNI and verification hostname (defaults to the host part of address)
+	resultKey  string // key in Result.Metrics
+	dsName     string // RRD DS name (e.g. "addr0")
+	label      string // human-readable label
+}
+
+// Check implements check.Check by inspecting the certificates presented
+// by one or more TLS endpoints.
+type Check struct {
+	targets []targetConfig
+	timeout time.Duration
+	policy  certs.Policy
+	desc    check.Descriptor
+}
+
+// Option is a functional option for configuring a TLS certificate Check.
+type Option func(*Check) error
+
+// WithTimeout sets the per-endpoint handshake timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Check) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout must be positive, got %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithWarningDays sets the remaining-days threshold below which the check
+// is marked degraded.
+func WithWarningDays(n int) Option {
+	return func(c *Check) error {
+		return c.policy.SetWarningDays(n)
+	}
+}
+
+// WithCriticalDays sets the remaining-days threshold below which the
+// check fails.
+func WithCriticalDays(n int) Option {
+	return func(c *Check) error {
+		return c.policy.SetCriticalDays(n)
+	}
+}
+
+// WithRootCAs sets the pool of trusted root certificates used for chain
+// verification instead of the system roots.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *Check) error {
+		return c.policy.SetRoots(pool)
+	}
+}
+
+// New creates a TLS certificate Check for the given host:port endpoints,
+// each verified against its own host name.
+func New(addresses []string, opts ...Option) (*Check, error) {
+	targets := make([]targetConfig, len(addresses))
+	for i, a := range addresses {
+		t, err := certs.NewTarget(a, "", "")
+		if err != nil {
+			return nil, fmt.Errorf("tls_cert: target at index %d: %w", i, err)
+		}
+		targets[i] = newTargetConfig(i, t)
+	}
+	return newCheck(targets, opts...)
+}
+
+// newCheck creates a TLS certificate Check for fully parsed targets.
+func newCheck(targets []targetConfig, opts ...Option) (*Check, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("tls_cert: at least one target is required")
+	}
+
+	c := &Check{
+		targets: targets,
+		timeout: DefaultTimeout,
+		policy:  certs.DefaultPolicy(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, fmt.Errorf("tls_cert: %w", err)
+		}
+	}
+
+	if err := c.policy.Validate(); err != nil {
+		return nil, fmt.Errorf("tls_cert: %w", err)
+	}
+
+	metrics := make([]check.MetricDef, len(targets))
+	for i, t := range targets {
+		metrics[i] = check.MetricDef{
+			ResultKey: t.resultKey,
+			DSName:    t.dsName,
+			Label:     t.label,
+			Unit:      "days",
+			Scale:     0,
+			Signed:    true, // expired certificates have negative days left
+		}
+	}
+	c.desc = check.Descriptor{
+		Label:   "certificate expiry",
+		Metrics: metrics,
+	}
+
+	return c, nil
+}
+
+// Type returns the check type name.
+func (c *Check) Type() string {
+	return TypeName
+}
+
+// Describe returns the Descriptor for this check instance.
+// One metric is produced per configured endpoint.
+func (c *Check) Describe() check.Descriptor {
+	return c.desc
+}
+
+// Run handshakes with all configured endpoints and returns a Result.
+// Each endpoint's days until leaf expiry is stored keyed by its result key,
+// even when verification fails, so that the graph keeps tracking the
+// certificate actually being served. Success requires every endpoint to
+// present a valid chain for its hostname with more than critical_days
+// remaining. The result is degraded if any endpoint has fewer than
+// warning_days remaining.
+func (c *Check) Run(ctx context.Context) check.Result {
+	metrics := make(map[string]*float64, len(c.targets))
+	var lastErr error
+	succeeded := 0
+	degraded := false
+
+	for _, t := range c.targets {
+		now := time.Now()
+
+		leaf, err := c.inspect(ctx, t, now)
+		if leaf == nil {
+			lastErr = fmt.Errorf("tls_cert %s: %w", t.label, err)
+			metrics[t.resultKey] = nil
+			continue
+		}
+
+		v := certs.DaysLeft(leaf, now)
+		metrics[t.resultKey] = &v
+
+		if err == nil {
+			var warn bool
+			warn, err = c.policy.CheckExpiry(leaf, now)
+			degraded = degraded || warn
+		}
+		if err != nil {
+			lastErr = fmt.Errorf("tls_cert %s: %w", t.label, err)
+			continue
+		}
+		succeeded++
+	}
+
+	return check.Result{
+		Timestamp: time.Now(),
+		Success:   succeeded == len(c.targets),
+		Degraded:  degraded,
+		Err:       lastErr,
+		Metrics:   metrics,
+	}
+}
+
+// inspect handshakes with a single endpoint and verifies the presented
+// chain. It returns the leaf certificate whenever one was received, along
+// with any verification error.
+func (c *Check) inspect(ctx context.Context, t targetConfig, now time.Time) (*x509.Certificate, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: c.timeout},
+		Config: &tls.Config{
+			ServerName: t.serverName,
+			// Verification is done below so that the leaf is still
+			// available for the expiry metric when the chain is bad.
+			InsecureSkipVerify: true,
+		},
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", t.address)
+	if err != nil {
+		return nil, fmt.Errorf("handshake failed: %w", err)
+	}
+	state := conn.(*tls.Conn).ConnectionState()
+	conn.Close()
+
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("no certificate presented")
+	}
+	return state.PeerCertificates[0], c.policy.Verify(state.PeerCertificates, t.serverName, now)
+}
+
+// Factory creates a TLS certificate Check from a config map.
+// Required keys:
+//   - "targets" (list) — each either a "host:port" string or an object
+//     with "address" and optional "server_name" (SNI override)
+//
+// Optional keys:
+//   - "timeout" (string) — duration string (e.g. "10s"), default "5s"
+//   - "warning_days" (number) — degrade below this many days, default 21
+//   - "critical_days" (number) — fail below this many days, default 7
+//   - "ca_file" (string) — PEM bundle of trusted roots instead of the system roots
+func Factory(config map[string]any) (check.Check, error) {
+	targets, err := extractTargets(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []Option
+
+	if v, ok := config["timeout"]; ok {
+		ts, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("tls_cert: 'timeout' must be a string, got %T", v)
+		}
+		d, err := time.ParseDuration(ts)
+		if err != nil {
+			return nil, fmt.Errorf("tls_cert: invalid timeout %q: %w", ts, err)
+		}
+		opts = append(opts, WithTimeout(d))
+	}
+
+	if v, ok := config["warning_days"]; ok {
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("tls_cert: 'warning_days' must be a number, got %T", v)
+		}
+		opts = append(opts, WithWarningDays(int(n)))
+	}
+
+	if v, ok := config["critical_days"]; ok {
+		n, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("tls_cert: 'critical_days' must be a number, got %T", v)
+		}
+		opts = append(opts, WithCriticalDays(int(n)))
+	}
+
+	if v, ok := config["ca_file"]; ok {
+		path, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("tls_cert: 'ca_file' must be a string, got %T", v)
+		}
+		pool, err := certs.LoadCAFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("tls_cert: %w", err)
+		}
+		opts = append(opts, WithRootCAs(pool))
+	}
+
+	return newCheck(targets, opts...)
+}
+
+// extractTargets parses the "targets" list from the config map.
+func extractTargets(config map[string]any) ([]targetConfig, error) {
+	parsed, err := certs.ParseTargets(config, "")
+	if err != nil {
+		return nil, fmt.Errorf("tls_cert: %w", err)
+	}
+	targets := make([]targetConfig, len(parsed))
+	for i, t := range parsed {
+		targets[i] = newTargetConfig(i, t)
+	}
+	return targets, nil
+}
+
+// newTargetConfig builds the targetConfig for the endpoint at index.
+func newTargetConfig(index int, t certs.Target) targetConfig {
+	return targetConfig{
+		address:    t.Address,
+		serverName: t.ServerName,
+		resultKey:  t.Label,
+		dsName:     fmt.Sprintf("addr%d", index),
+		label:      t.Label,
+	}
+}
@@ -0,0 +1,361 @@
+package tlscert
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylerisse/wasgeht/pkg/check"
+	"github.com/kylerisse/wasgeht/pkg/check/internal/certs"
+)
+
+// startTLSServer starts an httptest TLS server and returns its address and
+// a pool trusting its self-signed certificate. The certificate is valid for
+// 127.0.0.1, ::1 and example.com and expires decades from now.
+func startTLSServer(t *testing.T) (string, *x509.CertPool) {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return srv.Listener.Addr().String(), pool
+}
+
+func mustTarget(t *testing.T, address, serverName string) targetConfig {
+	t.Helper()
+	target, err := certs.NewTarget(address, serverName, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return newTargetConfig(0, target)
+}
+
+// --- New() tests ---
+
+func TestNew_Defaults(t *testing.T) {
+	c, err := New([]string{"example.com:443"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Type() != TypeName {
+		t.Errorf("expected type %q, got %q", TypeName, c.Type())
+	}
+	if c.timeout != DefaultTimeout {
+		t.Errorf("expected default timeout, got %v", c.timeout)
+	}
+	if c.policy.WarningDays != DefaultWarningDays {
+		t.Errorf("expected default warning days, got %d", c.policy.WarningDays)
+	}
+	if c.policy.CriticalDays != DefaultCriticalDays {
+		t.Errorf("expected default critical days, got %d", c.policy.CriticalDays)
+	}
+}
+
+func TestNew_NoTargets(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("expected error for empty targets")
+	}
+}
+
+func TestNew_InvalidAddress(t *testing.T) {
+	if _, err := New([]string{"example.com"}); err == nil {
+		t.Error("expected error for address without a port")
+	}
+}
+
+func TestNew_WarningBelowCritical(t *testing.T) {
+	_, err := newCheck([]targetConfig{mustTarget(t, "example.com:443", "")},
+		WithWarningDays(3), WithCriticalDays(10))
+	if err == nil {
+		t.Error("expected error when warning_days < critical_days")
+	}
+}
+
+func TestNew_NegativeThresholds(t *testing.T) {
+	tc := []targetConfig{mustTarget(t, "example.com:443", "")}
+	if _, err := newCheck(tc, WithWarningDays(-1)); err == nil {
+		t.Error("expected error for negative warning_days")
+	}
+	if _, err := newCheck(tc, WithCriticalDays(-1)); err == nil {
+		t.Error("expected error for negative critical_days")
+	}
+}
+
+func TestNewTargetConfig(t *testing.T) {
+	tc := mustTarget(t, "10.0.0.5:8443", "portal.example.com")
+	if tc.serverName != "portal.example.com" {
+		t.Errorf("expected SNI override, got %q", tc.serverName)
+	}
+	if tc.resultKey != "10.0.0.5:8443 (portal.example.com)" {
+		t.Errorf("unexpected result key %q", tc.resultKey)
+	}
+
+	tc = mustTarget(t, "portal.example.com:443", "")
+	if tc.serverName != "portal.example.com" {
+		t.Errorf("expected server name from host, got %q", tc.serverName)
+	}
+	if tc.resultKey != "portal.example.com:443" {
+		t.Errorf("unexpected result key %q", tc.resultKey)
+	}
+
+	for _, bad := range []string{"", "example.com", ":443"} {
+		if _, err := certs.NewTarget(bad, "", ""); err == nil {
+			t.Errorf("expected error for address %q", bad)
+		}
+	}
+}
+
+// --- Describe tests ---
+
+func TestDescribe(t *testing.T) {
+	chk, err := Factory(map[string]any{
+		"targets": []any{
+			"a.example.com:443",
+			map[string]any{"address": "10.0.0.5:443", "server_name": "b.example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	desc := chk.Describe()
+	if desc.Label != "certificate expiry" {
+		t.Errorf("expected Descriptor.Label 'certificate expiry', got %q", desc.Label)
+	}
+	if len(desc.Metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d", len(desc.Metrics))
+	}
+	if desc.Metrics[0].DSName != "addr0" || desc.Metrics[1].DSName != "addr1" {
+		t.Errorf("unexpected DS names %q, %q", desc.Metrics[0].DSName, desc.Metrics[1].DSName)
+	}
+	if desc.Metrics[1].ResultKey != "10.0.0.5:443 (b.example.com)" {
+		t.Errorf("unexpected result key %q", desc.Metrics[1].ResultKey)
+	}
+	for _, m := range desc.Metrics {
+		if m.Unit != "days" {
+			t.Errorf("expected Unit 'days', got %q", m.Unit)
+		}
+		if !m.Signed {
+			t.Error("expected a signed metric so expired certificates are recorded")
+		}
+	}
+}
+
+// --- Factory tests ---
+
+func TestFactory_AllOptions(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// Write the server certificate to a CA file.
+	var buf strings.Builder
+	if err := pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}); err != nil {
+		t.Fatalf("pem encode: %v", err)
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte(buf.String()), 0644); err != nil {
+		t.Fatalf("write ca file: %v", err)
+	}
+
+	chk, err := Factory(map[string]any{
+		"targets":       []any{srv.Listener.Addr().String()},
+		"timeout":       "2s",
+		"warning_days":  float64(30),
+		"critical_days": float64(10),
+		"ca_file":       caFile,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := chk.(*Check)
+	if c.timeout != 2*time.Second || c.policy.WarningDays != 30 || c.policy.CriticalDays != 10 {
+		t.Errorf("options not applied: %+v", c)
+	}
+	if c.policy.Roots == nil {
+		t.Fatal("expected roots from ca_file")
+	}
+
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Errorf("expected success with ca_file trust, got %v", result.Err)
+	}
+}
+
+func TestFactory_Errors(t *testing.T) {
+	target := []any{"example.com:443"}
+	tests := []struct {
+		name   string
+		config map[string]any
+	}{
+		{"missing targets", map[string]any{}},
+		{"targets not a list", map[string]any{"targets": "example.com:443"}},
+		{"empty targets", map[string]any{"targets": []any{}}},
+		{"target wrong type", map[string]any{"targets": []any{443}}},
+		{"object missing address", map[string]any{"targets": []any{map[string]any{"server_name": "x"}}}},
+		{"empty server_name", map[string]any{"targets": []any{map[string]any{"address": "a:443", "server_name": ""}}}},
+		{"bad timeout", map[string]any{"targets": target, "timeout": "later"}},
+		{"timeout wrong type", map[string]any{"targets": target, "timeout": 5}},
+		{"warning_days wrong type", map[string]any{"targets": target, "warning_days": "30"}},
+		{"critical_days wrong type", map[string]any{"targets": target, "critical_days": "7"}},
+		{"missing ca_file", map[string]any{"targets": target, "ca_file": "/nonexistent/ca.pem"}},
+		{"ca_file wrong type", map[string]any{"targets": target, "ca_file": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Factory(tt.config); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestRegistryIntegration(t *testing.T) {
+	reg := check.NewRegistry()
+	if err := reg.Register(TypeName, Factory); err != nil {
+		t.Fatalf("failed to register tls_cert: %v", err)
+	}
+	chk, err := reg.Create(TypeName, map[string]any{"targets": []any{"example.com:443"}})
+	if err != nil {
+		t.Fatalf("failed to create tls_cert check: %v", err)
+	}
+	if chk.Type() != TypeName {
+		t.Errorf("expected type %q, got %q", TypeName, chk.Type())
+	}
+}
+
+func TestCheckInterface(t *testing.T) {
+	var _ check.Check = &Check{}
+}
+
+// --- Run tests ---
+
+func TestRun_ValidCertificate(t *testing.T) {
+	addr, pool := startTLSServer(t)
+
+	c, err := newCheck([]targetConfig{mustTarget(t, addr, "")}, WithRootCAs(pool))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success, got %v", result.Err)
+	}
+	if result.Degraded {
+		t.Error("expected not degraded")
+	}
+	if v := result.Metrics[addr]; v == nil || *v < 365 {
+		t.Errorf("expected days remaining well above a year, got %v", v)
+	}
+}
+
+func TestRun_SNIOverride(t *testing.T) {
+	addr, pool := startTLSServer(t)
+
+	c, err := newCheck([]targetConfig{mustTarget(t, addr, "example.com")}, WithRootCAs(pool))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Errorf("expected success verifying as example.com, got %v", result.Err)
+	}
+}
+
+func TestRun_HostnameMismatch(t *testing.T) {
+	addr, pool := startTLSServer(t)
+	tc := mustTarget(t, addr, "wrong.example.net")
+
+	c, err := newCheck([]targetConfig{tc}, WithRootCAs(pool))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Error("expected failure for hostname mismatch")
+	}
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "verification failed") {
+		t.Errorf("expected verification error, got %v", result.Err)
+	}
+	if result.Metrics[tc.resultKey] == nil {
+		t.Error("expected days remaining to be recorded despite verification failure")
+	}
+}
+
+func TestRun_UntrustedChain(t *testing.T) {
+	addr, _ := startTLSServer(t)
+
+	c, err := newCheck([]targetConfig{mustTarget(t, addr, "")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Error("expected failure for certificate not trusted by system roots")
+	}
+}
+
+func TestRun_WarningThreshold(t *testing.T) {
+	addr, pool := startTLSServer(t)
+
+	// httptest certificates expire in 2084, so a huge threshold is needed.
+	c, err := newCheck([]targetConfig{mustTarget(t, addr, "")},
+		WithRootCAs(pool), WithWarningDays(100000), WithCriticalDays(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success below warning threshold, got %v", result.Err)
+	}
+	if !result.Degraded {
+		t.Error("expected degraded below warning threshold")
+	}
+}
+
+func TestRun_CriticalThreshold(t *testing.T) {
+	addr, pool := startTLSServer(t)
+
+	c, err := newCheck([]targetConfig{mustTarget(t, addr, "")},
+		WithRootCAs(pool), WithWarningDays(100000), WithCriticalDays(100000))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Error("expected failure below critical threshold")
+	}
+	if result.Metrics[addr] == nil {
+		t.Error("expected days remaining to be recorded")
+	}
+}
+
+func TestRun_ConnectionRefused(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	addr := srv.Listener.Addr().String()
+	srv.Close()
+
+	c, err := newCheck([]targetConfig{mustTarget(t, addr, "")}, WithTimeout(time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := c.Run(context.Background())
+	if result.Success {
+		t.Error("expected failure for closed endpoint")
+	}
+	if v, ok := result.Metrics[addr]; !ok || v != nil {
+		t.Errorf("expected nil metric for failed handshake, got %v (present=%v)", v, ok)
+	}
+}
@@ -10,19 +10,20 @@
 //
 // Each radio becomes a separate RRD data source. A "total" data source is
 // also stored, computed as the sum of all configured radios.
+//
+// It is a preset of the generic prometheus check: scraping and parsing
+// are shared, with a fixed port, path, metric name and label.
 package wifistations
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/kylerisse/wasgeht/pkg/check"
+	"github.com/kylerisse/wasgeht/pkg/check/prometheus"
 )
 
 const (
@@ -147,34 +148,18 @@ func (w *WifiStations) Describe() check.Descriptor {
 func (w *WifiStations) Run(ctx context.Context) check.Result {
 	now := time.Now()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.url, nil)
-	if err != nil {
-		return check.Result{
-			Timestamp: now,
-			Success:   false,
-			Err:       fmt.Errorf("wifi_stations: failed to create request: %w", err),
-		}
-	}
-
-	resp, err := w.client.Do(req)
-	if err != nil {
-		return check.Result{
-			Timestamp: now,
-			Success:   false,
-			Err:       fmt.Errorf("wifi_stations: scrape failed: %w", err),
-		}
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return check.Result{
-			Timestamp: now,
-			Success:   false,
-			Err:       fmt.Errorf("wifi_stations: unexpected status %d", resp.StatusCode),
+	// Node exporters often carry collectors that emit lines the parser
+	// rejects; only the wifi_stations series matter here, so skip them.
+	var radioMetrics map[string]float64
+	skipped, err := prometheus.ScrapeLenient(ctx, w.client, w.url, func(s prometheus.Sample) {
+		radioMetrics = collect(radioMetrics, s, w.radios)
+	})
+	if err == nil && len(radioMetrics) == 0 {
+		err = errNoMetrics
+		if skipped > 0 {
+			err = fmt.Errorf("%w (%d malformed lines skipped)", errNoMetrics, skipped)
 		}
 	}
-
-	radioMetrics, err := parseMetrics(resp.Body, w.radios)
 	if err != nil {
 		return check.Result{
 			Timestamp: now,
@@ -184,14 +169,14 @@ func (w *WifiStations) Run(ctx context.Context) check.Result {
 	}
 
 	// Compute derived total from all found radio values.
-	var total int64
+	var total float64
 	for _, v := range radioMetrics {
 		total += v
 	}
 	radioMetrics[TotalResultKey] = total
 
-	// Convert to *int64 for Result.Metrics.
-	metrics := make(map[string]*int64, len(radioMetrics))
+	// Convert to *float64 for Result.Metrics.
+	metrics := make(map[string]*float64, len(radioMetrics))
 	for k, v := range radioMetrics {
 		cv := v
 		metrics[k] = &cv
@@ -283,70 +268,25 @@ func parseRadiosConfig(raw any) ([]radioConfig, error) {
 	return radios, nil
 }
 
-func parseMetrics(r io.Reader, radios []radioConfig) (map[string]int64, error) {
-	lookup := make(map[string]radioConfig, len(radios))
-	for _, radio := range radios {
-		lookup[radio.ifname] = radio
-	}
-
-	found := make(map[string]int64)
-	scanner := bufio.NewScanner(r)
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+// errNoMetrics is returned when a scrape has no series for any radio.
+var errNoMetrics = errors.New("no wifi_stations metrics found for configured radios")
 
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		if !strings.HasPrefix(line, MetricName+"{") {
-			continue
-		}
-
-		ifname, value, err := parseLine(line)
-		if err != nil {
-			continue
-		}
-
-		if radio, ok := lookup[ifname]; ok {
-			found[radio.resultKey] = value
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading metrics: %w", err)
-	}
-
-	if len(found) == 0 {
-		return nil, fmt.Errorf("no wifi_stations metrics found for configured radios")
-	}
-
-	return found, nil
+// radioSelector returns the series selector for a radio.
+func radioSelector(r radioConfig) prometheus.Selector {
+	return prometheus.Selector{Name: MetricName, Labels: map[string]string{"ifname": r.ifname}}
 }
 
-func parseLine(line string) (string, int64, error) {
-	ifnameStart := strings.Index(line, `ifname="`)
-	if ifnameStart == -1 {
-		return "", 0, fmt.Errorf("no ifname label found")
-	}
-	ifnameStart += len(`ifname="`)
-
-	ifnameEnd := strings.Index(line[ifnameStart:], `"`)
-	if ifnameEnd == -1 {
-		return "", 0, fmt.Errorf("unterminated ifname label")
-	}
-	ifname := line[ifnameStart : ifnameStart+ifnameEnd]
-
-	braceEnd := strings.Index(line, "}")
-	if braceEnd == -1 {
-		return "", 0, fmt.Errorf("no closing brace found")
-	}
-
-	valueStr := strings.TrimSpace(line[braceEnd+1:])
-	value, err := strconv.ParseFloat(valueStr, 64)
-	if err != nil {
-		return "", 0, fmt.Errorf("invalid value %q: %w", valueStr, err)
+// collect adds s to found if it is a wifi_stations series for one of
+// radios, allocating found on first use. Series for the same radio that
+// differ in other labels (e.g. per SSID) are summed.
+func collect(found map[string]float64, s prometheus.Sample, radios []radioConfig) map[string]float64 {
+	for _, radio := range radios {
+		if radioSelector(radio).Matches(s) {
+			if found == nil {
+				found = make(map[string]float64)
+			}
+			found[radio.resultKey] += s.Value
+		}
 	}
-
-	return ifname, int64(value), nil
+	return found
 }
@@ -265,126 +265,107 @@ func TestRun_NoMatchingRadios(t *testing.T) {
 	}
 }
 
-// --- parseLine tests ---
-
-func TestParseLine_Valid(t *testing.T) {
-	ifname, value, err := parseLine(`wifi_stations{ifname="phy0-ap0"} 3`)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if ifname != "phy0-ap0" {
-		t.Errorf("expected ifname 'phy0-ap0', got %q", ifname)
-	}
-	if value != 3 {
-		t.Errorf("expected value 3, got %d", value)
-	}
-}
-
-func TestParseLine_FloatValue(t *testing.T) {
-	_, value, err := parseLine(`wifi_stations{ifname="phy1-ap0"} 7.0`)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if value != 7 {
-		t.Errorf("expected value 7, got %d", value)
+// serveMetrics starts a test server returning body and a check scraping it
+// for radios.
+func serveMetrics(t *testing.T, body string, radios []radioConfig) *WifiStations {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return &WifiStations{
+		url:    server.URL,
+		radios: radios,
+		client: &http.Client{Timeout: 5 * time.Second},
 	}
 }
 
-func TestParseLine_MultipleLabels(t *testing.T) {
-	ifname, value, err := parseLine(`wifi_stations{ifname="phy0-ap0",ssid="mynet"} 12`)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if ifname != "phy0-ap0" {
-		t.Errorf("expected ifname 'phy0-ap0', got %q", ifname)
+func TestRun_SkipsUnrelatedMalformedLines(t *testing.T) {
+	body := `wifi_stations{ifname="phy0-ap0"} 3
+node_textfile_broken{path="/var/lib/x} 1
+wifi_stations{ifname="phy1-ap0"} 7
+`
+	result := serveMetrics(t, body, testRadios()).Run(context.Background())
+	if !result.Success {
+		t.Fatalf("expected success despite unrelated malformed line, got error: %v", result.Err)
 	}
-	if value != 12 {
-		t.Errorf("expected value 12, got %d", value)
+	if p := result.Metrics["phy0-ap0"]; p == nil || *p != 3 {
+		t.Errorf("expected phy0-ap0=3, got %v", result.Metrics["phy0-ap0"])
 	}
-}
-
-func TestParseLine_NoIfname(t *testing.T) {
-	_, _, err := parseLine(`wifi_stations{ssid="mynet"} 3`)
-	if err == nil {
-		t.Error("expected error for missing ifname label")
+	if p := result.Metrics["phy1-ap0"]; p == nil || *p != 7 {
+		t.Errorf("expected phy1-ap0=7, got %v", result.Metrics["phy1-ap0"])
 	}
-}
-
-func TestParseLine_InvalidValue(t *testing.T) {
-	_, _, err := parseLine(`wifi_stations{ifname="phy0-ap0"} abc`)
-	if err == nil {
-		t.Error("expected error for non-numeric value")
+	if p := result.Metrics[TotalResultKey]; p == nil || *p != 10 {
+		t.Errorf("expected total=10, got %v", result.Metrics[TotalResultKey])
 	}
 }
 
-func TestParseLine_ZeroValue(t *testing.T) {
-	_, value, err := parseLine(`wifi_stations{ifname="phy0-ap0"} 0`)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+func TestRun_NoMetricsReportsSkippedLines(t *testing.T) {
+	body := `wifi_stations{ifname="phy0-ap0"} abc
+`
+	result := serveMetrics(t, body, testRadios()).Run(context.Background())
+	if result.Success {
+		t.Fatal("expected failure when the only wifi_stations line is malformed")
 	}
-	if value != 0 {
-		t.Errorf("expected value 0, got %d", value)
+	if !strings.Contains(result.Err.Error(), "1 malformed lines skipped") {
+		t.Errorf("expected skipped line count in error, got %v", result.Err)
 	}
 }
 
-// --- parseMetrics tests ---
-
-func TestParseMetrics_Success(t *testing.T) {
-	r := strings.NewReader(sampleMetrics)
-	values, err := parseMetrics(r, testRadios())
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+func TestRun_FloatAndZeroValues(t *testing.T) {
+	body := `wifi_stations{ifname="phy0-ap0"} 0
+wifi_stations{ifname="phy1-ap0"} 7.0
+`
+	result := serveMetrics(t, body, testRadios()).Run(context.Background())
+	if !result.Success {
+		t.Fatalf("unexpected failure: %v", result.Err)
 	}
-	if values["phy0-ap0"] != 3 {
-		t.Errorf("expected phy0-ap0=3, got %d", values["phy0-ap0"])
+	if p := result.Metrics["phy0-ap0"]; p == nil || *p != 0 {
+		t.Errorf("expected phy0-ap0=0, got %v", result.Metrics["phy0-ap0"])
 	}
-	if values["phy1-ap0"] != 7 {
-		t.Errorf("expected phy1-ap0=7, got %d", values["phy1-ap0"])
+	if p := result.Metrics["phy1-ap0"]; p == nil || *p != 7 {
+		t.Errorf("expected phy1-ap0=7, got %v", result.Metrics["phy1-ap0"])
 	}
 }
 
-func TestParseMetrics_DoesNotIncludeTotal(t *testing.T) {
-	// parseMetrics itself should NOT include total; Run() adds it
-	r := strings.NewReader(sampleMetrics)
-	values, err := parseMetrics(r, testRadios())
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+func TestRun_SumsSeriesWithExtraLabels(t *testing.T) {
+	body := `wifi_stations{ifname="phy0-ap0",ssid="home"} 12
+wifi_stations{ifname="phy0-ap0",ssid="guest"} 2
+wifi_stations{ssid="mynet"} 5
+`
+	radios := testRadios()[:1]
+	result := serveMetrics(t, body, radios).Run(context.Background())
+	if !result.Success {
+		t.Fatalf("unexpected failure: %v", result.Err)
+	}
+	if p := result.Metrics["phy0-ap0"]; p == nil || *p != 14 {
+		t.Errorf("expected phy0-ap0=14, got %v", result.Metrics["phy0-ap0"])
 	}
-	if _, ok := values[TotalResultKey]; ok {
-		t.Error("parseMetrics should not include 'total'; that is added by Run()")
+	if p := result.Metrics[TotalResultKey]; p == nil || *p != 14 {
+		t.Errorf("expected total=14, got %v", result.Metrics[TotalResultKey])
 	}
 }
 
-func TestParseMetrics_IgnoresUnknownRadios(t *testing.T) {
-	input := `wifi_stations{ifname="phy0-ap0"} 3
+func TestRun_IgnoresUnknownRadios(t *testing.T) {
+	body := `wifi_stations{ifname="phy0-ap0"} 3
 wifi_stations{ifname="phy2-ap0"} 99
 `
-	r := strings.NewReader(input)
-	radios := []radioConfig{
-		{ifname: "phy0-ap0", resultKey: "phy0-ap0", dsName: "radio0", label: "phy0-ap0"},
-	}
-	values, err := parseMetrics(r, radios)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	result := serveMetrics(t, body, testRadios()[:1]).Run(context.Background())
+	if !result.Success {
+		t.Fatalf("unexpected failure: %v", result.Err)
 	}
-	if len(values) != 1 {
-		t.Errorf("expected 1 value, got %d", len(values))
+	if len(result.Metrics) != 2 {
+		t.Errorf("expected phy0-ap0 and total only, got %v", result.Metrics)
 	}
-}
-
-func TestParseMetrics_EmptyInput(t *testing.T) {
-	r := strings.NewReader("")
-	_, err := parseMetrics(r, testRadios())
-	if err == nil {
-		t.Error("expected error for empty input")
+	if p := result.Metrics[TotalResultKey]; p == nil || *p != 3 {
+		t.Errorf("expected total=3, got %v", result.Metrics[TotalResultKey])
 	}
 }
 
-func TestParseMetrics_OnlyComments(t *testing.T) {
-	r := strings.NewReader("# just comments\n")
-	_, err := parseMetrics(r, testRadios())
-	if err == nil {
-		t.Error("expected error when no matching metrics found")
+func TestRun_EmptyResponse(t *testing.T) {
+	result := serveMetrics(t, "", testRadios()).Run(context.Background())
+	if result.Success {
+		t.Error("expected failure for empty response")
 	}
 }
 
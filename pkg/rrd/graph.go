@@ -47,7 +47,7 @@ type graph struct {
 //   - rrdPath: The path to the RRD file.
 //   - timeLength: The time range for the graph (e.g., "4h").
 //   - consolidationFunction: The RRD consolidation function ("AVERAGE", "MAX", etc.).
-//   - checkType: The check type name, used for graph file naming (e.g., "ping").
+//   - checkType: The check type name, used for graph file naming (e.g., "ping" or "ping_loss").
 //   - metrics: The metric definitions for data sources in the RRD.
 //   - descLabel: Descriptor-level label override for graph title/axis (may be empty).
 //   - logger: The logger instance.
@@ -101,6 +101,26 @@ func needsScaling(m check.MetricDef) bool {
 	return m.Scale > 1
 }
 
+// displayUnit returns the unit shown on graphs for a metric. Rate data
+// sources store per-second values, so "/s" is appended to their unit
+// (e.g. "bits" becomes "bits/s").
+func displayUnit(m check.MetricDef) string {
+	if m.DSType.IsRate() {
+		return m.Unit + "/s"
+	}
+	return m.Unit
+}
+
+// gprintFormat returns the GPRINT format of a metric's value and unit.
+// Rates can span many orders of magnitude, so they are printed with an SI
+// prefix (e.g. "12.50 Mbits/s") rather than in full.
+func gprintFormat(m check.MetricDef) string {
+	if m.DSType.IsRate() {
+		return "%.2lf %s" + displayUnit(m)
+	}
+	return "%.2lf " + m.Unit
+}
+
 // rrdEscape escapes a string for use in rrdtool graph labels and comments.
 func rrdEscape(s string) string {
 	s = strings.ReplaceAll(s, `\`, `\\`)
@@ -109,9 +129,23 @@ func rrdEscape(s string) string {
 }
 
 // draw draws a graph based on the current parameters of the graph struct.
-// All metrics are rendered as colored LINE2s.
 func (g *graph) draw() error {
-	unit := g.metrics[0].Unit
+	cmd := exec.Command("rrdtool", g.drawArgs()...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rrdtool graph failed for %s: %w\nOutput: %s", g.filePath, err, string(output))
+	}
+
+	g.logger.Debugf("Graph drawn successfully: %s", g.filePath)
+	return nil
+}
+
+// drawArgs builds the rrdtool graph arguments for the graph.
+// Metrics with a Stack group are rendered as stacked AREAs, each group
+// starting from zero; all other metrics are rendered as colored LINE2s
+// drawn over the areas.
+func (g *graph) drawArgs() []string {
+	unit := displayUnit(g.metrics[0])
 	label := g.descLabel
 	if label == "" {
 		label = g.metrics[0].Label
@@ -119,8 +153,10 @@ func (g *graph) draw() error {
 
 	var defs []string
 	var cdefs []string
+	var areas []string
 	var lines []string
 	var gprints []string
+	stacks := make(map[string]bool)
 
 	for i, m := range g.metrics {
 		rawVar := fmt.Sprintf("%s_raw", m.DSName)
@@ -134,23 +170,30 @@ func (g *graph) draw() error {
 			cdefs = append(cdefs, fmt.Sprintf("CDEF:%s=%s,%d,/", dispVar, rawVar, m.Scale))
 		}
 
-		lines = append(lines, fmt.Sprintf("LINE2:%s#%s:%s", dispVar, color, escapedLabel))
+		switch {
+		case m.Stack == "":
+			lines = append(lines, fmt.Sprintf("LINE2:%s#%s:%s", dispVar, color, escapedLabel))
+		case stacks[m.Stack]:
+			areas = append(areas, fmt.Sprintf("AREA:%s#%s:%s:STACK", dispVar, color, escapedLabel))
+		default:
+			stacks[m.Stack] = true
+			areas = append(areas, fmt.Sprintf("AREA:%s#%s:%s", dispVar, color, escapedLabel))
+		}
 
-		gfmt := "%.2lf"
 		gprints = append(gprints,
-			fmt.Sprintf("GPRINT:%s:LAST:  %s last\\: %s %s", dispVar, escapedLabel, gfmt, unit),
+			fmt.Sprintf("GPRINT:%s:LAST:  %s last\\: %s", dispVar, escapedLabel, gprintFormat(m)),
 		)
 	}
 
 	// For single-metric graphs, include the full stats line (backward compatible)
 	if len(g.metrics) == 1 {
 		dispVar := displayVarName(g.metrics[0])
-		gfmt := "%.2lf"
+		gfmt := gprintFormat(g.metrics[0])
 		gprints = []string{
-			fmt.Sprintf("GPRINT:%s:MIN:Min\\: %s %s", dispVar, gfmt, unit),
-			fmt.Sprintf("GPRINT:%s:MAX:Max\\: %s %s", dispVar, gfmt, unit),
-			fmt.Sprintf("GPRINT:%s:AVERAGE:Average\\: %s %s", dispVar, gfmt, unit),
-			fmt.Sprintf("GPRINT:%s:LAST:Last\\: %s %s", dispVar, gfmt, unit),
+			fmt.Sprintf("GPRINT:%s:MIN:Min\\: %s", dispVar, gfmt),
+			fmt.Sprintf("GPRINT:%s:MAX:Max\\: %s", dispVar, gfmt),
+			fmt.Sprintf("GPRINT:%s:AVERAGE:Average\\: %s", dispVar, gfmt),
+			fmt.Sprintf("GPRINT:%s:LAST:Last\\: %s", dispVar, gfmt),
 		}
 	}
 
@@ -174,16 +217,10 @@ func (g *graph) draw() error {
 
 	args = append(args, defs...)
 	args = append(args, cdefs...)
+	args = append(args, areas...)
 	args = append(args, lines...)
 	args = append(args, gprints...)
 	args = append(args, commentStrings...)
 
-	cmd := exec.Command("rrdtool", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("rrdtool graph failed for %s: %w\nOutput: %s", g.filePath, err, string(output))
-	}
-
-	g.logger.Debugf("Graph drawn successfully: %s", g.filePath)
-	return nil
+	return args
 }
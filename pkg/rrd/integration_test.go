@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
@@ -50,7 +51,7 @@ func TestNewRRD_CreatesFile(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", logger)
+	r, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD failed: %v", err)
 	}
@@ -69,7 +70,7 @@ func TestNewRRD_CreatesGraphDir(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", logger)
+	r, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD failed: %v", err)
 	}
@@ -88,7 +89,7 @@ func TestNewRRD_CreatesGraphFiles(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", logger)
+	r, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD failed: %v", err)
 	}
@@ -114,22 +115,80 @@ func TestNewRRD_Idempotent(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r1, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", logger)
+	r1, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("first NewRRD failed: %v", err)
 	}
 	r1.file.Close()
 
-	r2, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", logger)
+	r2, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("second NewRRD failed: %v", err)
 	}
 	r2.file.Close()
 }
 
+func TestNewRRD_AddsMissingDataSources(t *testing.T) {
+	requireRRDTool(t)
+
+	rrdDir := t.TempDir()
+	graphDir := t.TempDir()
+	logger := testLogger()
+
+	// A file created before the check gained a metric.
+	r1, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", time.Minute, logger)
+	if err != nil {
+		t.Fatalf("first NewRRD failed: %v", err)
+	}
+	r1.file.Close()
+
+	grown := append(slices.Clone(singleMetric), check.MetricDef{ResultKey: "loss", DSName: "latency_loss", Label: "loss", Unit: "%", Graph: "loss"})
+	r2, err := NewRRD("testhost", rrdDir, graphDir, "ping", grown, "", time.Minute, logger)
+	if err != nil {
+		t.Fatalf("NewRRD with an added metric failed: %v", err)
+	}
+	defer r2.file.Close()
+
+	info, err := fileInfo(r2.file.Name())
+	if err != nil {
+		t.Fatalf("fileInfo failed: %v", err)
+	}
+	if got := parseDSNames(info); !slices.Equal(got, []string{"latency", "latency_loss"}) {
+		t.Errorf("expected data sources [latency latency_loss], got %v", got)
+	}
+	if _, err := r2.SafeUpdate(time.Now(), []string{"12340", "0"}); err != nil {
+		t.Errorf("SafeUpdate after adding a data source failed: %v", err)
+	}
+}
+
+func TestSafeUpdate_FileWithExtraDataSources(t *testing.T) {
+	requireRRDTool(t)
+
+	rrdDir := t.TempDir()
+	graphDir := t.TempDir()
+	logger := testLogger()
+
+	r1, err := NewRRD("testhost", rrdDir, graphDir, "http", lineMetrics, "", time.Minute, logger)
+	if err != nil {
+		t.Fatalf("first NewRRD failed: %v", err)
+	}
+	r1.file.Close()
+
+	// The check no longer declares the second data source.
+	r2, err := NewRRD("testhost", rrdDir, graphDir, "http", lineMetrics[:1], "", time.Minute, logger)
+	if err != nil {
+		t.Fatalf("NewRRD with fewer metrics failed: %v", err)
+	}
+	defer r2.file.Close()
+
+	if _, err := r2.SafeUpdate(time.Now(), []string{"12340"}); err != nil {
+		t.Errorf("SafeUpdate of a subset of data sources failed: %v", err)
+	}
+}
+
 func TestNewRRD_BadRrdDir(t *testing.T) {
 	logger := testLogger()
-	_, err := NewRRD("testhost", "/nonexistent/path", "/tmp", "ping", singleMetric, "", logger)
+	_, err := NewRRD("testhost", "/nonexistent/path", "/tmp", "ping", singleMetric, "", time.Minute, logger)
 	if err == nil {
 		t.Error("expected error for nonexistent rrdDir")
 	}
@@ -137,12 +196,26 @@ func TestNewRRD_BadRrdDir(t *testing.T) {
 
 func TestNewRRD_EmptyMetrics(t *testing.T) {
 	logger := testLogger()
-	_, err := NewRRD("testhost", t.TempDir(), t.TempDir(), "ping", []check.MetricDef{}, "", logger)
+	_, err := NewRRD("testhost", t.TempDir(), t.TempDir(), "ping", []check.MetricDef{}, "", time.Minute, logger)
 	if err == nil {
 		t.Error("expected error for empty metrics")
 	}
 }
 
+func TestNewRRD_InvalidDataSource(t *testing.T) {
+	logger := testLogger()
+	rrdDir := t.TempDir()
+	metrics := []check.MetricDef{
+		{ResultKey: "load", DSName: "load", Label: "load", Min: check.Bound(100), Max: check.Bound(0)},
+	}
+	if _, err := NewRRD("testhost", rrdDir, t.TempDir(), "json", metrics, "", time.Minute, logger); err == nil {
+		t.Error("expected error for min above max")
+	}
+	if _, err := os.Stat(filepath.Join(rrdDir, "testhost")); !os.IsNotExist(err) {
+		t.Error("expected no host directory to be created for invalid metrics")
+	}
+}
+
 func TestNewRRD_PerHostSubdirectory(t *testing.T) {
 	requireRRDTool(t)
 
@@ -150,13 +223,13 @@ func TestNewRRD_PerHostSubdirectory(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r1, err := NewRRD("host-a", rrdDir, graphDir, "ping", singleMetric, "", logger)
+	r1, err := NewRRD("host-a", rrdDir, graphDir, "ping", singleMetric, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD for host-a failed: %v", err)
 	}
 	defer r1.file.Close()
 
-	r2, err := NewRRD("host-b", rrdDir, graphDir, "ping", singleMetric, "", logger)
+	r2, err := NewRRD("host-b", rrdDir, graphDir, "ping", singleMetric, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD for host-b failed: %v", err)
 	}
@@ -184,13 +257,13 @@ func TestNewRRD_MultipleCheckTypes(t *testing.T) {
 		{ResultKey: "response_ms", DSName: "response", Label: "response time", Unit: "ms", Scale: 0},
 	}
 
-	r1, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", logger)
+	r1, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD for ping failed: %v", err)
 	}
 	defer r1.file.Close()
 
-	r2, err := NewRRD("testhost", rrdDir, graphDir, "http", httpMetrics, "", logger)
+	r2, err := NewRRD("testhost", rrdDir, graphDir, "http", httpMetrics, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD for http failed: %v", err)
 	}
@@ -216,7 +289,7 @@ func TestNewRRD_MultiDS_CreatesFile(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r, err := NewRRD("ap1", rrdDir, graphDir, "wifi_stations", multiMetrics, "", logger)
+	r, err := NewRRD("ap1", rrdDir, graphDir, "wifi_stations", multiMetrics, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD multi-DS failed: %v", err)
 	}
@@ -235,7 +308,7 @@ func TestNewRRD_MultiDS_CreatesGraphs(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r, err := NewRRD("ap1", rrdDir, graphDir, "wifi_stations", multiMetrics, "", logger)
+	r, err := NewRRD("ap1", rrdDir, graphDir, "wifi_stations", multiMetrics, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD multi-DS failed: %v", err)
 	}
@@ -258,7 +331,7 @@ func TestSafeUpdate_MultiDS_Success(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r, err := NewRRD("ap1", rrdDir, graphDir, "wifi_stations", multiMetrics, "", logger)
+	r, err := NewRRD("ap1", rrdDir, graphDir, "wifi_stations", multiMetrics, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD multi-DS failed: %v", err)
 	}
@@ -281,7 +354,7 @@ func TestSafeUpdate_MultiDS_AcceptsNewer(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r, err := NewRRD("ap1", rrdDir, graphDir, "wifi_stations", multiMetrics, "", logger)
+	r, err := NewRRD("ap1", rrdDir, graphDir, "wifi_stations", multiMetrics, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD multi-DS failed: %v", err)
 	}
@@ -312,7 +385,7 @@ func TestNewRRD_MultiMetric_CreatesGraphs(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r, err := NewRRD("qube", rrdDir, graphDir, "http", lineMetrics, "response time", logger)
+	r, err := NewRRD("qube", rrdDir, graphDir, "http", lineMetrics, "response time", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD multi-metric failed: %v", err)
 	}
@@ -335,7 +408,7 @@ func TestSafeUpdate_MultiMetric_Success(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r, err := NewRRD("qube", rrdDir, graphDir, "http", lineMetrics, "response time", logger)
+	r, err := NewRRD("qube", rrdDir, graphDir, "http", lineMetrics, "response time", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD multi-metric failed: %v", err)
 	}
@@ -360,7 +433,7 @@ func TestSafeUpdate_SingleDS(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", logger)
+	r, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD failed: %v", err)
 	}
@@ -383,7 +456,7 @@ func TestSafeUpdate_RejectsSameTimestamp(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", logger)
+	r, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD failed: %v", err)
 	}
@@ -408,7 +481,7 @@ func TestSafeUpdate_EmptyValues(t *testing.T) {
 	graphDir := t.TempDir()
 	logger := testLogger()
 
-	r, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", logger)
+	r, err := NewRRD("testhost", rrdDir, graphDir, "ping", singleMetric, "", time.Minute, logger)
 	if err != nil {
 		t.Fatalf("NewRRD failed: %v", err)
 	}
@@ -2,8 +2,10 @@ package rrd
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,6 +15,25 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// archive is a round robin archive expressed as the resolution and
+// retention it aims for. For steps coarser than the resolution, each row
+// holds a single step and the row count shrinks to keep the retention.
+type archive struct {
+	cf         string
+	resolution time.Duration
+	retention  time.Duration
+}
+
+// archives lists the archives created for every RRD file, in order.
+var archives = []archive{
+	{"MAX", 0, 7 * 24 * time.Hour},                     // per-step max for 1 week
+	{"AVERAGE", 0, 7 * 24 * time.Hour},                 // per-step average for 1 week
+	{"AVERAGE", 5 * time.Minute, 31 * 24 * time.Hour},  // 5-minute average for 31 days
+	{"AVERAGE", 15 * time.Minute, 91 * 24 * time.Hour}, // 15-minute average for 13 weeks
+	{"AVERAGE", time.Hour, 366 * 24 * time.Hour},       // 1-hour average for 1 year
+	{"AVERAGE", 8 * time.Hour, 1830 * 24 * time.Hour},  // 8-hour average for 5 years
+}
+
 // RRD represents an RRD file, including metadata and synchronization tools.
 // It contains the file pointer, a mutex for thread safety, metric definitions,
 // and graph instances for visualization.
@@ -33,6 +54,7 @@ type RRD struct {
 // with one data source per metric in the provided slice.
 //
 // RRD files are stored under {rrdDir}/{name}/{checkType}.rrd and graphs under {graphDir}/imgs/{name}/.
+// Metrics with a Graph name are drawn on a graph of their own.
 //
 // Parameters:
 //   - name: The identifier (typically host name) for which the RRD file will be created.
@@ -41,11 +63,29 @@ type RRD struct {
 //   - checkType: The check type name, used for the RRD filename (e.g. "ping").
 //   - metrics: The metric definitions describing the data sources to create.
 //   - descLabel: Descriptor-level label for graph title/axis (may be empty).
+//   - step: The interval at which the check runs and the RRD is updated.
 //   - logger: The logger instance.
-func NewRRD(name string, rrdDir string, graphDir string, checkType string, metrics []check.MetricDef, descLabel string, logger *logrus.Logger) (*RRD, error) {
+//
+// The step, default heartbeats and archive layout of a new file follow
+// step. An existing file keeps the step it was created with; a mismatch is
+// logged, since the file must be recreated for the new step to apply. Data
+// sources an existing file lacks are added to it with rrdtool tune.
+func NewRRD(name string, rrdDir string, graphDir string, checkType string, metrics []check.MetricDef, descLabel string, step time.Duration, logger *logrus.Logger) (*RRD, error) {
 	if len(metrics) == 0 {
 		return nil, fmt.Errorf("at least one metric definition is required")
 	}
+	if step < time.Second || step%time.Second != 0 {
+		return nil, fmt.Errorf("step must be a whole number of seconds, got %v", step)
+	}
+
+	dataSources := make([]string, len(metrics))
+	for i, m := range metrics {
+		ds, err := dataSource(m, step)
+		if err != nil {
+			return nil, err
+		}
+		dataSources[i] = ds
+	}
 
 	// verify rrdDir exists
 	if _, err := os.Stat(rrdDir); os.IsNotExist(err) {
@@ -68,19 +108,10 @@ func NewRRD(name string, rrdDir string, graphDir string, checkType string, metri
 		// Build rrdtool create args with one DS per metric
 		args := []string{
 			"create", rrdPath,
-			"--step", "60",
+			"--step", strconv.FormatInt(int64(step/time.Second), 10),
 		}
-		for _, m := range metrics {
-			args = append(args, fmt.Sprintf("DS:%s:GAUGE:120:0:U", m.DSName))
-		}
-		args = append(args,
-			"RRA:MAX:0.5:1:10080",      // 1-minute max for 1 week (10080 data points)
-			"RRA:AVERAGE:0.5:1:10080",  // 1-minute average for 1 week (10080 data points)
-			"RRA:AVERAGE:0.5:5:8928",   // 5-minute average for 31 days (8928 data points)
-			"RRA:AVERAGE:0.5:15:8736",  // 15-minute average for 13 weeks (8736 data points)
-			"RRA:AVERAGE:0.5:60:8784",  // 1-hour average for 1 year (8784 data points)
-			"RRA:AVERAGE:0.5:480:5490", // 8-hour average for 5 years (5490 data points)
-		)
+		args = append(args, dataSources...)
+		args = append(args, roundRobinArchives(step)...)
 
 		cmd := exec.Command("rrdtool", args...)
 		if err := cmd.Run(); err != nil {
@@ -89,6 +120,18 @@ func NewRRD(name string, rrdDir string, graphDir string, checkType string, metri
 		logger.Debugf("RRD file %s created successfully.", rrdPath)
 	} else {
 		logger.Debugf("RRD file %s already exists.", rrdPath)
+		info, err := fileInfo(rrdPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read RRD file %s: %w", rrdPath, err)
+		}
+		if existing, err := parseStep(info); err != nil {
+			logger.Warnf("Could not read the step of RRD file %s: %v", rrdPath, err)
+		} else if existing != step {
+			logger.Warnf("RRD file %s has a step of %v but the check runs every %v; remove or migrate the file to match.", rrdPath, existing, step)
+		}
+		if err := addMissingDataSources(rrdPath, parseDSNames(info), metrics, dataSources, logger); err != nil {
+			return nil, err
+		}
 	}
 
 	file, err := os.OpenFile(rrdPath, os.O_RDWR, 0644)
@@ -115,6 +158,148 @@ func NewRRD(name string, rrdDir string, graphDir string, checkType string, metri
 	return rrd, nil
 }
 
+// roundRobinArchives returns the rrdtool create RRA definitions for step.
+// With the default one-minute step they are the archives wasgeht has always
+// created. Archives that would consolidate the same number of steps are
+// merged, keeping the longer retention.
+func roundRobinArchives(step time.Duration) []string {
+	type rra struct {
+		cf          string
+		stepsPerRow int64
+		rows        int64
+	}
+	var rras []rra
+	for _, a := range archives {
+		stepsPerRow := max(int64((a.resolution+step-1)/step), 1)
+		rowSpan := time.Duration(stepsPerRow) * step
+		rows := int64((a.retention + rowSpan - 1) / rowSpan)
+		if n := len(rras); n > 0 && rras[n-1].cf == a.cf && rras[n-1].stepsPerRow == stepsPerRow {
+			rras[n-1].rows = max(rras[n-1].rows, rows)
+			continue
+		}
+		rras = append(rras, rra{a.cf, stepsPerRow, rows})
+	}
+
+	out := make([]string, len(rras))
+	for i, r := range rras {
+		out[i] = fmt.Sprintf("RRA:%s:0.5:%d:%d", r.cf, r.stepsPerRow, r.rows)
+	}
+	return out
+}
+
+// dataSource returns the rrdtool create DS definition for a metric in a
+// file with the given step. Unless the metric declares otherwise, data
+// sources are gauges with a heartbeat of two steps, bounded below by 0 (or
+// unbounded if the metric is signed) and unbounded above.
+func dataSource(m check.MetricDef, step time.Duration) (string, error) {
+	dsType := m.DSType
+	switch dsType {
+	case "":
+		dsType = check.DSGauge
+	case check.DSGauge, check.DSCounter, check.DSDerive, check.DSAbsolute:
+	default:
+		return "", fmt.Errorf("data source %s: unknown type %q", m.DSName, m.DSType)
+	}
+
+	heartbeat := m.Heartbeat
+	if heartbeat == 0 {
+		heartbeat = 2 * step
+	}
+	if heartbeat < time.Second || heartbeat%time.Second != 0 {
+		return "", fmt.Errorf("data source %s: heartbeat must be a whole number of seconds, got %v", m.DSName, m.Heartbeat)
+	}
+
+	lower := "0"
+	if m.Signed {
+		lower = "U"
+	}
+	upper := "U"
+	for _, b := range []*float64{m.Min, m.Max} {
+		if b != nil && (math.IsNaN(*b) || math.IsInf(*b, 0)) {
+			return "", fmt.Errorf("data source %s: bounds must be finite", m.DSName)
+		}
+	}
+	if m.Min != nil {
+		lower = strconv.FormatFloat(*m.Min, 'f', -1, 64)
+	}
+	if m.Max != nil {
+		upper = strconv.FormatFloat(*m.Max, 'f', -1, 64)
+	}
+	if m.Min != nil && m.Max != nil && *m.Min >= *m.Max {
+		return "", fmt.Errorf("data source %s: min %s must be less than max %s", m.DSName, lower, upper)
+	}
+	if m.Min == nil && !m.Signed && m.Max != nil && *m.Max <= 0 {
+		return "", fmt.Errorf("data source %s: max %s must be greater than the default min of 0", m.DSName, upper)
+	}
+
+	return fmt.Sprintf("DS:%s:%s:%d:%s:%s", m.DSName, dsType, int64(heartbeat/time.Second), lower, upper), nil
+}
+
+// fileInfo returns the rrdtool info output of an existing RRD file.
+func fileInfo(path string) (string, error) {
+	output, err := exec.Command("rrdtool", "info", path).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to execute rrdtool info: %w", err)
+	}
+	return string(output), nil
+}
+
+// addMissingDataSources adds the data sources of metrics that an existing
+// RRD file lacks, so that a check which gained metrics (e.g. ping with
+// statistics enabled) keeps recording to the same file. The added data
+// sources are unknown for the time before they were added. existing lists
+// the file's data source names and dataSources the definitions of metrics.
+func addMissingDataSources(path string, existing []string, metrics []check.MetricDef, dataSources []string, logger *logrus.Logger) error {
+	var names, missing []string
+	for i, m := range metrics {
+		if !slices.Contains(existing, m.DSName) {
+			names = append(names, m.DSName)
+			missing = append(missing, dataSources[i])
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	args := append([]string{"tune", path}, missing...)
+	if output, err := exec.Command("rrdtool", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("RRD file %s lacks data sources %v and could not be extended (%s): %w; remove or migrate the file",
+			path, names, strings.TrimSpace(string(output)), err)
+	}
+	logger.Infof("Added data sources %v to RRD file %s.", names, path)
+	return nil
+}
+
+// parseDSNames extracts the data source names from rrdtool info output, in
+// the order they are stored.
+func parseDSNames(info string) []string {
+	var names []string
+	for _, line := range strings.Split(info, "\n") {
+		key, _, ok := strings.Cut(line, " = ")
+		if !ok || !strings.HasPrefix(key, "ds[") || !strings.HasSuffix(key, "].index") {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(strings.TrimPrefix(key, "ds["), "].index"))
+	}
+	return names
+}
+
+// parseStep extracts the step from rrdtool info output.
+func parseStep(info string) (time.Duration, error) {
+	for _, line := range strings.Split(info, "\n") {
+		key, value, ok := strings.Cut(line, " = ")
+		if !ok || key != "step" {
+			continue
+		}
+		secs, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse step %q: %w", value, err)
+		}
+		return time.Duration(secs) * time.Second, nil
+	}
+	return 0, fmt.Errorf("no step in rrdtool info output")
+}
+
 // getLastUpdate retrieves the timestamp of the last update from the RRD file.
 // It returns the Unix timestamp of the most recent entry.
 func (r *RRD) getLastUpdate() (int64, error) {
@@ -173,7 +358,14 @@ func (r *RRD) SafeUpdate(t time.Time, values []string) (int64, error) {
 		copy(parts[1:], values)
 		updateStr := strings.Join(parts, ":")
 
-		cmd := exec.Command("rrdtool", "update", r.file.Name(), updateStr)
+		// Name the data sources, so that a file holding others no longer
+		// declared (e.g. ping statistics that were disabled) still updates.
+		names := make([]string, len(r.metrics))
+		for i, m := range r.metrics {
+			names[i] = m.DSName
+		}
+
+		cmd := exec.Command("rrdtool", "update", r.file.Name(), "--template", strings.Join(names, ":"), updateStr)
 
 		if err := cmd.Run(); err != nil {
 			return 0, fmt.Errorf("failed to update RRD file %s with rrdtool: %w", r.file.Name(), err)
@@ -218,15 +410,51 @@ func (r *RRD) initGraphs() {
 		"5y":  {"AVERAGE", 6 * time.Hour},
 	}
 
-	for timeLength, spec := range specs {
-		graph, err := newGraph(r.name, r.graphDir, r.file.Name(), timeLength, spec.conFunc, r.checkTyp, r.metrics, r.descLabel, spec.interval, r.logger)
-		if err != nil {
-			r.logger.Errorf("Failed to create %s graph for %s with time length %s: %v", spec.conFunc, r.name, timeLength, err)
-			continue
+	for _, group := range graphGroups(r.checkTyp, r.descLabel, r.metrics) {
+		for timeLength, spec := range specs {
+			graph, err := newGraph(r.name, r.graphDir, r.file.Name(), timeLength, spec.conFunc, group.name, group.metrics, group.label, spec.interval, r.logger)
+			if err != nil {
+				r.logger.Errorf("Failed to create %s %s graph for %s with time length %s: %v", group.name, spec.conFunc, r.name, timeLength, err)
+				continue
+			}
+			r.graphs = append(r.graphs, graph)
+			r.logger.Debugf("Added %s %s graph for %s with time length %s.", group.name, spec.conFunc, r.name, timeLength)
 		}
-		r.graphs = append(r.graphs, graph)
-		r.logger.Debugf("Added %s graph for %s with time length %s.", spec.conFunc, r.name, timeLength)
 	}
 
 	r.logger.Debugf("Total graphs initialized for %s: %d", r.name, len(r.graphs))
 }
+
+// graphGroup is a set of metrics drawn together on one graph.
+type graphGroup struct {
+	name    string            // graph name used for file naming, e.g. "ping" or "ping_loss"
+	label   string            // graph title/axis label (may be empty)
+	metrics []check.MetricDef // metrics drawn on the graph
+}
+
+// graphGroups splits metrics into the check's main graph and one graph for
+// each MetricDef.Graph name, in order of first use. A named graph is drawn
+// to {checkType}_{graph} files and labelled by its name. The main graph is
+// left out if every metric belongs to a named graph.
+func graphGroups(checkType string, descLabel string, metrics []check.MetricDef) []graphGroup {
+	main := graphGroup{name: checkType, label: descLabel}
+	var named []graphGroup
+	index := map[string]int{}
+	for _, m := range metrics {
+		if m.Graph == "" {
+			main.metrics = append(main.metrics, m)
+			continue
+		}
+		i, ok := index[m.Graph]
+		if !ok {
+			i = len(named)
+			index[m.Graph] = i
+			named = append(named, graphGroup{name: checkType + "_" + m.Graph, label: m.Graph})
+		}
+		named[i].metrics = append(named[i].metrics, m)
+	}
+	if len(main.metrics) == 0 {
+		return named
+	}
+	return append([]graphGroup{main}, named...)
+}
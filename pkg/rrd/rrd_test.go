@@ -1,7 +1,11 @@
 package rrd
 
 import (
+	"math"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kylerisse/wasgeht/pkg/check"
 )
@@ -83,6 +87,187 @@ func TestDisplayVarName(t *testing.T) {
 	}
 }
 
+func TestDataSource(t *testing.T) {
+	tests := []struct {
+		name string
+		m    check.MetricDef
+		want string
+	}{
+		{"unsigned", check.MetricDef{DSName: "latency"}, "DS:latency:GAUGE:120:0:U"},
+		{"signed", check.MetricDef{DSName: "s0_offset", Signed: true}, "DS:s0_offset:GAUGE:120:U:U"},
+		{"counter", check.MetricDef{DSName: "in", DSType: check.DSCounter}, "DS:in:COUNTER:120:0:U"},
+		{"derive", check.MetricDef{DSName: "out", DSType: check.DSDerive}, "DS:out:DERIVE:120:0:U"},
+		{"absolute", check.MetricDef{DSName: "events", DSType: check.DSAbsolute}, "DS:events:ABSOLUTE:120:0:U"},
+		{"max", check.MetricDef{DSName: "in", DSType: check.DSDerive, Max: check.Bound(1e10)}, "DS:in:DERIVE:120:0:10000000000"},
+		{"min overrides signed", check.MetricDef{DSName: "temp", Signed: true, Min: check.Bound(-40.5)}, "DS:temp:GAUGE:120:-40.5:U"},
+		{"min and max", check.MetricDef{DSName: "load", Min: check.Bound(0), Max: check.Bound(100)}, "DS:load:GAUGE:120:0:100"},
+		{"heartbeat", check.MetricDef{DSName: "latency", Heartbeat: 10 * time.Minute}, "DS:latency:GAUGE:600:0:U"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dataSource(tt.m, time.Minute)
+			if err != nil {
+				t.Fatalf("dataSource() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("dataSource() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataSource_DefaultHeartbeatFollowsStep(t *testing.T) {
+	got, err := dataSource(check.MetricDef{DSName: "days"}, time.Hour)
+	if err != nil {
+		t.Fatalf("dataSource() error: %v", err)
+	}
+	if want := "DS:days:GAUGE:7200:0:U"; got != want {
+		t.Errorf("dataSource() = %q, want %q", got, want)
+	}
+}
+
+func TestRoundRobinArchives(t *testing.T) {
+	tests := []struct {
+		name string
+		step time.Duration
+		want []string
+	}{
+		{"one minute", time.Minute, []string{
+			"RRA:MAX:0.5:1:10080",
+			"RRA:AVERAGE:0.5:1:10080",
+			"RRA:AVERAGE:0.5:5:8928",
+			"RRA:AVERAGE:0.5:15:8736",
+			"RRA:AVERAGE:0.5:60:8784",
+			"RRA:AVERAGE:0.5:480:5490",
+		}},
+		{"fifteen seconds", 15 * time.Second, []string{
+			"RRA:MAX:0.5:1:40320",
+			"RRA:AVERAGE:0.5:1:40320",
+			"RRA:AVERAGE:0.5:20:8928",
+			"RRA:AVERAGE:0.5:60:8736",
+			"RRA:AVERAGE:0.5:240:8784",
+			"RRA:AVERAGE:0.5:1920:5490",
+		}},
+		{"one hour", time.Hour, []string{
+			"RRA:MAX:0.5:1:168",
+			"RRA:AVERAGE:0.5:1:8784",
+			"RRA:AVERAGE:0.5:8:5490",
+		}},
+		{"seven minutes", 7 * time.Minute, []string{
+			"RRA:MAX:0.5:1:1440",
+			"RRA:AVERAGE:0.5:1:6378",
+			"RRA:AVERAGE:0.5:3:6240",
+			"RRA:AVERAGE:0.5:9:8366",
+			"RRA:AVERAGE:0.5:69:5456",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := roundRobinArchives(tt.step)
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("roundRobinArchives(%v) = %v, want %v", tt.step, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStep(t *testing.T) {
+	info := "filename = \"ping.rrd\"\nrrd_version = \"0003\"\nstep = 15\nlast_update = 1700000000\n"
+	step, err := parseStep(info)
+	if err != nil {
+		t.Fatalf("parseStep() error: %v", err)
+	}
+	if step != 15*time.Second {
+		t.Errorf("parseStep() = %v, want 15s", step)
+	}
+
+	if _, err := parseStep("filename = \"ping.rrd\"\n"); err == nil {
+		t.Error("expected error for output without a step")
+	}
+}
+
+func TestParseDSNames(t *testing.T) {
+	info := "step = 60\nds[addr0].index = 0\nds[addr0].type = \"GAUGE\"\nds[addr0_loss].index = 1\nds[addr0_loss].min = 0.0000000000e+00\nrra[0].cf = \"MAX\"\n"
+	if got := parseDSNames(info); !slices.Equal(got, []string{"addr0", "addr0_loss"}) {
+		t.Errorf("parseDSNames() = %v, want [addr0 addr0_loss]", got)
+	}
+	if got := parseDSNames("step = 60\n"); got != nil {
+		t.Errorf("expected no data sources, got %v", got)
+	}
+}
+
+func TestDataSource_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		m    check.MetricDef
+	}{
+		{"unknown type", check.MetricDef{DSName: "x", DSType: "COMPUTE"}},
+		{"negative heartbeat", check.MetricDef{DSName: "x", Heartbeat: -time.Second}},
+		{"fractional heartbeat", check.MetricDef{DSName: "x", Heartbeat: 1500 * time.Millisecond}},
+		{"min above max", check.MetricDef{DSName: "x", Min: check.Bound(10), Max: check.Bound(5)}},
+		{"min equals max", check.MetricDef{DSName: "x", Min: check.Bound(5), Max: check.Bound(5)}},
+		{"max below default min", check.MetricDef{DSName: "x", Max: check.Bound(-1)}},
+		{"infinite max", check.MetricDef{DSName: "x", Max: check.Bound(math.Inf(1))}},
+		{"NaN min", check.MetricDef{DSName: "x", Min: check.Bound(math.NaN())}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ds, err := dataSource(tt.m, time.Minute); err == nil {
+				t.Errorf("expected error, got %q", ds)
+			}
+		})
+	}
+}
+
+func TestDrawArgs_Rate(t *testing.T) {
+	g := &graph{
+		rrdPath:               "/tmp/x.rrd",
+		filePath:              "/tmp/x.png",
+		timeLength:            "1h",
+		consolidationFunction: "MAX",
+		metrics: []check.MetricDef{
+			{DSName: "in", Label: "uplink in", Unit: "bits", DSType: check.DSDerive},
+		},
+	}
+	args := strings.Join(g.drawArgs(), "\n")
+	for _, want := range []string{"uplink in (bits/s)", "GPRINT:in_raw:LAST:Last\\: %.2lf %sbits/s"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected args to contain %q", want)
+		}
+	}
+
+	g.metrics[0].DSType = check.DSGauge
+	args = strings.Join(g.drawArgs(), "\n")
+	if !strings.Contains(args, "GPRINT:in_raw:LAST:Last\\: %.2lf bits") || strings.Contains(args, "bits/s") {
+		t.Errorf("expected gauge to be printed without a rate unit, got %q", args)
+	}
+}
+
+func TestGraphGroups(t *testing.T) {
+	groups := graphGroups("ping", "", []check.MetricDef{
+		{DSName: "rtt", Label: "latency", Unit: "ms"},
+		{DSName: "loss", Label: "loss", Unit: "%", Graph: "loss"},
+		{DSName: "max", Label: "max", Unit: "ms"},
+	})
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 graphs, got %d: %+v", len(groups), groups)
+	}
+	if groups[0].name != "ping" || groups[0].label != "" || len(groups[0].metrics) != 2 {
+		t.Errorf("unexpected main graph: %+v", groups[0])
+	}
+	if groups[1].name != "ping_loss" || groups[1].label != "loss" || len(groups[1].metrics) != 1 || groups[1].metrics[0].DSName != "loss" {
+		t.Errorf("unexpected loss graph: %+v", groups[1])
+	}
+
+	groups = graphGroups("snmp", "traffic", []check.MetricDef{{DSName: "a", Graph: "errors"}})
+	if len(groups) != 1 || groups[0].name != "snmp_errors" {
+		t.Errorf("expected only the named graph when no metric is on the main graph, got %+v", groups)
+	}
+}
+
 func TestLineColors_HasTenEntries(t *testing.T) {
 	if len(lineColors) != 10 {
 		t.Errorf("expected 10 lineColors, got %d", len(lineColors))
@@ -120,3 +305,63 @@ func TestLineColors_AllValidHex(t *testing.T) {
 		}
 	}
 }
+
+func TestDrawArgs_LinesOnly(t *testing.T) {
+	g := &graph{
+		rrdPath:               "/tmp/x.rrd",
+		filePath:              "/tmp/x.png",
+		timeLength:            "1h",
+		consolidationFunction: "MAX",
+		metrics: []check.MetricDef{
+			{ResultKey: "http://a.com", DSName: "url0", Label: "http://a.com", Unit: "ms", Scale: 1000},
+			{ResultKey: "http://b.com", DSName: "url1", Label: "http://b.com", Unit: "ms", Scale: 1000},
+		},
+	}
+	args := strings.Join(g.drawArgs(), "\n")
+	if strings.Contains(args, "AREA:") {
+		t.Error("expected no AREA elements for unstacked metrics")
+	}
+	for _, want := range []string{"LINE2:url0_ms#", "LINE2:url1_ms#", "CDEF:url0_ms=url0_raw,1000,/"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected args to contain %q", want)
+		}
+	}
+}
+
+func TestDrawArgs_StackGroups(t *testing.T) {
+	g := &graph{
+		rrdPath:               "/tmp/x.rrd",
+		filePath:              "/tmp/x.png",
+		timeLength:            "1h",
+		consolidationFunction: "MAX",
+		metrics: []check.MetricDef{
+			{DSName: "url0", Label: "total", Unit: "ms", Scale: 1000},
+			{DSName: "url0_dns", Label: "dns", Unit: "ms", Scale: 1000, Stack: "url0"},
+			{DSName: "url0_conn", Label: "connect", Unit: "ms", Scale: 1000, Stack: "url0"},
+			{DSName: "url1_dns", Label: "dns", Unit: "ms", Scale: 1000, Stack: "url1"},
+			{DSName: "url1_conn", Label: "connect", Unit: "ms", Scale: 1000, Stack: "url1"},
+		},
+	}
+	args := g.drawArgs()
+
+	var drawn []string
+	for _, a := range args {
+		if strings.HasPrefix(a, "AREA:") || strings.HasPrefix(a, "LINE2:") {
+			drawn = append(drawn, a)
+		}
+	}
+	if len(drawn) != 5 {
+		t.Fatalf("expected 5 drawing elements, got %d: %v", len(drawn), drawn)
+	}
+
+	wantPrefix := []string{"AREA:url0_dns_ms#", "AREA:url0_conn_ms#", "AREA:url1_dns_ms#", "AREA:url1_conn_ms#", "LINE2:url0_ms#"}
+	wantStack := []bool{false, true, false, true, false}
+	for i, d := range drawn {
+		if !strings.HasPrefix(d, wantPrefix[i]) {
+			t.Errorf("element %d: expected prefix %q, got %q", i, wantPrefix[i], d)
+		}
+		if got := strings.HasSuffix(d, ":STACK"); got != wantStack[i] {
+			t.Errorf("element %d: expected STACK=%v, got %q", i, wantStack[i], d)
+		}
+	}
+}
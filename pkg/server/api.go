@@ -8,14 +8,20 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kylerisse/wasgeht/pkg/check"
 	"golang.org/x/time/rate"
 )
 
 // CheckStatusResponse represents the status of a single check in the API response.
 type CheckStatusResponse struct {
-	Alive      bool              `json:"alive"`
-	Metrics    map[string]*int64 `json:"metrics,omitempty"`
-	LastUpdate int64             `json:"lastupdate"`
+	Alive      bool                `json:"alive"`
+	Degraded   bool                `json:"degraded,omitempty"`
+	Unknown    bool                `json:"unknown,omitempty"`
+	State      check.StateType     `json:"state"`
+	Attempt    int                 `json:"attempt,omitempty"`
+	Metrics    map[string]*float64 `json:"metrics,omitempty"`
+	LastUpdate int64               `json:"lastupdate"`
+	Graphs     []string            `json:"graphs,omitempty"`
 }
 
 // HostAPIResponse represents a host in the API response.
@@ -125,8 +131,13 @@ func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
 		for checkType, snap := range snapshots {
 			checksResponse[checkType] = CheckStatusResponse{
 				Alive:      snap.Alive,
+				Degraded:   snap.Degraded,
+				Unknown:    snap.Unknown,
+				State:      snap.State,
+				Attempt:    snap.Attempt,
 				Metrics:    snap.Metrics,
 				LastUpdate: snap.LastUpdate,
+				Graphs:     snap.Graphs,
 			}
 		}
 
@@ -227,8 +238,13 @@ func (s *Server) handleHostAPI(w http.ResponseWriter, r *http.Request) {
 	for checkType, snap := range snapshots {
 		checksResponse[checkType] = CheckStatusResponse{
 			Alive:      snap.Alive,
+			Degraded:   snap.Degraded,
+			Unknown:    snap.Unknown,
+			State:      snap.State,
+			Attempt:    snap.Attempt,
 			Metrics:    snap.Metrics,
 			LastUpdate: snap.LastUpdate,
+			Graphs:     snap.Graphs,
 		}
 	}
 
@@ -2,8 +2,10 @@ package server
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,7 +25,7 @@ func TestHandleAPI_BasicResponse(t *testing.T) {
 	status := s.getOrCreateStatus("google", "ping")
 	status.SetResult(check.Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(12345)},
+		Metrics: map[string]*float64{"latency_us": p64(12345)},
 	})
 	status.SetLastUpdate(1700000000)
 
@@ -67,6 +69,76 @@ func TestHandleAPI_BasicResponse(t *testing.T) {
 	}
 }
 
+
+func TestHandleAPI_MetricValues(t *testing.T) {
+	s := &Server{
+		hosts: map[string]*host.Host{
+			"ups": {Name: "ups"},
+		},
+		statuses: make(map[string]map[string]*check.Status),
+	}
+
+	status := s.getOrCreateStatus("ups", "json")
+	status.SetResult(check.Result{
+		Success: true,
+		Metrics: map[string]*float64{
+			"load":    p64(23),
+			"voltage": p64(230.5),
+			"bad":     p64(math.NaN()),
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	w := httptest.NewRecorder()
+
+	s.handleAPI(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	// Whole values keep their integer encoding; NaN cannot be encoded and
+	// is reported as null.
+	body := w.Body.String()
+	for _, want := range []string{`"load":23,`, `"voltage":230.5`, `"bad":null`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected body to contain %s, got %s", want, body)
+		}
+	}
+}
+
+func TestHandleAPI_SoftState(t *testing.T) {
+	s := &Server{
+		hosts: map[string]*host.Host{
+			"flaky": {Name: "flaky"},
+		},
+		statuses: make(map[string]map[string]*check.Status),
+	}
+
+	status := s.getOrCreateStatus("flaky", "ping")
+	status.SetRetries(2)
+	status.SetResult(check.Result{Success: true})
+	status.SetResult(check.Result{Success: false})
+	status.SetLastUpdate(time.Now().Unix())
+
+	req := httptest.NewRequest("GET", "/api", nil)
+	w := httptest.NewRecorder()
+
+	s.handleAPI(w, req)
+
+	var resp APIResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	h := resp.Hosts["flaky"]
+	got := h.Checks["ping"]
+	if !got.Alive || got.State != check.StateSoft || got.Attempt != 1 {
+		t.Errorf("expected alive soft failure at attempt 1, got alive=%v state=%q attempt=%d", got.Alive, got.State, got.Attempt)
+	}
+	if h.Status != HostStatusUp {
+		t.Errorf("expected a soft failure to leave the host up, got %q", h.Status)
+	}
+}
+
 func TestHandleAPI_IncludesHostStatus(t *testing.T) {
 	s := &Server{
 		hosts: map[string]*host.Host{
@@ -659,9 +731,10 @@ func TestHandleHostAPI_Found(t *testing.T) {
 	status := s.getOrCreateStatus("ap1", "ping")
 	status.SetResult(check.Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(5000)},
+		Metrics: map[string]*float64{"latency_us": p64(5000)},
 	})
 	status.SetLastUpdate(time.Now().Unix())
+	status.SetGraphs([]string{"loss"})
 
 	req := httptest.NewRequest("GET", "/api/hosts/ap1", nil)
 	req.SetPathValue("hostname", "ap1")
@@ -685,9 +758,13 @@ func TestHandleHostAPI_Found(t *testing.T) {
 	if body.Tags["category"] != "ap" {
 		t.Errorf("expected category=ap, got %q", body.Tags["category"])
 	}
-	if _, ok := body.Checks["ping"]; !ok {
+	ping, ok := body.Checks["ping"]
+	if !ok {
 		t.Error("expected ping check in response")
 	}
+	if len(ping.Graphs) != 1 || ping.Graphs[0] != "loss" {
+		t.Errorf("expected graphs [loss], got %v", ping.Graphs)
+	}
 }
 
 func TestHandleHostAPI_NotFound(t *testing.T) {
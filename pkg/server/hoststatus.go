@@ -20,35 +20,47 @@ const (
 	HostStatusStale HostStatus = "stale"
 	// HostStatusUp means all checks are up and have recent results.
 	HostStatusUp HostStatus = "up"
-	// HostStatusDegraded means some checks are up and some are down, stale, or pending.
+	// HostStatusDegraded means some checks are up and some are down, stale, or pending,
+	// or all checks are up but at least one reported a warning condition.
 	HostStatusDegraded HostStatus = "degraded"
 	// HostStatusDown means all checks have fresh results and all are down.
 	HostStatusDown HostStatus = "down"
 )
 
-// stalenessWindow is how old a check result can be before it's considered stale.
+// stalenessWindow is how old a check result can be before it's considered
+// stale. Checks that run less often get two intervals instead.
 const stalenessWindow = 5 * time.Minute
 
+// staleAfter returns how old the last update of a check running every
+// interval can be before the check is considered stale.
+func staleAfter(interval time.Duration) time.Duration {
+	return max(stalenessWindow, 2*interval)
+}
+
 // computeHostStatus determines the aggregate status of a host from its check snapshots.
 // Each check is classified into one of four buckets:
 //   - never_run:  LastUpdate == 0
-//   - fresh_up:   LastUpdate > cutoff && Alive
+//   - fresh_up:   LastUpdate > cutoff && Alive (counted as warned if also Degraded)
 //   - fresh_down: LastUpdate > cutoff && !Alive
 //   - stale:      LastUpdate > 0 && LastUpdate <= cutoff
+//
+// The cutoff is per check, since it depends on the check's interval.
 func computeHostStatus(snapshots map[string]check.StatusSnapshot, now time.Time) HostStatus {
 	if len(snapshots) == 0 {
 		return HostStatusUnconfigured
 	}
 
-	cutoff := now.Add(-stalenessWindow).Unix()
-
-	var neverRun, freshUp, freshDown, staleCount int
+	var neverRun, freshUp, freshDown, staleCount, warned int
 	for _, snap := range snapshots {
+		cutoff := now.Add(-staleAfter(snap.Interval)).Unix()
 		switch {
 		case snap.LastUpdate == 0:
 			neverRun++
 		case snap.LastUpdate > cutoff && snap.Alive:
 			freshUp++
+			if snap.Degraded {
+				warned++
+			}
 		case snap.LastUpdate > cutoff && !snap.Alive:
 			freshDown++
 		default:
@@ -59,7 +71,7 @@ func computeHostStatus(snapshots map[string]check.StatusSnapshot, now time.Time)
 	switch {
 	case neverRun == len(snapshots):
 		return HostStatusPending
-	case freshUp > 0 && freshDown == 0 && staleCount == 0 && neverRun == 0:
+	case freshUp > 0 && freshDown == 0 && staleCount == 0 && neverRun == 0 && warned == 0:
 		return HostStatusUp
 	case freshUp > 0:
 		return HostStatusDegraded
@@ -70,6 +70,14 @@ func TestComputeHostStatus(t *testing.T) {
 			want: HostStatusDown,
 		},
 		// degraded
+		{
+			name: "all fresh up but one check warned",
+			snapshots: map[string]check.StatusSnapshot{
+				"ping":     {Alive: true, LastUpdate: fresh},
+				"tls_cert": {Alive: true, Degraded: true, LastUpdate: fresh},
+			},
+			want: HostStatusDegraded,
+		},
 		{
 			name: "mixed fresh up and fresh down",
 			snapshots: map[string]check.StatusSnapshot{
@@ -127,6 +135,22 @@ func TestComputeHostStatus(t *testing.T) {
 			},
 			want: HostStatusStale,
 		},
+		// interval-aware staleness
+		{
+			name:      "hourly check not stale after ten minutes",
+			snapshots: map[string]check.StatusSnapshot{"tls_cert": {Alive: true, LastUpdate: stale, Interval: time.Hour}},
+			want:      HostStatusUp,
+		},
+		{
+			name:      "hourly check stale after two intervals",
+			snapshots: map[string]check.StatusSnapshot{"tls_cert": {Alive: true, LastUpdate: now.Add(-2 * time.Hour).Unix(), Interval: time.Hour}},
+			want:      HostStatusStale,
+		},
+		{
+			name:      "short interval keeps staleness window",
+			snapshots: map[string]check.StatusSnapshot{"ping": {Alive: true, LastUpdate: now.Add(-4 * time.Minute).Unix(), Interval: 15 * time.Second}},
+			want:      HostStatusUp,
+		},
 	}
 
 	for _, tt := range tests {
@@ -235,10 +259,10 @@ func TestCheckStatusResponse_OmitsEmptyMetrics(t *testing.T) {
 }
 
 func TestCheckStatusResponse_IncludesMetrics(t *testing.T) {
-	v := int64(12345)
+	v := float64(12345)
 	resp := CheckStatusResponse{
 		Alive:      true,
-		Metrics:    map[string]*int64{"latency_us": &v},
+		Metrics:    map[string]*float64{"latency_us": &v},
 		LastUpdate: 1700000000,
 	}
 
@@ -3,10 +3,13 @@ package server
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// handlePrometheus writes Prometheus-formatted metrics for all hosts and their checks.
+// handlePrometheus writes Prometheus-formatted metrics for all hosts and
+// their checks, followed by the scheduler's own metrics.
 func (s *Server) handlePrometheus(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 
@@ -35,15 +38,60 @@ func (s *Server) handlePrometheus(w http.ResponseWriter, _ *http.Request) {
 					continue
 				}
 				w.Write(fmt.Appendf([]byte{},
-					"check_metric{host=\"%s\", check=\"%s\", metric=\"%s\"} %d\n",
+					"check_metric{host=\"%s\", check=\"%s\", metric=\"%s\"} %s\n",
 					sanitizedName,
 					sanitizedCheck,
 					sanitizePrometheusLabel(metricKey),
-					*metricVal,
+					strconv.FormatFloat(*metricVal, 'f', -1, 64),
 				))
 			}
 		}
 	}
+
+	if s.scheduler != nil {
+		writeSchedulerMetrics(w, s.scheduler.snapshot())
+	}
+}
+
+// writeSchedulerMetrics writes the scheduler's queue depth, worker usage,
+// lateness and run duration in Prometheus format.
+func writeSchedulerMetrics(w http.ResponseWriter, st schedulerStats) {
+	gauges := []struct {
+		name, help string
+		value      int
+	}{
+		{"scheduler_checks", "Number of check instances being scheduled.", st.Checks},
+		{"scheduler_workers", "Size of the check worker pool.", st.Workers},
+		{"scheduler_busy_workers", "Number of workers currently running a check.", st.Busy},
+		{"scheduler_queue_depth", "Number of check runs that are due but waiting for a free worker.", st.Due},
+	}
+	for _, g := range gauges {
+		w.Write(fmt.Appendf([]byte{}, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", g.name, g.help, g.name, g.name, g.value))
+	}
+
+	counters := []struct {
+		name, help string
+		value      uint64
+	}{
+		{"scheduler_retries_total", "Check runs that retried a soft failure.", st.Retries},
+		{"scheduler_skipped_runs_total", "Check runs skipped because the previous run had not finished in time.", st.Skipped},
+	}
+	for _, c := range counters {
+		w.Write(fmt.Appendf([]byte{}, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.value))
+	}
+
+	summaries := []struct {
+		name, help string
+		sum        time.Duration
+	}{
+		{"scheduler_run_lateness_seconds", "Time check runs started after they were due.", st.Lateness},
+		{"scheduler_run_duration_seconds", "Time spent running checks.", st.Duration},
+	}
+	for _, m := range summaries {
+		w.Write(fmt.Appendf([]byte{}, "# HELP %s %s\n# TYPE %s summary\n", m.name, m.help, m.name))
+		w.Write(fmt.Appendf([]byte{}, "%s_sum %s\n%s_count %d\n",
+			m.name, strconv.FormatFloat(m.sum.Seconds(), 'f', -1, 64), m.name, st.Runs))
+	}
 }
 
 // sanitizePrometheusLabel escapes backslash, double-quote, and newline
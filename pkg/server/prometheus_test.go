@@ -1,10 +1,12 @@
 package server
 
 import (
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kylerisse/wasgeht/pkg/check"
 	"github.com/kylerisse/wasgeht/pkg/host"
@@ -22,7 +24,7 @@ func TestHandlePrometheus_BasicOutput(t *testing.T) {
 	status := s.getOrCreateStatus("google", "ping")
 	status.SetResult(check.Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(12345)},
+		Metrics: map[string]*float64{"latency_us": p64(12345)},
 	})
 
 	req := httptest.NewRequest("GET", "/metrics", nil)
@@ -64,6 +66,86 @@ func TestHandlePrometheus_BasicOutput(t *testing.T) {
 	}
 }
 
+func TestHandlePrometheus_FractionalValues(t *testing.T) {
+	s := &Server{
+		hosts: map[string]*host.Host{
+			"ntp": {Name: "ntp"},
+		},
+		statuses: make(map[string]map[string]*check.Status),
+	}
+
+	status := s.getOrCreateStatus("ntp", "ntp")
+	status.SetResult(check.Result{
+		Success: true,
+		Metrics: map[string]*float64{
+			"offset": p64(-1234.567),
+			"big":    p64(12345678901),
+			"bad":    p64(math.Inf(-1)),
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	s.handlePrometheus(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`check_metric{host="ntp", check="ntp", metric="offset"} -1234.567`,
+		`check_metric{host="ntp", check="ntp", metric="big"} 12345678901`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("expected %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `metric="bad"`) {
+		t.Errorf("expected no line for a non-finite value, got:\n%s", body)
+	}
+}
+
+func TestHandlePrometheus_SchedulerMetrics(t *testing.T) {
+	sc := newScheduler(4, nil)
+	sc.stats.Checks = 10
+	sc.stats.Busy = 1
+	sc.stats.Runs = 3
+	sc.stats.Skipped = 2
+	sc.stats.Retries = 5
+	sc.stats.Lateness = 1500 * time.Millisecond
+	sc.stats.Duration = 6 * time.Second
+	sc.queue = runQueue{{next: time.Now().Add(-time.Second)}, {next: time.Now().Add(time.Hour)}}
+
+	s := &Server{
+		hosts:     map[string]*host.Host{},
+		statuses:  make(map[string]map[string]*check.Status),
+		scheduler: sc,
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+
+	s.handlePrometheus(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE scheduler_queue_depth gauge",
+		"scheduler_checks 10",
+		"scheduler_workers 4",
+		"scheduler_busy_workers 1",
+		"scheduler_queue_depth 1",
+		"scheduler_skipped_runs_total 2",
+		"scheduler_retries_total 5",
+		"# TYPE scheduler_run_lateness_seconds summary",
+		"scheduler_run_lateness_seconds_sum 1.5",
+		"scheduler_run_lateness_seconds_count 3",
+		"scheduler_run_duration_seconds_sum 6",
+		"scheduler_run_duration_seconds_count 3",
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("expected %q, got:\n%s", want, body)
+		}
+	}
+}
+
 func TestHandlePrometheus_DownHost(t *testing.T) {
 	s := &Server{
 		hosts: map[string]*host.Host{
@@ -123,13 +205,13 @@ func TestHandlePrometheus_MultipleChecks(t *testing.T) {
 	pingStatus := s.getOrCreateStatus("multi", "ping")
 	pingStatus.SetResult(check.Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(500)},
+		Metrics: map[string]*float64{"latency_us": p64(500)},
 	})
 
 	httpStatus := s.getOrCreateStatus("multi", "http")
 	httpStatus.SetResult(check.Result{
 		Success: true,
-		Metrics: map[string]*int64{"response_ms": p64(42)},
+		Metrics: map[string]*float64{"response_ms": p64(42)},
 	})
 
 	req := httptest.NewRequest("GET", "/metrics", nil)
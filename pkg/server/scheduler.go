@@ -0,0 +1,258 @@
+package server
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// startupSpread is the longest time over which the first runs of all checks
+// are spread after startup. Checks with shorter intervals are spread over
+// their interval instead.
+const startupSpread = 2 * time.Minute
+
+// scheduledCheck is a check instance waiting in the scheduler's queue.
+type scheduledCheck struct {
+	host  string
+	inst  checkInstance
+	next  time.Time // when the check is next due to run
+	slot  time.Time // latest regular run time, excluding retries
+	index int       // position in the queue, maintained by container/heap
+}
+
+// runQueue is a priority queue of scheduled checks ordered by next run time.
+// It implements heap.Interface.
+type runQueue []*scheduledCheck
+
+func (q runQueue) Len() int           { return len(q) }
+func (q runQueue) Less(i, j int) bool { return q[i].next.Before(q[j].next) }
+
+func (q runQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *runQueue) Push(x any) {
+	c := x.(*scheduledCheck)
+	c.index = len(*q)
+	*q = append(*q, c)
+}
+
+func (q *runQueue) Pop() any {
+	old := *q
+	n := len(old)
+	c := old[n-1]
+	old[n-1] = nil
+	c.index = -1
+	*q = old[:n-1]
+	return c
+}
+
+// schedulerStats is a point-in-time view of the scheduler's own metrics.
+type schedulerStats struct {
+	Checks   int           // check instances being scheduled
+	Workers  int           // size of the worker pool
+	Busy     int           // workers currently running a check
+	Due      int           // runs that are due but waiting for a free worker
+	Runs     uint64        // runs completed since startup
+	Retries  uint64        // runs that were retries of a soft failure
+	Skipped  uint64        // runs skipped because the check was still running or waiting
+	Lateness time.Duration // total time runs started after they were due
+	Duration time.Duration // total time spent running checks
+}
+
+// scheduler runs check instances on their intervals. A single dispatcher
+// takes due checks from a priority queue of next-run times and hands them
+// to a fixed pool of workers. A check is queued again only once its run
+// completes, so runs of the same check never overlap; runs missed in the
+// meantime are skipped rather than made up. When run reports that a check
+// should be retried, it is queued after its retry interval instead, and
+// returns to its regular schedule once the retries are over.
+type scheduler struct {
+	workers int
+	run     func(host string, inst checkInstance) (retry bool)
+	wake    chan struct{} // signals the dispatcher that the queue head may have changed
+
+	mu      sync.Mutex // protects the fields below
+	queue   runQueue
+	pending *scheduledCheck // popped by the dispatcher, not yet taken by a worker
+	stats   schedulerStats
+}
+
+// newScheduler creates a scheduler that calls run for each due check using
+// at most workers goroutines at a time.
+func newScheduler(workers int, run func(host string, inst checkInstance) bool) *scheduler {
+	return &scheduler{
+		workers: workers,
+		run:     run,
+		wake:    make(chan struct{}, 1),
+		stats:   schedulerStats{Workers: workers},
+	}
+}
+
+// start queues checks with their first runs spread evenly after now, then
+// launches the dispatcher and the worker pool. Both stop once done is
+// closed; checks already running are allowed to finish. Every goroutine
+// started is tracked by wg.
+func (sc *scheduler) start(checks []*scheduledCheck, now time.Time, done <-chan struct{}, wg *sync.WaitGroup) {
+	sc.mu.Lock()
+	for i, c := range checks {
+		c.next = firstRun(now, c.inst.interval, i, len(checks))
+		c.slot = c.next
+		heap.Push(&sc.queue, c)
+	}
+	sc.stats.Checks += len(checks)
+	sc.mu.Unlock()
+
+	jobs := make(chan *scheduledCheck)
+
+	wg.Add(1 + sc.workers)
+	go func() {
+		defer wg.Done()
+		sc.dispatch(jobs, done)
+	}()
+	for range sc.workers {
+		go func() {
+			defer wg.Done()
+			sc.work(jobs, done)
+		}()
+	}
+}
+
+// dispatch hands due checks to the worker pool in order of their next run
+// time, sleeping until the earliest one is due.
+func (sc *scheduler) dispatch(jobs chan<- *scheduledCheck, done <-chan struct{}) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		sc.mu.Lock()
+		var due *scheduledCheck
+		wait := time.Hour // nothing queued; wait to be woken
+		if len(sc.queue) > 0 {
+			if wait = time.Until(sc.queue[0].next); wait <= 0 {
+				due = heap.Pop(&sc.queue).(*scheduledCheck)
+				sc.pending = due
+			}
+		}
+		sc.mu.Unlock()
+
+		if due == nil {
+			timer.Reset(wait)
+			select {
+			case <-timer.C:
+			case <-sc.wake:
+			case <-done:
+				return
+			}
+			continue
+		}
+
+		select {
+		case jobs <- due:
+		case <-done:
+			return
+		}
+	}
+}
+
+// work runs checks received from the dispatcher until shutdown.
+func (sc *scheduler) work(jobs <-chan *scheduledCheck, done <-chan struct{}) {
+	for {
+		select {
+		case c := <-jobs:
+			sc.execute(c)
+		case <-done:
+			return
+		}
+	}
+}
+
+// execute runs a check, records how late and how long the run was, and
+// queues the check for its next run.
+func (sc *scheduler) execute(c *scheduledCheck) {
+	start := time.Now()
+	sc.mu.Lock()
+	if sc.pending == c {
+		sc.pending = nil
+	}
+	sc.stats.Busy++
+	sc.mu.Unlock()
+
+	retry := sc.run(c.host, c.inst)
+
+	end := time.Now()
+	wasRetry := !c.next.Equal(c.slot)
+
+	sc.mu.Lock()
+	sc.stats.Busy--
+	sc.stats.Runs++
+	sc.stats.Lateness += max(start.Sub(c.next), 0)
+	sc.stats.Duration += end.Sub(start)
+	if wasRetry {
+		sc.stats.Retries++
+	}
+	switch {
+	case retry:
+		c.next = end.Add(c.inst.retryInterval)
+	case wasRetry:
+		// Regular runs that fell within the retries were replaced by them,
+		// so they do not count as skipped.
+		c.slot, _ = nextRun(c.slot, c.inst.interval, end)
+		c.next = c.slot
+	default:
+		var skipped int
+		c.slot, skipped = nextRun(c.slot, c.inst.interval, end)
+		c.next = c.slot
+		sc.stats.Skipped += uint64(skipped)
+	}
+	heap.Push(&sc.queue, c)
+	sc.mu.Unlock()
+
+	// Wake the dispatcher in case this check is now due before the one it
+	// is waiting for.
+	select {
+	case sc.wake <- struct{}{}:
+	default:
+	}
+}
+
+// snapshot returns the scheduler's current metrics.
+func (sc *scheduler) snapshot() schedulerStats {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	stats := sc.stats
+	now := time.Now()
+	for _, c := range sc.queue {
+		if !c.next.After(now) {
+			stats.Due++
+		}
+	}
+	if sc.pending != nil {
+		stats.Due++
+	}
+	return stats
+}
+
+// firstRun returns when the i-th of n checks first runs after now. First
+// runs are spaced evenly over the check's interval, or over startupSpread
+// if that is shorter, so that checks sharing an interval stay spread out.
+func firstRun(now time.Time, interval time.Duration, i, n int) time.Time {
+	spread := min(interval, startupSpread)
+	return now.Add(spread * time.Duration(i) / time.Duration(n))
+}
+
+// nextRun returns the first run time after now that lies a whole number of
+// intervals after prev, keeping a check on its original schedule. It also
+// returns how many run times were passed over because they were not after
+// now.
+func nextRun(prev time.Time, interval time.Duration, now time.Time) (time.Time, int) {
+	next := prev.Add(interval)
+	if next.After(now) {
+		return next, 0
+	}
+	skipped := int(now.Sub(next)/interval) + 1
+	return next.Add(time.Duration(skipped) * interval), skipped
+}
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunQueue_PopsEarliestFirst(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	var q runQueue
+	for _, offset := range []int{30, 10, 50, 20, 40} {
+		heap.Push(&q, &scheduledCheck{host: fmt.Sprint(offset), next: base.Add(time.Duration(offset) * time.Second)})
+	}
+
+	var got []string
+	for q.Len() > 0 {
+		got = append(got, heap.Pop(&q).(*scheduledCheck).host)
+	}
+	want := []string{"10", "20", "30", "40", "50"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected pop order %v, got %v", want, got)
+	}
+}
+
+func TestFirstRun_SpreadsEvenly(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		i, n     int
+		want     time.Duration
+	}{
+		{"first check runs immediately", time.Minute, 0, 4, 0},
+		{"spread over the interval", time.Minute, 1, 4, 15 * time.Second},
+		{"last check", time.Minute, 3, 4, 45 * time.Second},
+		{"short interval", 20 * time.Second, 2, 4, 10 * time.Second},
+		{"long interval capped at startup spread", time.Hour, 2, 4, time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := firstRun(now, tt.interval, tt.i, tt.n).Sub(now)
+			if got != tt.want {
+				t.Errorf("expected offset %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	prev := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name        string
+		now         time.Duration // elapsed since prev
+		want        time.Duration // next run relative to prev
+		wantSkipped int
+	}{
+		{"finished early", 5 * time.Second, time.Minute, 0},
+		{"finished exactly on the next run", time.Minute, 2 * time.Minute, 1},
+		{"overran one interval", 90 * time.Second, 2 * time.Minute, 1},
+		{"overran several intervals", 200 * time.Second, 4 * time.Minute, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, skipped := nextRun(prev, time.Minute, prev.Add(tt.now))
+			if got := next.Sub(prev); got != tt.want {
+				t.Errorf("expected next run at +%v, got +%v", tt.want, got)
+			}
+			if skipped != tt.wantSkipped {
+				t.Errorf("expected %d skipped, got %d", tt.wantSkipped, skipped)
+			}
+		})
+	}
+}
+
+func TestScheduler_RunsEachCheckRepeatedly(t *testing.T) {
+	var mu sync.Mutex
+	runs := make(map[string]int)
+	enough := make(chan struct{})
+	var once sync.Once
+
+	sc := newScheduler(2, func(host string, _ checkInstance) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		runs[host]++
+		if runs["a"] >= 3 && runs["b"] >= 3 {
+			once.Do(func() { close(enough) })
+		}
+		return false
+	})
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	sc.start([]*scheduledCheck{
+		{host: "a", inst: checkInstance{interval: 10 * time.Millisecond}},
+		{host: "b", inst: checkInstance{interval: 20 * time.Millisecond}},
+	}, time.Now(), done, &wg)
+
+	select {
+	case <-enough:
+	case <-time.After(5 * time.Second):
+		mu.Lock()
+		t.Errorf("expected each check to run at least 3 times, got %v", runs)
+		mu.Unlock()
+	}
+	close(done)
+	wg.Wait()
+
+	st := sc.snapshot()
+	if st.Checks != 2 || st.Workers != 2 {
+		t.Errorf("expected 2 checks on 2 workers, got %d on %d", st.Checks, st.Workers)
+	}
+	if st.Runs < 6 {
+		t.Errorf("expected at least 6 runs recorded, got %d", st.Runs)
+	}
+	if st.Busy != 0 {
+		t.Errorf("expected no busy workers after shutdown, got %d", st.Busy)
+	}
+}
+
+func TestScheduler_BoundsWorkers(t *testing.T) {
+	const workers, checks = 2, 5
+
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	sc := newScheduler(workers, func(string, checkInstance) bool {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		return false
+	})
+
+	queued := make([]*scheduledCheck, checks)
+	for i := range queued {
+		queued[i] = &scheduledCheck{host: fmt.Sprint(i), inst: checkInstance{interval: time.Millisecond}}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	sc.start(queued, time.Now(), done, &wg)
+
+	// Wait for the pool to fill and the remaining checks to come due.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st := sc.snapshot()
+		if st.Busy == workers && st.Due == checks-workers {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d busy and %d due, got %d busy and %d due", workers, checks-workers, st.Busy, st.Due)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(done)
+	close(release)
+	wg.Wait()
+
+	if p := peak.Load(); p > workers {
+		t.Errorf("expected at most %d concurrent runs, got %d", workers, p)
+	}
+}
+
+func TestScheduler_NoOverlappingRuns(t *testing.T) {
+	var running, overlaps, runs atomic.Int32
+	sc := newScheduler(4, func(string, checkInstance) bool {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		time.Sleep(15 * time.Millisecond) // longer than the interval
+		running.Add(-1)
+		runs.Add(1)
+		return false
+	})
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	sc.start([]*scheduledCheck{
+		{host: "slow", inst: checkInstance{interval: 5 * time.Millisecond}},
+	}, time.Now(), done, &wg)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runs.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(done)
+	wg.Wait()
+
+	if n := overlaps.Load(); n != 0 {
+		t.Errorf("expected runs of the same check never to overlap, got %d overlaps", n)
+	}
+	if st := sc.snapshot(); st.Skipped == 0 {
+		t.Error("expected overrunning check to skip runs")
+	}
+}
+
+func TestScheduler_RetriesSoftFailures(t *testing.T) {
+	// The first run fails softly twice before succeeding. Retries come
+	// quickly; the regular interval is far longer than the test.
+	var mu sync.Mutex
+	var starts []time.Time
+	retried := make(chan struct{})
+	sc := newScheduler(1, func(string, checkInstance) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		starts = append(starts, time.Now())
+		if len(starts) == 3 {
+			close(retried)
+		}
+		return len(starts) < 3
+	})
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	c := &scheduledCheck{host: "flaky", inst: checkInstance{interval: time.Hour, retryInterval: 10 * time.Millisecond}}
+	begin := time.Now()
+	sc.start([]*scheduledCheck{c}, begin, done, &wg)
+
+	select {
+	case <-retried:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the soft failure to be retried twice")
+	}
+	// Give the worker time to requeue the check after the last run.
+	deadline := time.Now().Add(5 * time.Second)
+	for sc.snapshot().Runs < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(done)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < 10*time.Millisecond {
+			t.Errorf("retry %d started %v after the previous run, expected at least the retry interval", i, gap)
+		}
+	}
+	if len(starts) != 3 {
+		t.Errorf("expected 3 runs, got %d", len(starts))
+	}
+
+	st := sc.snapshot()
+	if st.Retries != 2 {
+		t.Errorf("expected 2 retries, got %d", st.Retries)
+	}
+	if st.Skipped != 0 {
+		t.Errorf("expected no skipped runs, got %d", st.Skipped)
+	}
+	if want := begin.Add(time.Hour); !c.next.Equal(want) || !c.slot.Equal(want) {
+		t.Errorf("expected next regular run at %v after recovering, got next %v slot %v", want, c.next, c.slot)
+	}
+}
@@ -12,8 +12,17 @@ import (
 
 	"github.com/kylerisse/wasgeht/pkg/check"
 	checkdns "github.com/kylerisse/wasgeht/pkg/check/dns"
+	checkexec "github.com/kylerisse/wasgeht/pkg/check/exec"
 	checkhttp "github.com/kylerisse/wasgeht/pkg/check/http"
+	"github.com/kylerisse/wasgeht/pkg/check/jsonapi"
+	"github.com/kylerisse/wasgeht/pkg/check/mail"
+	"github.com/kylerisse/wasgeht/pkg/check/ntp"
 	"github.com/kylerisse/wasgeht/pkg/check/ping"
+	checkprometheus "github.com/kylerisse/wasgeht/pkg/check/prometheus"
+	"github.com/kylerisse/wasgeht/pkg/check/snmp"
+	checkssh "github.com/kylerisse/wasgeht/pkg/check/ssh"
+	checktcp "github.com/kylerisse/wasgeht/pkg/check/tcp"
+	"github.com/kylerisse/wasgeht/pkg/check/tlscert"
 	"github.com/kylerisse/wasgeht/pkg/check/wifistations"
 	"github.com/kylerisse/wasgeht/pkg/host"
 	"github.com/sirupsen/logrus"
@@ -28,14 +37,20 @@ type Server struct {
 	httpServer *http.Server
 	done       chan struct{}
 	wg         sync.WaitGroup
+	scheduler  *scheduler
 	logger     *logrus.Logger
 	rrdDir     string
 	graphDir   string
 	listenPort string
 }
 
-// NewServer initializes a new server with the given host file
-func NewServer(hostFile string, rrdDir string, graphDir string, listenPort string, logger *logrus.Logger) (*Server, error) {
+// NewServer initializes a new server with the given host file. Checks are
+// run by a pool of maxConcurrentChecks workers shared by all hosts.
+func NewServer(hostFile string, rrdDir string, graphDir string, listenPort string, maxConcurrentChecks int, logger *logrus.Logger) (*Server, error) {
+	if maxConcurrentChecks < 1 {
+		return nil, fmt.Errorf("max concurrent checks must be at least 1, got %d", maxConcurrentChecks)
+	}
+
 	hosts, err := loadHosts(hostFile)
 	if err != nil {
 		return nil, err
@@ -54,6 +69,42 @@ func NewServer(hostFile string, rrdDir string, graphDir string, listenPort strin
 	if err := registry.Register(checkdns.TypeName, checkdns.Factory); err != nil {
 		return nil, fmt.Errorf("failed to register dns check: %w", err)
 	}
+	if err := registry.Register(checkdns.SerialTypeName, checkdns.SerialFactory); err != nil {
+		return nil, fmt.Errorf("failed to register dns_serial check: %w", err)
+	}
+	if err := registry.Register(checktcp.TypeName, checktcp.Factory); err != nil {
+		return nil, fmt.Errorf("failed to register tcp check: %w", err)
+	}
+	if err := registry.Register(tlscert.TypeName, tlscert.Factory); err != nil {
+		return nil, fmt.Errorf("failed to register tls_cert check: %w", err)
+	}
+	if err := registry.Register(checkprometheus.TypeName, checkprometheus.Factory); err != nil {
+		return nil, fmt.Errorf("failed to register prometheus check: %w", err)
+	}
+	if err := registry.Register(jsonapi.TypeName, jsonapi.Factory); err != nil {
+		return nil, fmt.Errorf("failed to register json check: %w", err)
+	}
+	if err := registry.Register(checkexec.TypeName, checkexec.Factory); err != nil {
+		return nil, fmt.Errorf("failed to register exec check: %w", err)
+	}
+	if err := registry.Register(checkssh.TypeName, checkssh.Factory); err != nil {
+		return nil, fmt.Errorf("failed to register ssh check: %w", err)
+	}
+	if err := registry.Register(mail.SMTPTypeName, mail.SMTPFactory); err != nil {
+		return nil, fmt.Errorf("failed to register smtp check: %w", err)
+	}
+	if err := registry.Register(mail.IMAPTypeName, mail.IMAPFactory); err != nil {
+		return nil, fmt.Errorf("failed to register imap check: %w", err)
+	}
+	if err := registry.Register(mail.POP3TypeName, mail.POP3Factory); err != nil {
+		return nil, fmt.Errorf("failed to register pop3 check: %w", err)
+	}
+	if err := registry.Register(ntp.TypeName, ntp.Factory); err != nil {
+		return nil, fmt.Errorf("failed to register ntp check: %w", err)
+	}
+	if err := registry.Register(snmp.TypeName, snmp.Factory); err != nil {
+		return nil, fmt.Errorf("failed to register snmp check: %w", err)
+	}
 
 	// Initialize the statuses map with an empty map per host
 	statuses := make(map[string]map[string]*check.Status, len(hosts))
@@ -61,7 +112,7 @@ func NewServer(hostFile string, rrdDir string, graphDir string, listenPort strin
 		statuses[name] = make(map[string]*check.Status)
 	}
 
-	return &Server{
+	s := &Server{
 		hosts:      hosts,
 		statuses:   statuses,
 		registry:   registry,
@@ -70,19 +121,19 @@ func NewServer(hostFile string, rrdDir string, graphDir string, listenPort strin
 		rrdDir:     rrdDir,
 		graphDir:   graphDir,
 		listenPort: listenPort,
-	}, nil
+	}
+	s.scheduler = newScheduler(maxConcurrentChecks, s.runCheck)
+	return s, nil
 }
 
-// Start begins a worker for each host
+// Start serves the API and begins scheduling the checks of all hosts.
 func (s *Server) Start() {
-	s.logger.Info("Starting workers for each host...")
+	s.logger.Info("Starting check scheduler...")
 
 	s.startAPI()
 
-	for name, host := range s.hosts {
-		s.wg.Add(1)
-		go s.worker(name, host)
-	}
+	s.wg.Add(1)
+	go s.startChecks()
 }
 
 // Stop gracefully shuts down the HTTP server and all workers.
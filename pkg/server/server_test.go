@@ -51,7 +51,7 @@ func TestHostStatuses_ReturnsSnapshots(t *testing.T) {
 	status := s.getOrCreateStatus("host1", "ping")
 	status.SetResult(check.Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(5000)},
+		Metrics: map[string]*float64{"latency_us": p64(5000)},
 	})
 	status.SetLastUpdate(1700000000)
 
@@ -94,7 +94,7 @@ func TestHostStatuses_SnapshotIndependence(t *testing.T) {
 	status := s.getOrCreateStatus("host1", "ping")
 	status.SetResult(check.Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(1000)},
+		Metrics: map[string]*float64{"latency_us": p64(1000)},
 	})
 
 	snaps := s.hostStatuses("host1")
@@ -145,6 +145,12 @@ func TestLoadHosts_ValidFile(t *testing.T) {
 	}
 }
 
+func TestNewServer_InvalidConcurrency(t *testing.T) {
+	if _, err := NewServer("hosts.json", "rrds", "graphs", "1982", 0, nil); err == nil {
+		t.Error("expected error for zero concurrent checks")
+	}
+}
+
 func TestLoadHosts_MissingFile(t *testing.T) {
 	_, err := loadHosts("/nonexistent/path/hosts.json")
 	if err == nil {
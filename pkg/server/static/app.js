@@ -489,9 +489,9 @@ document.addEventListener('alpine:init', function () {
                 });
             },
 
-            openModal: function (checkType, t) {
-                this.modalSrc = this.imgSrc(checkType, t.key);
-                this.modalAlt = this.hostname + ' ' + checkType + ' ' + t.label;
+            openModal: function (g, t) {
+                this.modalSrc = this.imgSrc(g.name, t.key);
+                this.modalAlt = this.graphAlt(g, t);
                 this.modalOpen = true;
             },
 
@@ -528,8 +528,8 @@ document.addEventListener('alpine:init', function () {
                 this.activeChecks = [];
             },
 
-            imgSrc: function (checkType, timeKey) {
-                return '/imgs/' + this.hostname + '/' + this.hostname + '_' + checkType + '_' + timeKey + '.png?t=' + this.graphTimestamp;
+            imgSrc: function (graphName, timeKey) {
+                return '/imgs/' + this.hostname + '/' + this.hostname + '_' + graphName + '_' + timeKey + '.png?t=' + this.graphTimestamp;
             },
 
             checkLabel: function (checkType) {
@@ -615,12 +615,25 @@ document.addEventListener('alpine:init', function () {
                 });
             },
 
-            graphImgSrc: function (checkType, t) {
-                return this.imgSrc(checkType, t.key);
+            visibleGraphs: function () {
+                var self = this;
+                var graphs = [];
+                this.visibleCheckTypes().forEach(function (ct) {
+                    graphs.push({ name: ct, label: self.checkLabel(ct) });
+                    var data = self.host && self.host.checks && self.host.checks[ct];
+                    ((data && data.graphs) || []).forEach(function (g) {
+                        graphs.push({ name: ct + '_' + g, label: self.checkLabel(ct) + ' ' + g });
+                    });
+                });
+                return graphs;
+            },
+
+            graphImgSrc: function (g, t) {
+                return this.imgSrc(g.name, t.key);
             },
 
-            graphAlt: function (checkType, t) {
-                return this.hostname + ' ' + checkType + ' ' + t.label;
+            graphAlt: function (g, t) {
+                return this.hostname + ' ' + g.label + ' ' + t.label;
             }
         });
     });
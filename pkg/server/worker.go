@@ -2,7 +2,10 @@ package server
 
 import (
 	"context"
-	"math/rand"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"strconv"
 	"time"
 
@@ -11,65 +14,90 @@ import (
 	"github.com/kylerisse/wasgeht/pkg/rrd"
 )
 
-// checkInstance pairs a check with its RRD file, metric definitions, and status tracker.
+// defaultInterval is how often a check runs when its config sets no interval.
+const defaultInterval = time.Minute
+
+// defaultRetryInterval is how soon a soft failure is retried when the config
+// sets no retry interval, unless the check's interval is shorter.
+const defaultRetryInterval = 10 * time.Second
+
+// checkInstance pairs a check with its RRD file, metric definitions, status
+// tracker, run interval, the deadline each run must finish within, and how
+// soon a soft failure is retried.
 type checkInstance struct {
-	check      check.Check
-	rrdFile    *rrd.RRD
-	metricDefs []check.MetricDef
-	status     *check.Status
+	check         check.Check
+	rrdFile       *rrd.RRD
+	metricDefs    []check.MetricDef
+	status        *check.Status
+	interval      time.Duration
+	deadline      time.Duration
+	retryInterval time.Duration
 }
 
-// worker periodically runs all enabled checks against the assigned host.
-func (s *Server) worker(name string, h *host.Host) {
+// startChecks initializes the enabled checks of every host and hands them
+// to the scheduler. Hosts are initialized one at a time to limit
+// filesystem activity while RRD files are created.
+func (s *Server) startChecks() {
 	defer s.wg.Done()
 
-	// Add a random delay of 1-119 seconds before starting to reduce initial filesystem activity.
-	startDelay := time.Duration(rand.Intn(119)+1) * time.Second
-	s.logger.Infof("Worker for host %s will start in %v", name, startDelay)
-	select {
-	case <-time.After(startDelay):
-		s.logger.Debugf("Worker for host %s starting", name)
-	case <-s.done:
-		s.logger.Infof("Worker for host %s received shutdown signal before starting", name)
-		return
-	}
-
-	// Initialize all enabled checks and their RRD files.
-	instances := s.initChecks(name, h)
-	if len(instances) == 0 {
-		s.logger.Warningf("Worker for host %s: no checks to run, exiting", name)
-		return
-	}
-
-	// Run periodic checks every minute.
-	for {
+	var checks []*scheduledCheck
+	for _, name := range slices.Sorted(maps.Keys(s.hosts)) {
 		select {
 		case <-s.done:
-			s.logger.Infof("Worker for host %s received shutdown signal.", name)
+			s.logger.Info("Received shutdown signal while initializing checks.")
 			return
 		default:
-			s.runChecks(name, instances)
-
-			select {
-			case <-time.After(time.Minute):
-				// continue with the next iteration
-			case <-s.done:
-				s.logger.Infof("Worker for host %s received shutdown signal.", name)
-				return
-			}
+		}
+
+		instances := s.initChecks(name, s.hosts[name])
+		if len(instances) == 0 {
+			s.logger.Warningf("Worker for host %s: no checks to run", name)
+			continue
+		}
+		for _, inst := range instances {
+			checks = append(checks, &scheduledCheck{host: name, inst: inst})
 		}
 	}
+
+	s.logger.Infof("Scheduling %d checks on %d workers", len(checks), s.scheduler.workers)
+	s.scheduler.start(checks, time.Now(), s.done, &s.wg)
 }
 
 // initChecks creates check instances and RRD files for all enabled checks on a host.
 // Each check's factory receives the user-provided config directly; all required
-// addressing information must be present in the config itself.
+// addressing information must be present in the config itself. The scheduling
+// keys "interval", "deadline", "retries" and "retry_interval" are handled here
+// and removed before the config reaches the factory.
 func (s *Server) initChecks(name string, h *host.Host) []checkInstance {
 	instances := make([]checkInstance, 0, len(h.Checks))
 
 	for checkType, cfg := range h.Checks {
 		factoryCfg := copyConfig(cfg)
 
+		interval, err := checkInterval(factoryCfg)
+		if err != nil {
+			s.logger.Errorf("Worker for host %s: failed to create %s check (%v)", name, checkType, err)
+			continue
+		}
+		deadline, err := checkDeadline(factoryCfg, interval)
+		if err != nil {
+			s.logger.Errorf("Worker for host %s: failed to create %s check (%v)", name, checkType, err)
+			continue
+		}
+		retries, err := checkRetries(factoryCfg)
+		if err != nil {
+			s.logger.Errorf("Worker for host %s: failed to create %s check (%v)", name, checkType, err)
+			continue
+		}
+		retryInterval, err := checkRetryInterval(factoryCfg, interval)
+		if err != nil {
+			s.logger.Errorf("Worker for host %s: failed to create %s check (%v)", name, checkType, err)
+			continue
+		}
+		for _, key := range []string{"interval", "deadline", "retries", "retry_interval"} {
+			delete(factoryCfg, key)
+		}
+
 		chk, err := s.registry.Create(checkType, factoryCfg)
 		if err != nil {
 			s.logger.Errorf("Worker for host %s: failed to create %s check (%v)", name, checkType, err)
@@ -83,51 +111,148 @@ func (s *Server) initChecks(name string, h *host.Host) []checkInstance {
 			continue
 		}
 
-		rrdFile, err := rrd.NewRRD(name, s.rrdDir, s.graphDir, checkType, desc.Metrics, desc.Label, s.logger)
+		rrdFile, err := rrd.NewRRD(name, s.rrdDir, s.graphDir, checkType, desc.Metrics, desc.Label, interval, s.logger)
 		if err != nil {
 			s.logger.Errorf("Worker for host %s: failed to initialize RRD for %s check (%v)", name, checkType, err)
 			continue
 		}
 
 		status := s.getOrCreateStatus(name, checkType)
+		status.SetInterval(interval)
+		status.SetRetries(retries)
+		status.SetGraphs(desc.Graphs())
 
 		instances = append(instances, checkInstance{
-			check:      chk,
-			rrdFile:    rrdFile,
-			metricDefs: desc.Metrics,
-			status:     status,
+			check:         chk,
+			rrdFile:       rrdFile,
+			metricDefs:    desc.Metrics,
+			status:        status,
+			interval:      interval,
+			deadline:      deadline,
+			retryInterval: retryInterval,
 		})
-		s.logger.Infof("Worker for host %s: initialized %s check", name, checkType)
+		s.logger.Infof("Worker for host %s: initialized %s check (every %v)", name, checkType, interval)
 	}
 
 	return instances
 }
 
-// runChecks executes all check instances for a host and updates their status and RRD files.
-func (s *Server) runChecks(name string, instances []checkInstance) {
-	for _, inst := range instances {
-		result := inst.check.Run(context.Background())
-		checkType := inst.check.Type()
+// runCheck executes a check instance once and updates its status and RRD file.
+// The run is cancelled once its deadline passes. Every attempt is recorded in
+// the RRD, including soft failures. It reports whether the check failed softly
+// and should be retried after its retry interval.
+func (s *Server) runCheck(name string, inst checkInstance) bool {
+	checkType := inst.check.Type()
 
-		inst.status.SetResult(result)
+	ctx, cancel := context.WithTimeout(context.Background(), inst.deadline)
+	defer cancel()
+	result := inst.check.Run(ctx)
 
-		values := rrdValuesFromResult(result, inst.metricDefs)
+	inst.status.SetResult(result)
 
-		s.logger.Debugf("Worker for host %s [%s]: Updating RRD with values %v.", name, checkType, values)
-		lastUpdate, err := inst.rrdFile.SafeUpdate(result.Timestamp, values)
-		if err != nil {
-			s.logger.Errorf("Worker for host %s [%s]: Failed to update RRD (%v)", name, checkType, err)
-		} else {
-			inst.status.SetLastUpdate(lastUpdate)
-			s.logger.Debugf("Worker for host %s [%s]: RRD update successful.", name, checkType)
-		}
+	values := rrdValuesFromResult(result, inst.metricDefs)
 
-		if result.Success {
-			s.logger.Infof("Worker for host %s [%s]: check successful", name, checkType)
-		} else {
-			s.logger.Warningf("Worker for host %s [%s]: check failed (%v)", name, checkType, result.Err)
-		}
+	s.logger.Debugf("Worker for host %s [%s]: Updating RRD with values %v.", name, checkType, values)
+	lastUpdate, err := inst.rrdFile.SafeUpdate(result.Timestamp, values)
+	if err != nil {
+		s.logger.Errorf("Worker for host %s [%s]: Failed to update RRD (%v)", name, checkType, err)
+	} else {
+		inst.status.SetLastUpdate(lastUpdate)
+		s.logger.Debugf("Worker for host %s [%s]: RRD update successful.", name, checkType)
+	}
+
+	switch {
+	case result.Success:
+		s.logger.Infof("Worker for host %s [%s]: check successful", name, checkType)
+	case inst.status.State() == check.StateSoft:
+		s.logger.Warningf("Worker for host %s [%s]: check failed, retrying in %v (attempt %d) (%v)",
+			name, checkType, inst.retryInterval, inst.status.Attempt(), result.Err)
+		return true
+	default:
+		s.logger.Warningf("Worker for host %s [%s]: check failed (%v)", name, checkType, result.Err)
+	}
+	return false
+}
+
+// checkInterval returns the run interval set by the "interval" key of a
+// check config, or defaultInterval if the key is absent. The interval is
+// also the RRD step, so it must be a whole number of seconds.
+func checkInterval(cfg map[string]any) (time.Duration, error) {
+	v, ok := cfg["interval"]
+	if !ok {
+		return defaultInterval, nil
+	}
+	str, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("'interval' must be a string, got %T", v)
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval %q: %w", str, err)
+	}
+	if d < time.Second || d%time.Second != 0 {
+		return 0, fmt.Errorf("interval %q must be a whole number of seconds, at least 1s", str)
+	}
+	return d, nil
+}
+
+// checkRetries returns how many times a failed check is retried before the
+// failure becomes hard, set by the "retries" key of its config (default 0).
+func checkRetries(cfg map[string]any) (int, error) {
+	v, ok := cfg["retries"]
+	if !ok {
+		return 0, nil
 	}
+	n, ok := v.(float64)
+	if !ok || n != math.Trunc(n) || n < 0 {
+		return 0, fmt.Errorf("'retries' must be a non-negative integer, got %v", v)
+	}
+	return int(n), nil
+}
+
+// checkRetryInterval returns how soon a soft failure is retried, set by the
+// "retry_interval" key of a check config. It defaults to defaultRetryInterval
+// and may not exceed the check's interval. Each retry updates the RRD, whose
+// timestamps have a resolution of one second, so it must be at least 1s.
+func checkRetryInterval(cfg map[string]any, interval time.Duration) (time.Duration, error) {
+	v, ok := cfg["retry_interval"]
+	if !ok {
+		return min(defaultRetryInterval, interval), nil
+	}
+	str, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("'retry_interval' must be a string, got %T", v)
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid retry_interval %q: %w", str, err)
+	}
+	if d < time.Second || d > interval {
+		return 0, fmt.Errorf("retry_interval %q must be at least 1s and at most the interval (%v)", str, interval)
+	}
+	return d, nil
+}
+
+// checkDeadline returns how long a single run of a check may take, set by the
+// "deadline" key of its config. It defaults to, and may not exceed, the
+// check's interval so that a run always finishes before the next is due.
+func checkDeadline(cfg map[string]any, interval time.Duration) (time.Duration, error) {
+	v, ok := cfg["deadline"]
+	if !ok {
+		return interval, nil
+	}
+	str, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("'deadline' must be a string, got %T", v)
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid deadline %q: %w", str, err)
+	}
+	if d <= 0 || d > interval {
+		return 0, fmt.Errorf("deadline %q must be positive and at most the interval (%v)", str, interval)
+	}
+	return d, nil
 }
 
 // copyConfig returns a shallow copy of the config map so that factories cannot
@@ -145,7 +270,8 @@ func copyConfig(cfg map[string]any) map[string]any {
 // metric definitions or no metrics map (skip RRD update entirely). A nil
 // pointer value for a key means the target failed; it is recorded as "U"
 // (UNKNOWN) so rrdtool graphs the surviving targets while showing a gap
-// for the failed one.
+// for the failed one. NaN and infinite values are recorded as "U" too.
+// Rate data sources only accept integers, so their values are rounded.
 func rrdValuesFromResult(result check.Result, metrics []check.MetricDef) []string {
 	if len(metrics) == 0 || result.Metrics == nil {
 		return nil
@@ -153,10 +279,13 @@ func rrdValuesFromResult(result check.Result, metrics []check.MetricDef) []strin
 	vals := make([]string, len(metrics))
 	for i, m := range metrics {
 		v, ok := result.Metrics[m.ResultKey]
-		if !ok || v == nil {
+		switch {
+		case !ok || v == nil || math.IsNaN(*v) || math.IsInf(*v, 0):
 			vals[i] = "U"
-		} else {
-			vals[i] = strconv.FormatInt(*v, 10)
+		case m.DSType.IsRate():
+			vals[i] = strconv.FormatFloat(math.Round(*v), 'f', 0, 64)
+		default:
+			vals[i] = strconv.FormatFloat(*v, 'f', -1, 64)
 		}
 	}
 	return vals
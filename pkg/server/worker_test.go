@@ -1,12 +1,14 @@
 package server
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/kylerisse/wasgeht/pkg/check"
 )
 
-func p64(v int64) *int64 { return &v }
+func p64(v float64) *float64 { return &v }
 
 // pingMetrics is the standard ping metric definition used across tests.
 var pingMetrics = []check.MetricDef{
@@ -54,10 +56,104 @@ func TestCopyConfig_NoTargetInjected(t *testing.T) {
 	}
 }
 
+func TestCheckInterval(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  map[string]any
+		want time.Duration
+	}{
+		{"default", map[string]any{}, time.Minute},
+		{"seconds", map[string]any{"interval": "15s"}, 15 * time.Second},
+		{"hourly", map[string]any{"interval": "1h"}, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkInterval(tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckInterval_Invalid(t *testing.T) {
+	for _, v := range []any{float64(60), "soon", "0s", "-1m", "1500ms", "500ms"} {
+		if d, err := checkInterval(map[string]any{"interval": v}); err == nil {
+			t.Errorf("interval %v: expected error, got %v", v, d)
+		}
+	}
+}
+
+func TestCheckDeadline(t *testing.T) {
+	d, err := checkDeadline(map[string]any{}, time.Minute)
+	if err != nil || d != time.Minute {
+		t.Errorf("expected default deadline of one interval, got %v (err %v)", d, err)
+	}
+	d, err = checkDeadline(map[string]any{"deadline": "20s"}, time.Minute)
+	if err != nil || d != 20*time.Second {
+		t.Errorf("expected 20s deadline, got %v (err %v)", d, err)
+	}
+}
+
+func TestCheckDeadline_Invalid(t *testing.T) {
+	for _, v := range []any{true, "later", "0s", "2m"} {
+		if d, err := checkDeadline(map[string]any{"deadline": v}, time.Minute); err == nil {
+			t.Errorf("deadline %v: expected error, got %v", v, d)
+		}
+	}
+}
+
+func TestCheckRetries(t *testing.T) {
+	if n, err := checkRetries(map[string]any{}); err != nil || n != 0 {
+		t.Errorf("expected no retries by default, got %d (err %v)", n, err)
+	}
+	if n, err := checkRetries(map[string]any{"retries": float64(3)}); err != nil || n != 3 {
+		t.Errorf("expected 3 retries, got %d (err %v)", n, err)
+	}
+	for _, v := range []any{"3", 1.5, float64(-1)} {
+		if n, err := checkRetries(map[string]any{"retries": v}); err == nil {
+			t.Errorf("retries %v: expected error, got %d", v, n)
+		}
+	}
+}
+
+func TestCheckRetryInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      map[string]any
+		interval time.Duration
+		want     time.Duration
+	}{
+		{"default", map[string]any{}, time.Minute, 10 * time.Second},
+		{"default capped at interval", map[string]any{}, 5 * time.Second, 5 * time.Second},
+		{"configured", map[string]any{"retry_interval": "30s"}, time.Hour, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkRetryInterval(tt.cfg, tt.interval)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	for _, v := range []any{float64(10), "often", "500ms", "2m"} {
+		if d, err := checkRetryInterval(map[string]any{"retry_interval": v}, time.Minute); err == nil {
+			t.Errorf("retry_interval %v: expected error, got %v", v, d)
+		}
+	}
+}
+
 func TestRrdValuesFromResult_Success(t *testing.T) {
 	result := check.Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(56780)},
+		Metrics: map[string]*float64{"latency_us": p64(56780)},
 	}
 
 	vals := rrdValuesFromResult(result, pingMetrics)
@@ -78,7 +174,7 @@ func TestRrdValuesFromResult_Failure(t *testing.T) {
 func TestRrdValuesFromResult_NoLatencyMetric(t *testing.T) {
 	result := check.Result{
 		Success: true,
-		Metrics: map[string]*int64{"something_else": p64(420)},
+		Metrics: map[string]*float64{"something_else": p64(420)},
 	}
 
 	vals := rrdValuesFromResult(result, pingMetrics)
@@ -94,7 +190,7 @@ func TestRrdValuesFromResult_MultipleMetrics(t *testing.T) {
 	}
 	result := check.Result{
 		Success: true,
-		Metrics: map[string]*int64{
+		Metrics: map[string]*float64{
 			"rx_bytes": p64(10000),
 			"tx_bytes": p64(2000),
 		},
@@ -119,7 +215,7 @@ func TestRrdValuesFromResult_PartialMetrics(t *testing.T) {
 	}
 	result := check.Result{
 		Success: false,
-		Metrics: map[string]*int64{
+		Metrics: map[string]*float64{
 			"rx_bytes": p64(10000),
 			"tx_bytes": nil, // target failed
 		},
@@ -137,10 +233,39 @@ func TestRrdValuesFromResult_PartialMetrics(t *testing.T) {
 	}
 }
 
+func TestRrdValuesFromResult_Fractional(t *testing.T) {
+	metrics := []check.MetricDef{
+		{ResultKey: "temp", DSName: "temp", Label: "temp", Unit: "°C"},
+		{ResultKey: "in", DSName: "in", Label: "in", Unit: "bits", DSType: check.DSDerive},
+		{ResultKey: "ratio", DSName: "ratio", Label: "ratio"},
+		{ResultKey: "inf", DSName: "inf", Label: "inf"},
+	}
+	result := check.Result{
+		Success: true,
+		Metrics: map[string]*float64{
+			"temp":  p64(21.75),
+			"in":    p64(1234.6),
+			"ratio": p64(math.NaN()),
+			"inf":   p64(math.Inf(1)),
+		},
+	}
+
+	vals := rrdValuesFromResult(result, metrics)
+	want := []string{"21.75", "1235", "U", "U"}
+	if len(vals) != len(want) {
+		t.Fatalf("expected %d values, got %v", len(want), vals)
+	}
+	for i, w := range want {
+		if vals[i] != w {
+			t.Errorf("value %d: expected %q, got %q", i, w, vals[i])
+		}
+	}
+}
+
 func TestRrdValuesFromResult_EmptyMetricDefs(t *testing.T) {
 	result := check.Result{
 		Success: true,
-		Metrics: map[string]*int64{"latency_us": p64(12340)},
+		Metrics: map[string]*float64{"latency_us": p64(12340)},
 	}
 
 	vals := rrdValuesFromResult(result, []check.MetricDef{})